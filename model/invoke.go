@@ -0,0 +1,90 @@
+package model
+
+import (
+	"fmt"
+	"strconv"
+	"time"
+
+	"github.com/pyihe/wechat-sdk/v3/pkg"
+	"github.com/pyihe/wechat-sdk/v3/pkg/errors"
+)
+
+// Signer 使用商户私钥进行SHA256 with RSA签名, *service.Config已实现该接口
+type Signer interface {
+	Sign(message string) (signature string, err error)
+}
+
+// JSAPIInvokeParams JSAPI(WeixinJSBridge.invoke)、小程序(wx.requestPayment)调起支付的参数, 可以直接序列化后返回给前端
+type JSAPIInvokeParams struct {
+	AppId     string `json:"appId"`     // 应用ID, 小程序调起支付时不需要
+	TimeStamp string `json:"timeStamp"` // 时间戳
+	NonceStr  string `json:"nonceStr"`  // 随机字符串
+	Package   string `json:"package"`   // 订单详情扩展字符串, 格式为: prepay_id=***
+	SignType  string `json:"signType"`  // 签名方式, 固定为: RSA
+	PaySign   string `json:"paySign"`   // 签名
+}
+
+// AppInvokeParams APP调起支付的参数
+type AppInvokeParams struct {
+	AppId     string `json:"appid"`     // 应用ID
+	PartnerId string `json:"partnerid"` // 商户号, 服务商模式为子商户号
+	PrepayId  string `json:"prepayid"`  // 预支付交易会话ID
+	Package   string `json:"package"`   // 订单详情扩展字符串, 固定为: Sign=WXPay
+	NonceStr  string `json:"noncestr"`  // 随机字符串
+	TimeStamp string `json:"timestamp"` // 时间戳
+	Sign      string `json:"sign"`      // 签名
+}
+
+// InvokeParams 生成JSAPI、小程序调起支付的参数并签名
+// appId: 直连商户为下单时的appid, 服务商为下单时的sp_appid或者sub_appid(与用户openid对应), 合单支付为combine_appid
+// 签名文档: https://pay.weixin.qq.com/wiki/doc/apiv3/apis/chapter3_1_4.shtml
+func (j *JSAPIResponse) InvokeParams(signer Signer, appId string) (params *JSAPIInvokeParams, err error) {
+	if signer == nil {
+		err = errors.ErrNoConfig
+		return
+	}
+	if j.PrepayId == "" || appId == "" {
+		err = errors.ErrParam
+		return
+	}
+	params = &JSAPIInvokeParams{
+		AppId:     appId,
+		TimeStamp: strconv.FormatInt(time.Now().Unix(), 10),
+		NonceStr:  pkg.String(32),
+		Package:   "prepay_id=" + j.PrepayId,
+		SignType:  "RSA",
+	}
+	message := fmt.Sprintf("%s\n%s\n%s\n%s\n", params.AppId, params.TimeStamp, params.NonceStr, params.Package)
+	if params.PaySign, err = signer.Sign(message); err != nil {
+		params = nil
+	}
+	return
+}
+
+// InvokeParams 生成APP调起支付的参数并签名
+// appId: 直连商户为下单时的appid, 服务商为sub_appid, 合单支付为combine_appid
+// partnerId: 直连商户为mchid, 服务商为sub_mchid, 合单支付为combine_mchid
+// 签名文档: https://pay.weixin.qq.com/wiki/doc/apiv3/apis/chapter3_2_4.shtml
+func (a *AppResponse) InvokeParams(signer Signer, appId, partnerId string) (params *AppInvokeParams, err error) {
+	if signer == nil {
+		err = errors.ErrNoConfig
+		return
+	}
+	if a.PrepayId == "" || appId == "" || partnerId == "" {
+		err = errors.ErrParam
+		return
+	}
+	params = &AppInvokeParams{
+		AppId:     appId,
+		PartnerId: partnerId,
+		PrepayId:  a.PrepayId,
+		Package:   "Sign=WXPay",
+		NonceStr:  pkg.String(32),
+		TimeStamp: strconv.FormatInt(time.Now().Unix(), 10),
+	}
+	message := fmt.Sprintf("%s\n%s\n%s\n%s\n", params.AppId, params.TimeStamp, params.NonceStr, params.PrepayId)
+	if params.Sign, err = signer.Sign(message); err != nil {
+		params = nil
+	}
+	return
+}
@@ -0,0 +1,114 @@
+package model
+
+import (
+	"fmt"
+
+	"github.com/pyihe/wechat-sdk/v3/pkg/validate"
+)
+
+/****************************************************《预支付请求参数》****************************************************/
+
+// PrepayAmount 预支付订单金额
+type PrepayAmount struct {
+	Total    int64  `json:"total"`              // 订单总金额, 单位为分
+	Currency string `json:"currency,omitempty"` // 货币类型, 境内商户号仅支持人民币: CNY
+}
+
+func (a *PrepayAmount) Validate() error {
+	return validate.First(
+		validate.Min("total", a.Total, 1),
+		validate.OneOf("currency", a.Currency, "CNY"),
+	)
+}
+
+// PrepayDetail 预支付订单优惠功能
+type PrepayDetail struct {
+	CostPrice   int64          `json:"cost_price,omitempty"`   // 订单原价
+	InvoiceId   string         `json:"invoice_id,omitempty"`   // 商品小票ID
+	GoodsDetail []*GoodsDetail `json:"goods_detail,omitempty"` // 单品列表
+}
+
+func (d *PrepayDetail) Validate() (err error) {
+	if err = validate.MaxLength("invoice_id", d.InvoiceId, 32); err != nil {
+		return
+	}
+	for i, goods := range d.GoodsDetail {
+		field := fmt.Sprintf("goods_detail[%d]", i)
+		if err = validate.Required(field, goods); err != nil {
+			return
+		}
+		err = validate.First(
+			validate.Required(field+".merchant_goods_id", goods.MerchantGoodsId),
+			validate.MaxLength(field+".merchant_goods_id", goods.MerchantGoodsId, 32),
+			validate.MaxLength(field+".wechatpay_goods_id", goods.WechatpayGoodsId, 32),
+			validate.MaxLength(field+".goods_name", goods.GoodsName, 256),
+			validate.Min(field+".quantity", int64(goods.Quantity), 1),
+			validate.Min(field+".unit_price", goods.UnitPrice, 0),
+		)
+		if err != nil {
+			return
+		}
+	}
+	return
+}
+
+// PrepaySceneInfo 预支付场景信息
+type PrepaySceneInfo struct {
+	PayerClientIp string     `json:"payer_client_ip"`      // 用户终端IP
+	DeviceId      string     `json:"device_id,omitempty"`  // 商户端设备号
+	StoreInfo     *StoreInfo `json:"store_info,omitempty"` // 商户门店信息
+	H5Info        *H5Info    `json:"h5_info,omitempty"`    // H5场景信息, H5支付时必填
+}
+
+func (s *PrepaySceneInfo) Validate() error {
+	return validate.First(
+		validate.Required("payer_client_ip", s.PayerClientIp),
+		validate.MaxLength("payer_client_ip", s.PayerClientIp, 45),
+		validate.MaxLength("device_id", s.DeviceId, 32),
+		validate.Nested("store_info", s.StoreInfo),
+		validate.Nested("h5_info", s.H5Info),
+	)
+}
+
+// StoreInfo 商户门店信息
+type StoreInfo struct {
+	Id       string `json:"id"`                  // 门店编号
+	Name     string `json:"name,omitempty"`      // 门店名称
+	AreaCode string `json:"area_code,omitempty"` // 地区编码
+	Address  string `json:"address,omitempty"`   // 详细地址
+}
+
+func (s *StoreInfo) Validate() error {
+	return validate.First(
+		validate.Required("id", s.Id),
+		validate.MaxLength("id", s.Id, 32),
+		validate.MaxLength("name", s.Name, 256),
+		validate.MaxLength("area_code", s.AreaCode, 32),
+		validate.MaxLength("address", s.Address, 512),
+	)
+}
+
+// H5Info H5场景信息
+type H5Info struct {
+	Type        string `json:"type"`                   // 场景类型: iOS、Android、Wap
+	AppName     string `json:"app_name,omitempty"`     // 应用名称
+	AppUrl      string `json:"app_url,omitempty"`      // 网站URL
+	BundleId    string `json:"bundle_id,omitempty"`    // iOS平台BundleID
+	PackageName string `json:"package_name,omitempty"` // Android平台PackageName
+}
+
+func (h *H5Info) Validate() error {
+	return validate.First(
+		validate.Required("type", h.Type),
+		validate.OneOf("type", h.Type, "iOS", "Android", "Wap"),
+		validate.MaxLength("app_name", h.AppName, 64),
+		validate.MaxLength("app_url", h.AppUrl, 128),
+		validate.MaxLength("bundle_id", h.BundleId, 128),
+		validate.MaxLength("package_name", h.PackageName, 128),
+	)
+}
+
+// SettleInfo 结算信息
+type SettleInfo struct {
+	ProfitSharing bool `json:"profit_sharing,omitempty"` // 是否指定分账
+}
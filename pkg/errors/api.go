@@ -0,0 +1,109 @@
+package errors
+
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+)
+
+// 微信支付API常见的错误码, 可以通过errors.Is(err, errors.ErrOrderPaid)判断
+var (
+	ErrOrderPaid          = &APIError{Code: "ORDERPAID"}           // 订单已支付
+	ErrOrderClosed        = &APIError{Code: "ORDER_CLOSED"}        // 订单已关闭
+	ErrOrderNotExist      = &APIError{Code: "ORDER_NOT_EXIST"}     // 订单不存在
+	ErrResourceNotExists  = &APIError{Code: "RESOURCE_NOT_EXISTS"} // 资源不存在
+	ErrNotEnough          = &APIError{Code: "NOT_ENOUGH"}          // 余额不足
+	ErrFrequencyLimited   = &APIError{Code: "FREQUENCY_LIMITED"}   // 频率超限
+	ErrSystemError        = &APIError{Code: "SYSTEM_ERROR"}        // 系统错误
+	ErrBankError          = &APIError{Code: "BANK_ERROR"}          // 银行系统异常
+	ErrUserPaying         = &APIError{Code: "USERPAYING"}          // 用户支付中
+	ErrParamError         = &APIError{Code: "PARAM_ERROR"}         // 参数错误
+	ErrInvalidRequestCode = &APIError{Code: "INVALID_REQUEST"}     // 不符合业务规则的请求
+	ErrSignError          = &APIError{Code: "SIGN_ERROR"}          // 签名错误
+	ErrNoAuth             = &APIError{Code: "NO_AUTH"}             // 没有权限
+)
+
+// APIError 微信支付API返回的错误(http状态码不为200、204), 可以通过errors.As获取
+// 其Unwrap返回http状态码对应的ErrorCode, 因此errors.Is(err, errors.New(http.StatusBadRequest))同样成立
+type APIError struct {
+	StatusCode int             // http状态码
+	Code       string          // 详细错误码, 如: ORDERPAID
+	Message    string          // 错误描述
+	Detail     *APIErrorDetail // 错误详细信息
+	RequestId  string          // 唯一请求ID, 即应答头中的Request-ID
+	Body       []byte          // 原始的应答body
+}
+
+// APIErrorDetail 错误详细信息
+type APIErrorDetail struct {
+	Field    string      `json:"field,omitempty"`    // 错误参数的位置
+	Value    interface{} `json:"value,omitempty"`    // 错误的值
+	Issue    string      `json:"issue,omitempty"`    // 具体错误的原因
+	Location string      `json:"location,omitempty"` // 出错的位置
+}
+
+// NewAPIError 根据http状态码、Request-ID以及应答body创建APIError, body不是json时只保留原始内容
+func NewAPIError(statusCode int, requestId string, body []byte) *APIError {
+	e := &APIError{
+		StatusCode: statusCode,
+		RequestId:  requestId,
+		Body:       body,
+	}
+	var data struct {
+		Code    interface{}     `json:"code"`
+		Message string          `json:"message"`
+		Detail  json.RawMessage `json:"detail"`
+	}
+	if err := json.Unmarshal(body, &data); err != nil {
+		return e
+	}
+	if data.Code != nil {
+		e.Code = fmt.Sprint(data.Code)
+	}
+	e.Message = data.Message
+	if len(data.Detail) > 0 {
+		detail := new(APIErrorDetail)
+		if err := json.Unmarshal(data.Detail, detail); err == nil && (detail.Field != "" || detail.Value != nil || detail.Issue != "" || detail.Location != "") {
+			e.Detail = detail
+		}
+	}
+	return e
+}
+
+func (e *APIError) Error() string {
+	var b strings.Builder
+	if e.StatusCode > 0 {
+		fmt.Fprintf(&b, "微信支付API错误[%d]", e.StatusCode)
+	} else {
+		b.WriteString("微信支付API错误")
+	}
+	if e.Code != "" {
+		fmt.Fprintf(&b, " %s", e.Code)
+	}
+	if e.Message != "" {
+		fmt.Fprintf(&b, ": %s", e.Message)
+	}
+	if e.Detail != nil {
+		fmt.Fprintf(&b, " (field: %s, value: %v, issue: %s, location: %s)", e.Detail.Field, e.Detail.Value, e.Detail.Issue, e.Detail.Location)
+	}
+	if e.RequestId != "" {
+		fmt.Fprintf(&b, ", Request-ID: %s", e.RequestId)
+	}
+	return b.String()
+}
+
+// Is 错误码相同时返回true, 用于与ErrOrderPaid等错误码比较
+func (e *APIError) Is(target error) bool {
+	t, ok := target.(*APIError)
+	if !ok || t.Code == "" {
+		return false
+	}
+	return t.Code == e.Code && (t.StatusCode == 0 || t.StatusCode == e.StatusCode)
+}
+
+func (e *APIError) Unwrap() error {
+	if e.StatusCode == 0 {
+		return nil
+	}
+	return ErrorCode(e.StatusCode)
+}
@@ -0,0 +1,150 @@
+package errors
+
+import (
+	"context"
+	stderrors "errors"
+	"net"
+	"net/http"
+	"sync"
+)
+
+// Class 错误分类, 用于判断请求失败后是否可以重试
+type Class int
+
+const (
+	ClassNone      Class = iota // 没有错误
+	ClassTerminal               // 终态错误: 业务拒绝、参数错误、配置错误等, 使用相同的参数重试也不会成功
+	ClassRetryable              // 可以使用相同的参数(相同的商户单号)重试, 如系统繁忙、频率超限、网络连接失败等
+	ClassNeedQuery              // 请求可能已经被微信处理(如超时、应答验签失败、用户支付中), 需要先调用查询接口确认结果
+)
+
+func (c Class) String() string {
+	switch c {
+	case ClassNone:
+		return "none"
+	case ClassTerminal:
+		return "terminal"
+	case ClassRetryable:
+		return "retryable"
+	case ClassNeedQuery:
+		return "need_query"
+	}
+	return "unknown"
+}
+
+// 微信支付错误码的分类, 整理自各API文档的错误码表, 未列出的错误码根据http状态码分类
+var (
+	codeLock    sync.RWMutex
+	codeClasses = map[string]Class{
+		// 公共错误码
+		"SYSTEM_ERROR":          ClassRetryable, // 系统异常, 请使用相同参数重新调用
+		"BANK_ERROR":            ClassRetryable, // 银行系统异常, 请使用相同参数重新调用
+		"FREQUENCY_LIMITED":     ClassRetryable, // 频率超限, 请降低频率后重试
+		"PARAM_ERROR":           ClassTerminal,
+		"INVALID_REQUEST":       ClassTerminal,
+		"SIGN_ERROR":            ClassTerminal,
+		"NO_AUTH":               ClassTerminal,
+		"RESOURCE_NOT_EXISTS":   ClassTerminal,
+		"APPID_MCHID_NOT_MATCH": ClassTerminal,
+		"MCH_NOT_EXISTS":        ClassTerminal,
+
+		// 基础支付: https://pay.weixin.qq.com/wiki/doc/apiv3/apis/chapter3_1_1.shtml
+		"USERPAYING":        ClassNeedQuery, // 用户支付中, 需要查询订单确认支付结果
+		"ORDERPAID":         ClassTerminal,
+		"ORDER_CLOSED":      ClassTerminal,
+		"ORDER_NOT_EXIST":   ClassTerminal,
+		"OUT_TRADE_NO_USED": ClassTerminal,
+		"ACCOUNT_ERROR":     ClassTerminal,
+		"TRADE_ERROR":       ClassTerminal,
+
+		// 退款: https://pay.weixin.qq.com/wiki/doc/apiv3/apis/chapter3_1_9.shtml
+		"NOT_ENOUGH":            ClassTerminal, // 余额不足, 充值后需要使用原商户退款单号重新发起
+		"USER_ACCOUNT_ABNORMAL": ClassTerminal,
+		"INVALID_TRANSACTIONID": ClassTerminal,
+
+		// 分账: https://pay.weixin.qq.com/wiki/doc/apiv3/apis/chapter8_1_1.shtml
+		"RATELIMIT_EXCEED": ClassRetryable, // 对同笔订单分账频率过高
+
+		// 代金券发放: https://pay.weixin.qq.com/wiki/doc/apiv3/apis/chapter9_1_2.shtml
+		"RULE_LIMIT":      ClassTerminal, // 用户领券数量达到上限
+		"REQUEST_BLOCKED": ClassTerminal, // 用户被风控拦截
+	}
+)
+
+// RegisterCode 设置(或覆盖)错误码的分类, 用于补充SDK尚未收录的错误码
+func RegisterCode(code string, class Class) {
+	codeLock.Lock()
+	codeClasses[code] = class
+	codeLock.Unlock()
+}
+
+// Classify 判断err的分类:
+// 1. *APIError根据错误码分类, 未收录的错误码按http状态码分类: 202为ClassNeedQuery, 429、5xx为ClassRetryable, 其他为ClassTerminal;
+// 2. 连接建立失败、触发客户端限流为ClassRetryable, 网络超时、连接中断、ctx取消或超时时请求可能已经送达, 为ClassNeedQuery;
+// 3. 应答验签失败(包括找不到应答对应的平台证书)、时间戳超出误差、随机串重放时, 为ClassNeedQuery;
+// 4. 参数校验失败、配置错误等其他SDK错误为ClassTerminal, 无法识别的错误为ClassNeedQuery
+func Classify(err error) Class {
+	if err == nil {
+		return ClassNone
+	}
+
+	var apiErr *APIError
+	if stderrors.As(err, &apiErr) {
+		codeLock.RLock()
+		class, ok := codeClasses[apiErr.Code]
+		codeLock.RUnlock()
+		if ok {
+			return class
+		}
+		return classifyStatus(apiErr.StatusCode)
+	}
+
+	var code ErrorCode
+	if stderrors.As(err, &code) {
+		switch code {
+		case ErrVerifySignFail, ErrTimestampExpired, ErrReplayedNonce:
+			return ClassNeedQuery
+		case ErrRateLimited:
+			return ClassRetryable
+		}
+		if code < ErrParam {
+			return classifyStatus(int(code))
+		}
+		return ClassTerminal
+	}
+
+	if stderrors.Is(err, context.Canceled) || stderrors.Is(err, context.DeadlineExceeded) {
+		return ClassNeedQuery
+	}
+	var opErr *net.OpError
+	if stderrors.As(err, &opErr) && opErr.Op == "dial" {
+		return ClassRetryable
+	}
+	// 读写超时、连接中断以及其他无法识别的错误, 都无法确定请求是否已经被处理
+	return ClassNeedQuery
+}
+
+// IsRetryable 判断err是否可以使用相同的参数重试
+func IsRetryable(err error) bool {
+	return Classify(err) == ClassRetryable
+}
+
+// IsTerminal 判断err是否为终态错误
+func IsTerminal(err error) bool {
+	return Classify(err) == ClassTerminal
+}
+
+// NeedQuery 判断err发生后是否需要调用查询接口确认请求结果
+func NeedQuery(err error) bool {
+	return Classify(err) == ClassNeedQuery
+}
+
+func classifyStatus(status int) Class {
+	switch {
+	case status == http.StatusAccepted:
+		return ClassNeedQuery
+	case status == http.StatusTooManyRequests, status >= http.StatusInternalServerError:
+		return ClassRetryable
+	}
+	return ClassTerminal
+}
@@ -24,6 +24,13 @@ const (
 	ErrInvalidSessionKey
 	ErrCheckHashValueFail
 	ErrMarshalFailInvalidDataType
+	ErrVerifySignFail
+	ErrTimestampExpired
+	ErrReplayedNonce
+	ErrDuplicateNotify
+	ErrInvalidRequest
+	ErrRateLimited
+	ErrNotifyProcessing
 )
 
 type ErrorCode int
@@ -82,6 +89,20 @@ func (code ErrorCode) Error() (err string) {
 		err = "Hash摘要值校验不通过!"
 	case ErrMarshalFailInvalidDataType:
 		err = "序列化失败: 不支持的数据类型!"
+	case ErrVerifySignFail:
+		err = "签名验证失败: 签名与数据不匹配!"
+	case ErrTimestampExpired:
+		err = "签名验证失败: Wechatpay-Timestamp超出允许的时间误差!"
+	case ErrReplayedNonce:
+		err = "签名验证失败: Wechatpay-Nonce已被使用, 可能为重放请求!"
+	case ErrDuplicateNotify:
+		err = "重复的微信通知: 该通知ID已处理过!"
+	case ErrNotifyProcessing:
+		err = "重复的微信通知: 该通知ID正在处理中!"
+	case ErrInvalidRequest:
+		err = "参数错误: 请求参数校验不通过!"
+	case ErrRateLimited:
+		err = "请求过于频繁: 触发了客户端限流!"
 	case ErrNoCipher:
 		err = "加解密/验签失败: 请先初始化Cipher!"
 	case ErrParam:
@@ -31,8 +31,13 @@ func LoadRSAPublicKey(file string) (publicKey *rsa.PublicKey, err error) {
 	if err != nil {
 		return
 	}
+	return ParseRSAPublicKey(data)
+}
+
+// ParseRSAPublicKey 解析PEM格式的RSA PUBLIC KEY
+func ParseRSAPublicKey(data []byte) (publicKey *rsa.PublicKey, err error) {
 	block, _ := pem.Decode(data)
-	if block.Type != "PUBLIC KEY" {
+	if block == nil || block.Type != "PUBLIC KEY" {
 		err = errors.New("证书类型必须是PUBLIC KEY")
 		return
 	}
@@ -109,3 +114,39 @@ func UnmarshalCertificate(data []byte) (serialNo string, cert *x509.Certificate,
 	serialNo = strings.ToUpper(cert.SerialNumber.Text(16))
 	return
 }
+
+// ParseRSAPrivateKey 解析PEM格式的RSA私钥, 自动识别PKCS#1(RSA PRIVATE KEY)和PKCS#8(PRIVATE KEY)格式
+func ParseRSAPrivateKey(data []byte) (privateKey *rsa.PrivateKey, err error) {
+	block, _ := pem.Decode(data)
+	if block == nil {
+		err = errors.New("解析私钥失败: 不是有效的PEM格式")
+		return
+	}
+	if privateKey, err = x509.ParsePKCS1PrivateKey(block.Bytes); err == nil {
+		return
+	}
+	key, err := x509.ParsePKCS8PrivateKey(block.Bytes)
+	if err != nil {
+		return
+	}
+	privateKey, ok := key.(*rsa.PrivateKey)
+	if !ok {
+		err = errors.New("请提供RSA私钥")
+	}
+	return
+}
+
+// ParseCertificate 解析PEM格式的证书, 同时返回证书序列号
+func ParseCertificate(data []byte) (serialNo string, cert *x509.Certificate, err error) {
+	block, _ := pem.Decode(data)
+	if block == nil || block.Type != "CERTIFICATE" {
+		err = errors.New("证书类型必须是CERTIFICATE")
+		return
+	}
+	cert, err = x509.ParseCertificate(block.Bytes)
+	if err != nil {
+		return
+	}
+	serialNo = strings.ToUpper(cert.SerialNumber.Text(16))
+	return
+}
@@ -2,6 +2,9 @@ package rsas
 
 import (
 	"crypto"
+	"crypto/rsa"
+	"crypto/sha256"
+	"encoding/base64"
 
 	"github.com/pyihe/secret"
 
@@ -33,3 +36,20 @@ func VerifySHA256WithRSA(cipher secret.Cipher, signText, plainText string) (err
 	}
 	return
 }
+
+// VerifySHA256WithRSAPublicKey 使用指定的RSA公钥验证SHA256-RSA签名, signText为base64编码后的签名
+func VerifySHA256WithRSAPublicKey(publicKey *rsa.PublicKey, signText, plainText string) (err error) {
+	if publicKey == nil {
+		err = errors.ErrNoCertificate
+		return
+	}
+	signature, err := base64.StdEncoding.DecodeString(signText)
+	if err != nil {
+		return
+	}
+	hashed := sha256.Sum256([]byte(plainText))
+	if rsa.VerifyPKCS1v15(publicKey, crypto.SHA256, hashed[:], signature) != nil {
+		err = errors.ErrVerifySignFail
+	}
+	return
+}
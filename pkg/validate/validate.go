@@ -0,0 +1,202 @@
+package validate
+
+import (
+	"fmt"
+	"net/url"
+	"reflect"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	"github.com/pyihe/wechat-sdk/v3/pkg/errors"
+)
+
+// Validator 可以在签名前校验自身参数的请求参数
+type Validator interface {
+	Validate() error
+}
+
+// Error 请求参数校验失败, 可以通过errors.Is(err, errors.ErrInvalidRequest)判断
+type Error struct {
+	Field  string // 参数名, 嵌套的参数使用.连接, 如: amount.total
+	Reason string // 失败原因
+}
+
+func (e *Error) Error() string {
+	return fmt.Sprintf("参数错误: %s%s!", e.Field, e.Reason)
+}
+
+func (e *Error) Unwrap() error {
+	return errors.ErrInvalidRequest
+}
+
+// Check 如果v实现了Validator则进行校验, v为nil指针时不校验
+func Check(v interface{}) error {
+	validator, ok := v.(Validator)
+	if !ok || isNil(v) {
+		return nil
+	}
+	return validator.Validate()
+}
+
+// First 返回第一个不为nil的error
+func First(errs ...error) error {
+	for _, err := range errs {
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Required 校验必填参数: 字符串不能为空, 指针、切片、map不能为nil或者为空
+func Required(field string, value interface{}) error {
+	if isZero(value) {
+		return &Error{Field: field, Reason: "不能为空"}
+	}
+	return nil
+}
+
+// Length 校验字符串的字节长度在[min, max]之间, 空字符串不校验(必填请使用Required)
+func Length(field, value string, min, max int) error {
+	if value == "" {
+		return nil
+	}
+	if n := len(value); n < min || n > max {
+		return &Error{Field: field, Reason: fmt.Sprintf("长度必须在[%d, %d]之间", min, max)}
+	}
+	return nil
+}
+
+// MaxLength 校验字符串的字节长度不超过max
+func MaxLength(field, value string, max int) error {
+	if len(value) > max {
+		return &Error{Field: field, Reason: fmt.Sprintf("长度不能超过%d", max)}
+	}
+	return nil
+}
+
+// MaxChars 校验字符串的字符个数不超过max, 一个汉字、字母或者数字均算一个字符
+func MaxChars(field, value string, max int) error {
+	if utf8.RuneCountInString(value) > max {
+		return &Error{Field: field, Reason: fmt.Sprintf("不能超过%d个字", max)}
+	}
+	return nil
+}
+
+// OneOf 校验字符串为options中的一个, 空字符串不校验(必填请使用Required)
+func OneOf(field, value string, options ...string) error {
+	if value == "" {
+		return nil
+	}
+	for _, option := range options {
+		if value == option {
+			return nil
+		}
+	}
+	return &Error{Field: field, Reason: fmt.Sprintf("必须为%s中的一个", strings.Join(options, "、"))}
+}
+
+// Range 校验金额、数量等整数在[min, max]之间
+func Range(field string, value, min, max int64) error {
+	if value < min || value > max {
+		return &Error{Field: field, Reason: fmt.Sprintf("必须在[%d, %d]之间", min, max)}
+	}
+	return nil
+}
+
+// Min 校验金额、数量等整数不小于min
+func Min(field string, value, min int64) error {
+	if value < min {
+		return &Error{Field: field, Reason: fmt.Sprintf("不能小于%d", min)}
+	}
+	return nil
+}
+
+// Size 校验数组元素个数在[min, max]之间
+func Size(field string, n, min, max int) error {
+	if n < min || n > max {
+		return &Error{Field: field, Reason: fmt.Sprintf("元素个数必须在[%d, %d]之间", min, max)}
+	}
+	return nil
+}
+
+// Time 校验时间为RFC3339格式, 如: 2018-06-08T10:34:56+08:00, 空字符串不校验(必填请使用Required)
+func Time(field, value string) error {
+	return TimeLayout(field, value, time.RFC3339)
+}
+
+// TimeLayout 校验时间为layout格式, 空字符串不校验(必填请使用Required)
+func TimeLayout(field, value, layout string) error {
+	if value == "" {
+		return nil
+	}
+	if _, err := time.Parse(layout, value); err != nil {
+		return &Error{Field: field, Reason: "时间格式必须为" + layout}
+	}
+	return nil
+}
+
+// Before 校验RFC3339格式的时间begin早于end, 任意一个为空或者格式错误时不校验
+func Before(field, begin, end string) error {
+	beginTime, err1 := time.Parse(time.RFC3339, begin)
+	endTime, err2 := time.Parse(time.RFC3339, end)
+	if err1 != nil || err2 != nil {
+		return nil
+	}
+	if !beginTime.Before(endTime) {
+		return &Error{Field: field, Reason: "开始时间必须早于结束时间"}
+	}
+	return nil
+}
+
+// NotifyUrl 校验回调地址: 必须为https地址且不能携带参数, 长度不超过256, 空字符串不校验(必填请使用Required)
+func NotifyUrl(field, value string) error {
+	if value == "" {
+		return nil
+	}
+	if err := MaxLength(field, value, 256); err != nil {
+		return err
+	}
+	u, err := url.Parse(value)
+	if err != nil || u.Scheme != "https" || u.Host == "" {
+		return &Error{Field: field, Reason: "必须为https地址"}
+	}
+	if u.RawQuery != "" {
+		return &Error{Field: field, Reason: "不能携带参数"}
+	}
+	return nil
+}
+
+// Nested 校验嵌套的参数, 返回的参数名会加上field前缀, v为nil时不校验(必填请使用Required)
+func Nested(field string, v Validator) error {
+	err := Check(v)
+	if e, ok := err.(*Error); ok {
+		return &Error{Field: field + "." + e.Field, Reason: e.Reason}
+	}
+	return err
+}
+
+func isNil(v interface{}) bool {
+	if v == nil {
+		return true
+	}
+	value := reflect.ValueOf(v)
+	switch value.Kind() {
+	case reflect.Ptr, reflect.Interface, reflect.Map, reflect.Slice, reflect.Func, reflect.Chan:
+		return value.IsNil()
+	}
+	return false
+}
+
+func isZero(v interface{}) bool {
+	if isNil(v) {
+		return true
+	}
+	value := reflect.ValueOf(v)
+	switch value.Kind() {
+	case reflect.String, reflect.Map, reflect.Slice, reflect.Array:
+		return value.Len() == 0
+	}
+	return false
+}
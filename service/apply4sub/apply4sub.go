@@ -5,7 +5,6 @@ import (
 	"net/http"
 
 	"github.com/pyihe/wechat-sdk/v3/pkg/errors"
-	"github.com/pyihe/wechat-sdk/v3/pkg/rsas"
 	"github.com/pyihe/wechat-sdk/v3/service"
 )
 
@@ -21,117 +20,17 @@ func Apply(config *service.Config, request *ApplyRequest) (applyResponse *ApplyR
 		return
 	}
 
-	req := request.clone()
-	if req.BusinessCode == "" {
+	if request.BusinessCode == "" {
 		err = errors.ErrParam
 		return
 	}
-
-	serialNo := ""
-	cipher := config.GetWechatCipher()
-	contactInfo := req.ContactInfo
-	subjectInfo := req.SubjectInfo
-	bankAccountInfo := req.BankAccountInfo
-
-	// 获取serialNo
-	serialNo, _ = config.GetValidPublicKey()
-	if serialNo == "" {
-		err = errors.ErrNoCertificate
+	// 超级管理员信息、主体信息以及结算银行账户信息为必填, 其中的敏感信息会在RequestWithSign中自动加密
+	if request.ContactInfo == nil || request.SubjectInfo == nil || request.SubjectInfo.IdentityInfo == nil || request.BankAccountInfo == nil {
+		err = errors.ErrParam
 		return
 	}
 
-	// 加密超级管理员相关信息
-	{
-		if contactInfo == nil {
-			err = errors.ErrParam
-			return
-		}
-		// 加密超级管理员的姓名
-		contactInfo.ContactName, err = rsas.EncryptOAEP(cipher, contactInfo.ContactName)
-		if err != nil {
-			return
-		}
-		// 加密超级管理员证件号码
-		if contactInfo.ContactIdNumber != "" {
-			contactInfo.ContactIdNumber, err = rsas.EncryptOAEP(cipher, contactInfo.ContactIdNumber)
-			if err != nil {
-				return
-			}
-		}
-		// 加密超级管理员手机号码
-		contactInfo.MobilePhone, err = rsas.EncryptOAEP(cipher, contactInfo.MobilePhone)
-		if err != nil {
-			return
-		}
-		// 加密超级管理员邮件地址
-		contactInfo.ContactEmail, err = rsas.EncryptOAEP(cipher, contactInfo.ContactEmail)
-		if err != nil {
-			return
-		}
-	}
-
-	// 加密主体信息
-	{
-		if subjectInfo == nil {
-			err = errors.ErrParam
-			return
-		}
-		if subjectInfo.IdentityInfo == nil {
-			err = errors.ErrParam
-			return
-		}
-		identityInfo := request.SubjectInfo.IdentityInfo
-		// 加密身份证信息
-		if idCardInfo := identityInfo.IdCardInfo; idCardInfo != nil {
-			idCardInfo.IdCardName, err = rsas.EncryptOAEP(cipher, idCardInfo.IdCardName)
-			if err != nil {
-				return
-			}
-			idCardInfo.IdCardNumber, err = rsas.EncryptOAEP(cipher, idCardInfo.IdCardNumber)
-			if err != nil {
-				return
-			}
-		}
-		// 加密其他证件信息
-		if idDocInfo := identityInfo.IdDocInfo; idDocInfo != nil {
-			idDocInfo.IdDocName, err = rsas.EncryptOAEP(cipher, idDocInfo.IdDocName)
-			if err != nil {
-				return
-			}
-			idDocInfo.IdDocNumber, err = rsas.EncryptOAEP(cipher, idDocInfo.IdDocNumber)
-			if err != nil {
-				return
-			}
-		}
-		if uboInfo := subjectInfo.UboInfo; uboInfo != nil {
-			uboInfo.Name, err = rsas.EncryptOAEP(cipher, uboInfo.Name)
-			if err != nil {
-				return
-			}
-			uboInfo.IdNumber, err = rsas.EncryptOAEP(cipher, uboInfo.IdNumber)
-			if err != nil {
-				return
-			}
-		}
-	}
-
-	// 加密结算银行账户信息
-	{
-		if bankAccountInfo == nil {
-			err = errors.ErrParam
-			return
-		}
-		bankAccountInfo.AccountName, err = rsas.EncryptOAEP(cipher, bankAccountInfo.AccountName)
-		if err != nil {
-			return
-		}
-		bankAccountInfo.AccountNumber, err = rsas.EncryptOAEP(cipher, bankAccountInfo.AccountNumber)
-		if err != nil {
-			return
-		}
-	}
-
-	response, err := config.RequestWithSign(http.MethodPost, "/v3/applyment4sub/applyment/", req, "Wechatpay-Serial", serialNo)
+	response, err := config.RequestWithSign(http.MethodPost, "/v3/applyment4sub/applyment/", request)
 	if err != nil {
 		return
 	}
@@ -185,22 +84,7 @@ func ModifySettlement(config *service.Config, request *ModifySettlementRequest)
 		return
 	}
 
-	var serialNo string
-	var cipher = config.GetWechatCipher()
-
-	// 找到加密用的公钥信息
-	serialNo, _ = config.GetValidPublicKey()
-	if serialNo == "" {
-		err = errors.ErrNoCertificate
-		return
-	}
-	req := request.clone()
-	req.AccountNumber, err = rsas.EncryptOAEP(cipher, request.AccountNumber)
-	if err != nil {
-		return
-	}
-
-	response, err := config.RequestWithSign(http.MethodPost, fmt.Sprintf("/v3/apply4sub/sub_merchants/%s/modify-settlement", request.SubMchId), req, "Wechatpay-Serial", serialNo)
+	response, err := config.RequestWithSign(http.MethodPost, fmt.Sprintf("/v3/apply4sub/sub_merchants/%s/modify-settlement", request.SubMchId), request)
 	if err != nil {
 		return
 	}
@@ -2,7 +2,6 @@ package apply4sub
 
 import (
 	"github.com/pyihe/wechat-sdk/v3/model"
-	"github.com/pyihe/wechat-sdk/v3/pkg"
 )
 
 // ApplyRequest 提交申请单请求参数
@@ -16,15 +15,6 @@ type ApplyRequest struct {
 	AdditionInfo    *AdditionInfo    `json:"addition_info,omitempty"` // 补充材料
 }
 
-func (apply *ApplyRequest) clone() *ApplyRequest {
-	data := pkg.DeepClone(apply)
-	req, ok := data.(*ApplyRequest)
-	if !ok || req == nil {
-		return nil
-	}
-	return req
-}
-
 // ApplyResponse 提交申请单应答参数
 type ApplyResponse struct {
 	model.WechatError
@@ -53,25 +43,13 @@ type QueryApplymentResponse struct {
 
 // ModifySettlementRequest 修改结算账号请求参数
 type ModifySettlementRequest struct {
-	SubMchId        string `json:"-"`                           // 特约商户/二级商户号
-	AccountType     string `json:"account_type,omitempty"`      // 账户类型
-	AccountBank     string `json:"account_bank,omitempty"`      // 开户银行
-	BankAddressCode string `json:"bank_address_code,omitempty"` // 开户银行省市编码
-	BankName        string `json:"bank_name,omitempty"`         // 开户银行全称(含支行)
-	BankBranchId    string `json:"bank_branch_id,omitempty"`    // 开户银行联行号
-	AccountNumber   string `json:"account_number,omitempty"`    // 银行账号
-}
-
-func (m *ModifySettlementRequest) clone() *ModifySettlementRequest {
-	return &ModifySettlementRequest{
-		SubMchId:        m.SubMchId,
-		AccountType:     m.AccountType,
-		AccountBank:     m.AccountBank,
-		BankAddressCode: m.BankAddressCode,
-		BankName:        m.BankName,
-		BankBranchId:    m.BankBranchId,
-		AccountNumber:   m.AccountNumber,
-	}
+	SubMchId        string `json:"-"`                                            // 特约商户/二级商户号
+	AccountType     string `json:"account_type,omitempty"`                       // 账户类型
+	AccountBank     string `json:"account_bank,omitempty"`                       // 开户银行
+	BankAddressCode string `json:"bank_address_code,omitempty"`                  // 开户银行省市编码
+	BankName        string `json:"bank_name,omitempty"`                          // 开户银行全称(含支行)
+	BankBranchId    string `json:"bank_branch_id,omitempty"`                     // 开户银行联行号
+	AccountNumber   string `json:"account_number,omitempty" wechatpay:"encrypt"` // 银行账号
 }
 
 // ModifySettlementResponse 修改结算账号应答参数
@@ -88,7 +66,7 @@ type QuerySettlementResponse struct {
 	AccountBank      string `json:"account_bank,omitempty"`       // 开户银行
 	BankName         string `json:"bank_name,omitempty"`          // 银行名称(含支行)
 	BankBranchId     string `json:"bank_branch_id,omitempty"`     // 开户银行联行号
-	AccountNumber    string `json:"account_number,omitempty"`     // 银行账号
+	AccountNumber    string `json:"account_number,omitempty"`     // 银行账号(掩码)
 	VerifyResult     string `json:"verify_result,omitempty"`      // 汇款验证结果
 	VerifyFailReason string `json:"verify_fail_reason,omitempty"` // 汇款验证失败原因
 }
@@ -102,11 +80,11 @@ type AuditDetail struct {
 
 // ContactInfo 超级管理员信息
 type ContactInfo struct {
-	ContactName     string `json:"contact_name"`                // 超级管理员姓名, 需要加密处理
-	ContactIdNumber string `json:"contact_id_number,omitempty"` // 超级管理员身份证件号, 需要加密处理
-	OpenId          string `json:"open_id,omitempty"`           // 超级管理员openid
-	MobilePhone     string `json:"mobile_phone"`                // 超级管理员联系手机, 需要加密
-	ContactEmail    string `json:"contact_email"`               // 超级管理员邮箱, 需要加密处理
+	ContactName     string `json:"contact_name" wechatpay:"encrypt"`                // 超级管理员姓名, 需要加密处理
+	ContactIdNumber string `json:"contact_id_number,omitempty" wechatpay:"encrypt"` // 超级管理员身份证件号, 需要加密处理
+	OpenId          string `json:"open_id,omitempty"`                               // 超级管理员openid
+	MobilePhone     string `json:"mobile_phone" wechatpay:"encrypt"`                // 超级管理员联系手机, 需要加密
+	ContactEmail    string `json:"contact_email" wechatpay:"encrypt"`               // 超级管理员邮箱, 需要加密处理
 }
 
 // SubjectInfo 主体资料
@@ -158,33 +136,33 @@ type IdentityInfo struct {
 
 // IdCardInfo 身份证信息
 type IdCardInfo struct {
-	IdCardCopy      string `json:"id_card_copy"`      // 身份证人像面照片, 需要加密
-	IdCardNational  string `json:"id_card_national"`  // 身份证国徽面照片，需要加密
-	IdCardName      string `json:"id_card_name"`      // 身份证姓名，需要加密
-	IdCardNumber    string `json:"id_card_number"`    // 身份证号码，需要加密
-	CardPeriodBegin string `json:"card_period_begin"` // 身份证有效期开始时间
-	CardPeriodEnd   string `json:"card_period_end"`   // 身份证有效期结束日期
+	IdCardCopy      string `json:"id_card_copy"`                       // 身份证人像面照片, 需要加密
+	IdCardNational  string `json:"id_card_national"`                   // 身份证国徽面照片，需要加密
+	IdCardName      string `json:"id_card_name" wechatpay:"encrypt"`   // 身份证姓名，需要加密
+	IdCardNumber    string `json:"id_card_number" wechatpay:"encrypt"` // 身份证号码，需要加密
+	CardPeriodBegin string `json:"card_period_begin"`                  // 身份证有效期开始时间
+	CardPeriodEnd   string `json:"card_period_end"`                    // 身份证有效期结束日期
 }
 
 // IdDocInfo 其他类型证件信息
 type IdDocInfo struct {
-	IdDocCopy      string `json:"id_doc_copy"`      // 证件照片
-	IdDocName      string `json:"id_doc_name"`      // 证件姓名，需要加密
-	IdDocNumber    string `json:"id_doc_number"`    // 证件号码，需要加密
-	DocPeriodBegin string `json:"doc_period_begin"` // 证件有效期开始时间
-	DocPeriodEnd   string `json:"doc_period_end"`   // 证件有效期结束日期
+	IdDocCopy      string `json:"id_doc_copy"`                       // 证件照片
+	IdDocName      string `json:"id_doc_name" wechatpay:"encrypt"`   // 证件姓名，需要加密
+	IdDocNumber    string `json:"id_doc_number" wechatpay:"encrypt"` // 证件号码，需要加密
+	DocPeriodBegin string `json:"doc_period_begin"`                  // 证件有效期开始时间
+	DocPeriodEnd   string `json:"doc_period_end"`                    // 证件有效期结束日期
 }
 
 // UboInfo 最终受益人信息
 type UboInfo struct {
-	IdType         string `json:"id_type"`                    // 证件类型
-	IdCardCopy     string `json:"id_card_copy,omitempty"`     // 身份证人像面照片
-	IdCardNational string `json:"id_card_national,omitempty"` // 身份证国徽面照片
-	IdDocCopy      string `json:"id_doc_copy,omitempty"`      // 证件照片
-	Name           string `json:"name"`                       // 受益人姓名，需要加密
-	IdNumber       string `json:"id_number"`                  // 证件号码，需要加密
-	IdPeriodBegin  string `json:"id_period_begin"`            // 证件有效期开始时间
-	IdPeriodEnd    string `json:"id_period_end"`              // 证件有效期结束时间
+	IdType         string `json:"id_type"`                       // 证件类型
+	IdCardCopy     string `json:"id_card_copy,omitempty"`        // 身份证人像面照片
+	IdCardNational string `json:"id_card_national,omitempty"`    // 身份证国徽面照片
+	IdDocCopy      string `json:"id_doc_copy,omitempty"`         // 证件照片
+	Name           string `json:"name" wechatpay:"encrypt"`      // 受益人姓名，需要加密
+	IdNumber       string `json:"id_number" wechatpay:"encrypt"` // 证件号码，需要加密
+	IdPeriodBegin  string `json:"id_period_begin"`               // 证件有效期开始时间
+	IdPeriodEnd    string `json:"id_period_end"`                 // 证件有效期结束时间
 }
 
 // BusinessInfo 经营资料
@@ -261,13 +239,13 @@ type SettlementInfo struct {
 
 // BankAccountInfo 结算银行账户
 type BankAccountInfo struct {
-	BankAccountType string `json:"bank_account_type"`        // 账户类型
-	AccountName     string `json:"account_name"`             // 开户名称，需要加密
-	AccountBank     string `json:"account_bank"`             // 开户银行
-	BankAddressCode string `json:"bank_address_code"`        // 开户银行省市编码
-	BankBranchId    string `json:"bank_branch_id,omitempty"` // 开户银行联行号
-	BankName        string `json:"bank_name,omitempty"`      // 开户银行全称（含支行）
-	AccountNumber   string `json:"account_number"`           // 银行账号
+	BankAccountType string `json:"bank_account_type"`                  // 账户类型
+	AccountName     string `json:"account_name" wechatpay:"encrypt"`   // 开户名称，需要加密
+	AccountBank     string `json:"account_bank"`                       // 开户银行
+	BankAddressCode string `json:"bank_address_code"`                  // 开户银行省市编码
+	BankBranchId    string `json:"bank_branch_id,omitempty"`           // 开户银行联行号
+	BankName        string `json:"bank_name,omitempty"`                // 开户银行全称（含支行）
+	AccountNumber   string `json:"account_number" wechatpay:"encrypt"` // 银行账号
 }
 
 // AdditionInfo 补充材料
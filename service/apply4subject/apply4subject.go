@@ -6,7 +6,6 @@ import (
 	"net/url"
 
 	"github.com/pyihe/wechat-sdk/v3/pkg/errors"
-	"github.com/pyihe/wechat-sdk/v3/pkg/rsas"
 	"github.com/pyihe/wechat-sdk/v3/service"
 )
 
@@ -22,52 +21,13 @@ func Apply(config *service.Config, request *ApplyRequest) (applyResponse *ApplyR
 		return
 	}
 
-	serialNo, _ := config.GetValidPublicKey()
-	if serialNo == "" {
-		err = errors.ErrNoCertificate
+	// 联系人信息以及法人身份信息为必填, 其中的敏感信息会在RequestWithSign中自动加密
+	if request.ContactInfo == nil || request.IdentificationInfo == nil {
+		err = errors.ErrParam
 		return
 	}
 
-	cipher := config.GetWechatCipher()
-	req := request.clone()
-	contactInfo := req.ContactInfo
-	identityInfo := req.IdentificationInfo
-	// 加密联系人信息
-	{
-		if contactInfo == nil {
-			err = errors.ErrParam
-			return
-		}
-		contactInfo.Name, err = rsas.EncryptOAEP(cipher, contactInfo.Name)
-		if err != nil {
-			return
-		}
-		contactInfo.Mobile, err = rsas.EncryptOAEP(cipher, contactInfo.Mobile)
-		if err != nil {
-			return
-		}
-		contactInfo.IdCardNumber, err = rsas.EncryptOAEP(cipher, contactInfo.IdCardNumber)
-		if err != nil {
-			return
-		}
-	}
-
-	// 加密法人身份信息
-	{
-		if identityInfo == nil {
-			err = errors.ErrParam
-			return
-		}
-		identityInfo.IdentificationName, err = rsas.EncryptOAEP(cipher, identityInfo.IdentificationName)
-		if err != nil {
-			return
-		}
-		identityInfo.IdentificationNumber, err = rsas.EncryptOAEP(cipher, identityInfo.IdentificationNumber)
-		if err != nil {
-			return
-		}
-	}
-	response, err := config.RequestWithSign(http.MethodPost, "/v3/apply4subject/applyment", req, "Wechatpay-Serial", serialNo)
+	response, err := config.RequestWithSign(http.MethodPost, "/v3/apply4subject/applyment", request)
 	if err != nil {
 		return
 	}
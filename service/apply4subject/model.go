@@ -2,7 +2,6 @@ package apply4subject
 
 import (
 	"github.com/pyihe/wechat-sdk/v3/model"
-	"github.com/pyihe/wechat-sdk/v3/pkg"
 )
 
 // ApplyRequest 提交申请单请求参数
@@ -15,15 +14,6 @@ type ApplyRequest struct {
 	AdditionInfo       *AdditionInfo       `json:"addition_info,omitempty"` // 补充材料
 }
 
-func (apply *ApplyRequest) clone() *ApplyRequest {
-	data := pkg.DeepClone(apply)
-	req, ok := data.(*ApplyRequest)
-	if !ok || req == nil {
-		return nil
-	}
-	return req
-}
-
 // ApplyResponse 提交申请单应答参数
 type ApplyResponse struct {
 	model.WechatError
@@ -67,9 +57,9 @@ type QueryMerchantStateResponse struct {
 
 // ContactInfo 联系人信息
 type ContactInfo struct {
-	Name         string `json:"name"`           // 联系人姓名，需要加密
-	Mobile       string `json:"mobile"`         // 联系人手机号，需要加密
-	IdCardNumber string `json:"id_card_number"` // 联系人身份证号码，需要加密
+	Name         string `json:"name" wechatpay:"encrypt"`           // 联系人姓名，需要加密
+	Mobile       string `json:"mobile" wechatpay:"encrypt"`         // 联系人手机号，需要加密
+	IdCardNumber string `json:"id_card_number" wechatpay:"encrypt"` // 联系人身份证号码，需要加密
 }
 
 // SubjectInfo 主体信息
@@ -121,12 +111,12 @@ type SpecialOperationList struct {
 
 // IdentificationInfo 法人身份信息
 type IdentificationInfo struct {
-	IdentificationType      string `json:"identification_type"`                // 法人证件类型
-	IdentificationName      string `json:"identification_name"`                // 证件姓名
-	IdentificationNumber    string `json:"identification_number"`              // 证件号码
-	IdentificationValidDate string `json:"identification_valid_date"`          // 证件有效日期
-	IdentificationFrontCopy string `json:"identification_front_copy"`          // 证件正面照片
-	IdentificationBackCopy  string `json:"identification_back_copy,omitempty"` // 证件反面照片
+	IdentificationType      string `json:"identification_type"`                       // 法人证件类型
+	IdentificationName      string `json:"identification_name" wechatpay:"encrypt"`   // 证件姓名
+	IdentificationNumber    string `json:"identification_number" wechatpay:"encrypt"` // 证件号码
+	IdentificationValidDate string `json:"identification_valid_date"`                 // 证件有效日期
+	IdentificationFrontCopy string `json:"identification_front_copy"`                 // 证件正面照片
+	IdentificationBackCopy  string `json:"identification_back_copy,omitempty"`        // 证件反面照片
 }
 
 // AdditionInfo 附加材料
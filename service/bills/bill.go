@@ -1,15 +1,16 @@
 package bills
 
 import (
+	"bytes"
 	"crypto"
 	"fmt"
+	"io"
 	"net/http"
 	"net/url"
 
 	"github.com/pyihe/wechat-sdk/v3/pkg/aess"
 	"github.com/pyihe/wechat-sdk/v3/pkg/errors"
 	"github.com/pyihe/wechat-sdk/v3/pkg/files"
-	"github.com/pyihe/wechat-sdk/v3/pkg/rsas"
 	"github.com/pyihe/wechat-sdk/v3/service"
 )
 
@@ -31,6 +32,9 @@ func DownloadTradeBill(config *service.Config, request *TradeBillRequest) (billR
 	if request.BillType != "" {
 		param.Add("bill_type", request.BillType)
 	}
+	if request.TarType != "" {
+		param.Add("tar_type", request.TarType)
+	}
 	response, err := config.RequestWithSign(http.MethodGet, fmt.Sprintf("/v3/bill/tradebill?%s", param.Encode()), nil)
 	if err != nil {
 		return
@@ -42,32 +46,35 @@ func DownloadTradeBill(config *service.Config, request *TradeBillRequest) (billR
 		return
 	}
 
-	// 下载文件
-	content, err := config.Download(billResponse.DownloadUrl)
-	if err != nil {
-		return
-	}
-
-	// 校验hash值
-	switch billResponse.HashType {
-	case "SHA1":
-		if err = config.VerifyHashValue(crypto.SHA1, content, billResponse.HashValue); err != nil {
+	// 流式下载并校验摘要值, gzip压缩的账单会自动解压
+	options := &service.DownloadOptions{HashType: billResponse.HashType, HashValue: billResponse.HashValue}
+	switch {
+	case request.Parse:
+		err = downloadAndParse(config, billResponse.DownloadUrl, options, func(r io.Reader) (err error) {
+			billResponse.TradeBill = new(TradeBill)
+			billResponse.TradeBill.Records, billResponse.TradeBill.Summary, err = ParseTradeBill(r)
 			return
+		})
+		if err != nil {
+			billResponse.TradeBill = nil
 		}
-	default:
-		err = errors.ErrInvalidHashType
+		return
+	case request.Handler != nil:
+		err = downloadAndParse(config, billResponse.DownloadUrl, options, request.Handler)
+		return
+	case request.Writer != nil:
+		_, err = config.DownloadTo(billResponse.DownloadUrl, request.Writer, options)
 		return
 	}
-
 	filename := request.FileName
 	filePath := request.FilePath
 	if filename == "" {
-		filename = fmt.Sprintf("trade_bill_%s.xlsx", request.BillDate)
+		filename = fmt.Sprintf("trade_bill_%s.csv", request.BillDate)
 	}
 	if filePath == "" {
 		filePath = "./tradebill"
 	}
-	err = files.WritToFile(filePath, filename, content)
+	_, err = config.DownloadToFile(billResponse.DownloadUrl, filePath, filename, options)
 	return
 }
 
@@ -89,6 +96,9 @@ func DownloadFundFlowBill(config *service.Config, request *FundFlowRequest) (bil
 	if request.AccountType != "" {
 		param.Add("account_type", request.AccountType)
 	}
+	if request.TarType != "" {
+		param.Add("tar_type", request.TarType)
+	}
 	response, err := config.RequestWithSign(http.MethodGet, fmt.Sprintf("/v3/bill/fundflowbill?%s", param.Encode()), nil)
 	if err != nil {
 		return
@@ -100,32 +110,31 @@ func DownloadFundFlowBill(config *service.Config, request *FundFlowRequest) (bil
 		return
 	}
 
-	// 下载文件
-	content, err := config.Download(billResponse.DownloadUrl)
-	if err != nil {
-		return
-	}
-
-	// 校验hash值
-	switch billResponse.HashType {
-	case "SHA1":
-		if err = config.VerifyHashValue(crypto.SHA1, content, billResponse.HashValue); err != nil {
+	// 流式下载并校验摘要值, gzip压缩的账单会自动解压
+	options := &service.DownloadOptions{HashType: billResponse.HashType, HashValue: billResponse.HashValue}
+	switch {
+	case request.Parse:
+		err = downloadAndParse(config, billResponse.DownloadUrl, options, func(r io.Reader) (err error) {
+			billResponse.FundFlowBill, err = ParseFundFlowBill(r)
 			return
+		})
+		if err != nil {
+			billResponse.FundFlowBill = nil
 		}
-	default:
-		err = errors.ErrInvalidHashType
+		return
+	case request.Writer != nil:
+		_, err = config.DownloadTo(billResponse.DownloadUrl, request.Writer, options)
 		return
 	}
-
 	filename := request.FileName
 	filePath := request.FilePath
 	if filename == "" {
-		filename = fmt.Sprintf("fund_flow_%s.xlsx", request.BillDate)
+		filename = fmt.Sprintf("fund_flow_%s.csv", request.BillDate)
 	}
 	if filePath == "" {
 		filePath = "./fundflow"
 	}
-	err = files.WritToFile(filePath, filename, content)
+	_, err = config.DownloadToFile(billResponse.DownloadUrl, filePath, filename, options)
 	return
 }
 
@@ -166,45 +175,58 @@ func DownloadSubMerchantFundFlowBill(config *service.Config, request *SubMerchan
 	// 根据序号(BillSequence)重新整理明细顺序
 	var serialList = make([]*DownloadBillList, billResponse.DownloadBillCount)
 	for _, list := range billResponse.DownloadBillList {
+		if list.BillSequence < 1 || int(list.BillSequence) > len(serialList) {
+			err = fmt.Errorf("下载子商户资金账单失败: 账单文件序号[%d]无效, 文件总数为%d", list.BillSequence, billResponse.DownloadBillCount)
+			return
+		}
 		serialList[list.BillSequence-1] = list
 	}
 
-	for _, list := range serialList {
-		// 下载单个账单文件对应的数据流
-		var cipherText, plainText, key []byte
-		cipherText, err = config.Download(list.DownloadUrl)
-		if err != nil {
+	for i, list := range serialList {
+		if list == nil {
+			err = fmt.Errorf("下载子商户资金账单失败: 缺少序号为%d的账单文件", i+1)
+			return
+		}
+		// 摘要值为解密后明文的摘要, 因此先确认摘要类型, 下载解密后再校验
+		var hashType crypto.Hash
+		if hashType, err = lookupHashType(list.HashType); err != nil {
+			return
+		}
+
+		// 流式下载单个账单文件对应的密文
+		var buf bytes.Buffer
+		if _, err = config.DownloadTo(list.DownloadUrl, &buf, nil); err != nil {
 			return
 		}
 
 		// 解密RSA加密后的encrypt_key
-		key, err = rsas.DecryptOAEP(cipher, list.EncryptKey)
+		var plainText, key []byte
+		key, err = config.DecryptOAEP(list.EncryptKey)
 		if err != nil {
 			return
 		}
-		plainText, err = aess.DecryptAEADAES256GCM(cipher, string(key), cipherText, "", list.Nonce)
+		plainText, err = aess.DecryptAEADAES256GCM(cipher, string(key), buf.Bytes(), "", list.Nonce)
 		if err != nil {
 			return
 		}
 		// 校验明文hash值
-		switch list.HashType {
-		case "SHA1":
-			if err = config.VerifyHashValue(crypto.SHA1, plainText, list.HashValue); err != nil {
-				return
-			}
-		default:
-			err = errors.ErrInvalidHashType
+		if err = config.VerifyHashValue(hashType, plainText, list.HashValue); err != nil {
 			return
 		}
 
 		content = append(content, plainText...)
 	}
 
+	if request.Parse {
+		billResponse.FundFlowBill, err = ParseFundFlowBill(bytes.NewReader(content))
+		return
+	}
+
 	// 写入文件
 	filename := request.FileName
 	filePath := request.FilePath
 	if filename == "" {
-		filename = fmt.Sprintf("sub_fund_flow_%s.xlsx", request.BillDate)
+		filename = fmt.Sprintf("sub_fund_flow_%s.csv", request.BillDate)
 	}
 	if filePath == "" {
 		filePath = "./mchfundflow"
@@ -212,3 +234,16 @@ func DownloadSubMerchantFundFlowBill(config *service.Config, request *SubMerchan
 	err = files.WritToFile(filePath, filename, content)
 	return
 }
+
+// lookupHashType 返回账单摘要类型对应的摘要算法
+func lookupHashType(hashType string) (hash crypto.Hash, err error) {
+	switch hashType {
+	case "SHA1":
+		hash = crypto.SHA1
+	case "SHA256":
+		hash = crypto.SHA256
+	default:
+		err = errors.ErrInvalidHashType
+	}
+	return
+}
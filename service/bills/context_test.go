@@ -0,0 +1,48 @@
+package bills
+
+import (
+	"context"
+	stderrors "errors"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/pyihe/wechat-sdk/v3/service/wechatpaytest"
+)
+
+func TestDownloadSubMerchantFundFlowBillContext(t *testing.T) {
+	server := wechatpaytest.NewServer()
+	defer server.Close()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	var downloads int
+	server.HandleFunc(http.MethodGet, "/v3/bill/sub-merchant-fundflowbill", func(r *http.Request, body []byte) (int, interface{}) {
+		return http.StatusOK, map[string]interface{}{
+			"download_bill_count": 2,
+			"download_bill_list": []map[string]interface{}{
+				{"bill_sequence": 1, "download_url": server.URL + "/v3/billdownload/file?token=1", "hash_type": "SHA1"},
+				{"bill_sequence": 2, "download_url": server.URL + "/v3/billdownload/file?token=2", "hash_type": "SHA1"},
+			},
+		}
+	})
+	// 下载第一个文件时取消, 之后的下载不应再发起
+	server.HandleFunc(http.MethodGet, "/v3/billdownload/file", func(r *http.Request, body []byte) (int, interface{}) {
+		downloads++
+		cancel()
+		select {
+		case <-r.Context().Done():
+		case <-time.After(10 * time.Second):
+		}
+		return http.StatusOK, []byte("")
+	})
+
+	config := server.NewConfig().WithContext(ctx)
+	_, err := DownloadSubMerchantFundFlowBill(config, &SubMerchantFundFlowRequest{SubMchId: "1900000109", BillDate: "2021-11-30", AccountType: "BASIC", Parse: true})
+	if !stderrors.Is(err, context.Canceled) {
+		t.Fatalf("expected context.Canceled, got: %v", err)
+	}
+	if downloads != 1 {
+		t.Fatalf("unexpected downloads after cancel: %d", downloads)
+	}
+}
@@ -0,0 +1,144 @@
+package bills
+
+import (
+	"io"
+	"io/ioutil"
+	"time"
+
+	"github.com/pyihe/wechat-sdk/v3/service"
+)
+
+// 资金账单的收支类型
+const (
+	FundFlowIncome  = "收入"
+	FundFlowExpense = "支出"
+)
+
+// FundFlowRecord 资金账单明细, 基本账户(BASIC)、运营账户(OPERATION)、手续费账户(FEES)的账单格式相同, 金额单位为分
+// 账单格式说明: https://pay.weixin.qq.com/wiki/doc/apiv3/apis/chapter3_1_7.shtml
+type FundFlowRecord struct {
+	BillingTime      time.Time         `bill:"记账时间"`                 // 记账时间(北京时间)
+	BizTransactionId string            `bill:"微信支付业务单号"`             // 微信支付业务单号
+	FundFlowId       string            `bill:"资金流水单号"`               // 资金流水单号
+	BizName          string            `bill:"业务名称"`                 // 业务名称, 如: 交易、退款
+	BizType          string            `bill:"业务类型"`                 // 业务类型
+	Direction        string            `bill:"收支类型"`                 // 收支类型: 收入、支出
+	Amount           int64             `bill:"收支金额（元）|收支金额(元)|收支金额"` // 收支金额
+	Balance          int64             `bill:"账户结余（元）|账户结余(元)|账户结余"` // 账户结余
+	Applicant        string            `bill:"资金变更提交申请人"`            // 资金变更提交申请人
+	Remark           string            `bill:"备注"`                   // 备注
+	BizVoucherId     string            `bill:"业务凭证号"`                // 业务凭证号
+	Extra            map[string]string // 没有对应字段的列, key为列名
+}
+
+// IsIncome 是否为收入
+func (r *FundFlowRecord) IsIncome() bool {
+	return r.Direction == FundFlowIncome
+}
+
+// SignedAmount 带符号的收支金额, 支出为负数
+func (r *FundFlowRecord) SignedAmount() int64 {
+	if r.Direction == FundFlowExpense {
+		return -r.Amount
+	}
+	return r.Amount
+}
+
+// FundFlowSummary 资金账单汇总, 金额单位为分
+type FundFlowSummary struct {
+	TotalCount    int   `bill:"资金流水总笔数"`              // 资金流水总笔数
+	IncomeCount   int   `bill:"收入笔数"`                 // 收入笔数
+	IncomeAmount  int64 `bill:"收入金额|收入金额（元）|收入金额(元)"` // 收入金额
+	ExpenseCount  int   `bill:"支出笔数"`                 // 支出笔数
+	ExpenseAmount int64 `bill:"支出金额|支出金额（元）|支出金额(元)"` // 支出金额
+}
+
+// FundFlowBill 解析后的资金账单
+type FundFlowBill struct {
+	Records []*FundFlowRecord // 明细
+	Summary *FundFlowSummary  // 汇总
+}
+
+// FundFlowReader 流式读取资金账单
+type FundFlowReader struct {
+	reader  *billReader
+	summary *FundFlowSummary
+}
+
+// NewFundFlowReader 创建资金账单读取器, r为下载(解密)得到的账单内容, 会立即读取表头
+func NewFundFlowReader(r io.Reader) (reader *FundFlowReader, err error) {
+	b, err := newBillReader(r)
+	if err != nil {
+		return
+	}
+	reader = &FundFlowReader{reader: b}
+	return
+}
+
+// Next 读取下一条明细, 明细读取完毕后返回io.EOF, 之后可以通过Summary获取汇总数据
+func (f *FundFlowReader) Next() (record *FundFlowRecord, err error) {
+	row, err := f.reader.next()
+	if err == io.EOF && f.summary == nil {
+		f.summary = new(FundFlowSummary)
+		if decodeErr := f.reader.decode(f.reader.summary, f.summary); decodeErr != nil {
+			err = decodeErr
+		}
+		return
+	}
+	if err != nil {
+		return
+	}
+	record = new(FundFlowRecord)
+	if err = f.reader.decode(row, record); err != nil {
+		record = nil
+	}
+	return
+}
+
+// Summary 获取账单汇总, Next返回io.EOF之前为nil
+func (f *FundFlowReader) Summary() *FundFlowSummary {
+	return f.summary
+}
+
+// ParseFundFlowBill 解析完整的资金账单(包括服务商下载的子商户资金账单)
+func ParseFundFlowBill(r io.Reader) (bill *FundFlowBill, err error) {
+	reader, err := NewFundFlowReader(r)
+	if err != nil {
+		return
+	}
+	bill = new(FundFlowBill)
+	for {
+		var record *FundFlowRecord
+		if record, err = reader.Next(); err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, err
+		}
+		bill.Records = append(bill.Records, record)
+	}
+	bill.Summary, err = reader.Summary(), nil
+	return
+}
+
+// downloadAndParse 边下载边解析, 下载完成且摘要值校验通过后才返回解析结果
+func downloadAndParse(config *service.Config, url string, options *service.DownloadOptions, parse func(r io.Reader) error) (err error) {
+	pr, pw := io.Pipe()
+	done := make(chan error, 1)
+	go func() {
+		_, downloadErr := config.DownloadTo(url, pw, options)
+		_ = pw.CloseWithError(downloadErr)
+		done <- downloadErr
+	}()
+	err = parse(pr)
+	if err == nil {
+		// 读取汇总之后剩余的内容(如末尾的空行), 以便下载可以正常结束
+		_, err = io.Copy(ioutil.Discard, pr)
+	}
+	_ = pr.Close()
+	// 解析失败后关闭管道导致的下载错误无需返回
+	if downloadErr := <-done; downloadErr != nil && downloadErr != io.ErrClosedPipe {
+		err = downloadErr
+	}
+	return
+}
@@ -0,0 +1,143 @@
+package bills
+
+import (
+	"bytes"
+	"compress/gzip"
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha1"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"net/http"
+	"strconv"
+	"strings"
+	"testing"
+
+	"github.com/pyihe/wechat-sdk/v3/pkg/errors"
+	"github.com/pyihe/wechat-sdk/v3/service/wechatpaytest"
+)
+
+const fundFlowBill = "记账时间,微信支付业务单号,资金流水单号,业务名称,业务类型,收支类型,收支金额（元）,账户结余（元）,资金变更提交申请人,备注,业务凭证号\n" +
+	"`2021-11-30 10:00:00,`4200001234202111301234567890,`4200001234202111301234567890,`交易,`交易,`收入,`100.01,`100.01,`system,`缺省,`4200001234202111301234567890\n" +
+	"`2021-11-30 12:30:00,`50000000000000000000000000,`50000000000000000000000000,`退款,`退款,`支出,`0.50,`99.51,`system,`缺省,`1217752501201407033233368020\n" +
+	"资金流水总笔数,收入笔数,收入金额,支出笔数,支出金额\n" +
+	"`2,`1,`100.01,`1,`0.50\n"
+
+func TestParseFundFlowBill(t *testing.T) {
+	bill, err := ParseFundFlowBill(strings.NewReader(fundFlowBill))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(bill.Records) != 2 {
+		t.Fatalf("unexpected records: %d", len(bill.Records))
+	}
+	income, expense := bill.Records[0], bill.Records[1]
+	if !income.IsIncome() || income.Amount != 10001 || income.Balance != 10001 || income.BizName != "交易" {
+		t.Fatalf("unexpected income record: %+v", income)
+	}
+	if expense.IsIncome() || expense.SignedAmount() != -50 || expense.Balance != 9951 || expense.BizVoucherId != "1217752501201407033233368020" {
+		t.Fatalf("unexpected expense record: %+v", expense)
+	}
+	if *bill.Summary != (FundFlowSummary{TotalCount: 2, IncomeCount: 1, IncomeAmount: 10001, ExpenseCount: 1, ExpenseAmount: 50}) {
+		t.Fatalf("unexpected summary: %+v", bill.Summary)
+	}
+}
+
+func TestDownloadFundFlowBillParse(t *testing.T) {
+	server := wechatpaytest.NewServer()
+	defer server.Close()
+
+	var compressed bytes.Buffer
+	gz := gzip.NewWriter(&compressed)
+	_, _ = gz.Write([]byte(fundFlowBill))
+	_ = gz.Close()
+	sum := sha1.Sum([]byte(fundFlowBill))
+	hashValue := hex.EncodeToString(sum[:])
+
+	server.HandleFunc(http.MethodGet, "/v3/bill/fundflowbill", func(r *http.Request, body []byte) (int, interface{}) {
+		if r.URL.Query().Get("tar_type") != "GZIP" {
+			return http.StatusBadRequest, wechatpaytest.Error("PARAM_ERROR", "tar_type错误")
+		}
+		return http.StatusOK, map[string]string{
+			"hash_type":    "SHA1",
+			"hash_value":   hashValue,
+			"download_url": server.URL + "/v3/billdownload/file?token=" + r.URL.Query().Get("bill_date"),
+		}
+	})
+	server.HandleFunc(http.MethodGet, "/v3/billdownload/file", func(r *http.Request, body []byte) (int, interface{}) {
+		if r.URL.Query().Get("token") != "2021-11-30" {
+			// 内容与摘要值不一致
+			return http.StatusOK, []byte(fundFlowBill + "\n")
+		}
+		return http.StatusOK, compressed.Bytes()
+	})
+	config := server.NewConfig()
+
+	response, err := DownloadFundFlowBill(config, &FundFlowRequest{BillDate: "2021-11-30", AccountType: "BASIC", TarType: "GZIP", Parse: true})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if response.FundFlowBill == nil || len(response.FundFlowBill.Records) != 2 || response.FundFlowBill.Summary.IncomeAmount != 10001 {
+		t.Fatalf("unexpected bill: %+v", response.FundFlowBill)
+	}
+
+	response, err = DownloadFundFlowBill(config, &FundFlowRequest{BillDate: "2021-12-01", TarType: "GZIP", Parse: true})
+	if err != errors.ErrCheckHashValueFail || response.FundFlowBill != nil {
+		t.Fatalf("expected ErrCheckHashValueFail, got: %v", err)
+	}
+}
+
+func TestDownloadSubMerchantFundFlowBillParse(t *testing.T) {
+	server := wechatpaytest.NewServer()
+	defer server.Close()
+
+	// 账单拆分为两个文件, 分别使用SHA256以及SHA1摘要, 明细按序号倒序返回
+	const key, nonce = "a7cde1ZJB1kG2e7VfTs3jQzaWizur8Gb", "fdasflkja484"
+	block, _ := aes.NewCipher([]byte(key))
+	gcm, _ := cipher.NewGCM(block)
+	data, err := rsa.EncryptOAEP(sha1.New(), rand.Reader, &server.MerchantKey.PublicKey, []byte(key), nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	encryptKey := base64.StdEncoding.EncodeToString(data)
+	split := strings.Index(fundFlowBill, "资金流水总笔数")
+	parts := []string{fundFlowBill[:split], fundFlowBill[split:]}
+	sum256, sum1 := sha256.Sum256([]byte(parts[0])), sha1.Sum([]byte(parts[1]))
+
+	sequence := 1
+	server.HandleFunc(http.MethodGet, "/v3/bill/sub-merchant-fundflowbill", func(r *http.Request, body []byte) (int, interface{}) {
+		return http.StatusOK, map[string]interface{}{
+			"download_bill_count": 2,
+			"download_bill_list": []map[string]interface{}{
+				{"bill_sequence": sequence + 1, "download_url": server.URL + "/v3/billdownload/file?part=1", "encrypt_key": encryptKey,
+					"hash_type": "SHA1", "hash_value": hex.EncodeToString(sum1[:]), "nonce": nonce},
+				{"bill_sequence": sequence, "download_url": server.URL + "/v3/billdownload/file?part=0", "encrypt_key": encryptKey,
+					"hash_type": "SHA256", "hash_value": hex.EncodeToString(sum256[:]), "nonce": nonce},
+			},
+		}
+	})
+	server.HandleFunc(http.MethodGet, "/v3/billdownload/file", func(r *http.Request, body []byte) (int, interface{}) {
+		part, _ := strconv.Atoi(r.URL.Query().Get("part"))
+		return http.StatusOK, gcm.Seal(nil, []byte(nonce), []byte(parts[part]), nil)
+	})
+	config := server.NewConfig()
+
+	request := &SubMerchantFundFlowRequest{SubMchId: "1900000109", BillDate: "2021-11-30", AccountType: "BASIC", Parse: true}
+	response, err := DownloadSubMerchantFundFlowBill(config, request)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if bill := response.FundFlowBill; bill == nil || len(bill.Records) != 2 || bill.Summary.IncomeAmount != 10001 {
+		t.Fatalf("unexpected bill: %+v", bill)
+	}
+
+	// 无效的序号返回错误而不是panic
+	for _, sequence = range []int{0, 2} {
+		if _, err = DownloadSubMerchantFundFlowBill(config, request); err == nil {
+			t.Fatalf("sequence %d: expected error", sequence)
+		}
+	}
+}
@@ -1,21 +1,35 @@
 package bills
 
-import "github.com/pyihe/wechat-sdk/v3/model"
+import (
+	"io"
+
+	"github.com/pyihe/wechat-sdk/v3/model"
+)
 
 // TradeBillRequest 申请交易账单
 type TradeBillRequest struct {
-	BillDate string `json:"bill_date"`           // 账单日期
-	BillType string `json:"bill_type,omitempty"` // 账单类型
-	FileName string `json:"file_name,omitempty"` // 文件存储名
-	FilePath string `json:"file_path,omitempty"` // 文件存放路径
+	BillDate string    `json:"bill_date"`           // 账单日期
+	BillType string    `json:"bill_type,omitempty"` // 账单类型
+	TarType  string    `json:"tar_type,omitempty"`  // 压缩类型, 为GZIP时下载后自动解压
+	FileName string    `json:"file_name,omitempty"` // 文件存储名
+	FilePath string    `json:"file_path,omitempty"` // 文件存放路径
+	Writer   io.Writer `json:"-"`                   // 账单写入的目标, 不为nil时写入Writer而不是文件
+	Parse    bool      `json:"-"`                   // 为true时解析账单到应答的TradeBill中, 不写入文件或Writer
+
+	// 不为nil时边下载边将账单内容(已解压)交给Handler处理, 如使用NewTradeBillReader逐行读取, 不写入文件或Writer,
+	// 下载完成且摘要值校验通过后才返回, Handler返回的error会原样返回
+	Handler func(bill io.Reader) error `json:"-"`
 }
 
 // FundFlowRequest 申请资金账单
 type FundFlowRequest struct {
-	BillDate    string `json:"bill_date"`              // 账单日期
-	AccountType string `json:"account_type,omitempty"` // 资金账户类型
-	FileName    string `json:"file_name,omitempty"`    // 文件存储名
-	FilePath    string `json:"file_path,omitempty"`    // 文件存放路径
+	BillDate    string    `json:"bill_date"`              // 账单日期
+	AccountType string    `json:"account_type,omitempty"` // 资金账户类型
+	TarType     string    `json:"tar_type,omitempty"`     // 压缩类型, 为GZIP时下载后自动解压
+	FileName    string    `json:"file_name,omitempty"`    // 文件存储名
+	FilePath    string    `json:"file_path,omitempty"`    // 文件存放路径
+	Writer      io.Writer `json:"-"`                      // 账单写入的目标, 不为nil时写入Writer而不是文件
+	Parse       bool      `json:"-"`                      // 为true时解析账单到应答的FundFlowBill中, 不写入文件或Writer
 }
 
 // BillResponse 账单申请应答
@@ -25,6 +39,9 @@ type BillResponse struct {
 	HashType    string `json:"hash_type,omitempty"`    // 原始账单的摘要值类型
 	HashValue   string `json:"hash_value,omitempty"`   // 摘要值
 	DownloadUrl string `json:"download_url,omitempty"` // 账单下载地址
+
+	TradeBill    *TradeBill    `json:"-"` // 解析后的交易账单, 仅在TradeBillRequest.Parse为true时有值
+	FundFlowBill *FundFlowBill `json:"-"` // 解析后的资金账单, 仅在FundFlowRequest.Parse为true时有值
 }
 
 // SubMerchantFundFlowRequest 服务商平台申请单个子商户资金账单
@@ -35,6 +52,7 @@ type SubMerchantFundFlowRequest struct {
 	Algorithm   string `json:"algorithm"`           // 加密算法
 	FileName    string `json:"file_name,omitempty"` // 账单文件存储名
 	FilePath    string `json:"file_path,omitempty"` // 账单文件存储路径
+	Parse       bool   `json:"-"`                   // 为true时解析账单到应答的FundFlowBill中, 不写入文件
 }
 
 // SubMerchantFundFlowResponse 服务商平台申请单个子商户资金账单应答
@@ -43,6 +61,8 @@ type SubMerchantFundFlowResponse struct {
 	RequestId         string              `json:"-"`
 	DownloadBillCount int32               `json:"download_bill_count,omitempty"` // 下载信息总数
 	DownloadBillList  []*DownloadBillList `json:"download_bill_list,omitempty"`  // 下载信息明细
+
+	FundFlowBill *FundFlowBill `json:"-"` // 解析后的资金账单, 仅在SubMerchantFundFlowRequest.Parse为true时有值
 }
 
 // DownloadBillList 下载信息明细
@@ -0,0 +1,238 @@
+package bills
+
+import (
+	"bufio"
+	"encoding/csv"
+	"fmt"
+	"io"
+	"reflect"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// 账单中的时间均为北京时间, 使用固定时区避免依赖系统的时区数据库
+var cst = time.FixedZone("CST", 8*3600)
+
+const billTimeLayout = "2006-01-02 15:04:05"
+
+// billReader 逐行读取微信账单: 第一行为表头, 之后每个字段以`开头的行为明细,
+// 明细之后不以`开头的一行为汇总表头, 其下一行为汇总数据
+type billReader struct {
+	reader  *csv.Reader
+	header  []string
+	line    int
+	summary map[string]string
+}
+
+func newBillReader(r io.Reader) (b *billReader, err error) {
+	reader := csv.NewReader(bufio.NewReaderSize(r, 64<<10))
+	reader.FieldsPerRecord = -1
+	reader.LazyQuotes = true
+	b = &billReader{reader: reader}
+	header, err := b.read()
+	if err == io.EOF {
+		err = fmt.Errorf("解析账单失败: 账单为空")
+		return
+	}
+	if err != nil {
+		return
+	}
+	// 去掉UTF-8 BOM
+	header[0] = strings.TrimPrefix(header[0], "\ufeff")
+	b.header = trimFields(header)
+	return
+}
+
+// next 返回下一条明细, key为表头中的列名, 明细读取完毕后返回io.EOF, 此时汇总数据已经读取
+func (b *billReader) next() (row map[string]string, err error) {
+	if b.summary != nil {
+		err = io.EOF
+		return
+	}
+	record, err := b.read()
+	if err == io.EOF {
+		b.summary = make(map[string]string)
+		return
+	}
+	if err != nil {
+		return
+	}
+	if !strings.HasPrefix(record[0], "`") {
+		return nil, b.readSummary(trimFields(record))
+	}
+	if row, err = b.zip(b.header, record); err != nil {
+		return
+	}
+	return
+}
+
+func (b *billReader) readSummary(header []string) (err error) {
+	b.summary = make(map[string]string)
+	record, err := b.read()
+	if err == io.EOF {
+		return io.EOF
+	}
+	if err != nil {
+		return
+	}
+	if b.summary, err = b.zip(header, record); err != nil {
+		return
+	}
+	return io.EOF
+}
+
+// read 读取下一个非空行
+func (b *billReader) read() (record []string, err error) {
+	for {
+		if record, err = b.reader.Read(); err != nil {
+			return
+		}
+		b.line++
+		if len(record) > 1 || strings.TrimSpace(record[0]) != "" {
+			return
+		}
+	}
+}
+
+func (b *billReader) zip(header, record []string) (map[string]string, error) {
+	if len(record) != len(header) {
+		return nil, fmt.Errorf("解析账单失败: 第%d行有%d列, 表头有%d列", b.line, len(record), len(header))
+	}
+	row := make(map[string]string, len(header))
+	for i, name := range header {
+		row[name] = strings.TrimSpace(strings.TrimPrefix(record[i], "`"))
+	}
+	return row, nil
+}
+
+// decode 将row赋值到dst(结构体指针)中带有bill tag的字段, tag为列名, 多个列名使用|分隔:
+// string直接赋值, int64为以元为单位的金额(转换为分), int为笔数, time.Time为北京时间,
+// 没有对应字段的列保存到名为Extra的map[string]string字段中(如果存在的话)
+func (b *billReader) decode(row map[string]string, dst interface{}) (err error) {
+	value := reflect.ValueOf(dst).Elem()
+	columns := billColumns(value.Type())
+	var extra map[string]string
+	for column, v := range row {
+		index, ok := columns[column]
+		if !ok {
+			if extra == nil {
+				extra = make(map[string]string)
+			}
+			extra[column] = v
+			continue
+		}
+		if err = setBillField(value.Field(index), v); err != nil {
+			return fmt.Errorf("解析账单失败: 第%d行[%s]: %v", b.line, column, err)
+		}
+	}
+	if field := value.FieldByName("Extra"); field.IsValid() && extra != nil {
+		field.Set(reflect.ValueOf(extra))
+	}
+	return
+}
+
+var (
+	billColumnLock  sync.RWMutex
+	billColumnCache = make(map[reflect.Type]map[string]int)
+)
+
+// billColumns 获取列名到字段序号的映射
+func billColumns(t reflect.Type) map[string]int {
+	billColumnLock.RLock()
+	columns, ok := billColumnCache[t]
+	billColumnLock.RUnlock()
+	if ok {
+		return columns
+	}
+	columns = make(map[string]int)
+	for i := 0; i < t.NumField(); i++ {
+		tag := t.Field(i).Tag.Get("bill")
+		if tag == "" {
+			continue
+		}
+		for _, name := range strings.Split(tag, "|") {
+			columns[name] = i
+		}
+	}
+	billColumnLock.Lock()
+	billColumnCache[t] = columns
+	billColumnLock.Unlock()
+	return columns
+}
+
+var timeType = reflect.TypeOf(time.Time{})
+
+func setBillField(field reflect.Value, value string) (err error) {
+	switch {
+	case field.Type() == timeType:
+		var t time.Time
+		if t, err = parseTime(value); err == nil {
+			field.Set(reflect.ValueOf(t))
+		}
+	case field.Kind() == reflect.String:
+		field.SetString(value)
+	case field.Kind() == reflect.Int64:
+		var fen int64
+		if fen, err = parseFen(value); err == nil {
+			field.SetInt(fen)
+		}
+	case field.Kind() == reflect.Int:
+		var n int64
+		if value != "" {
+			n, err = strconv.ParseInt(value, 10, 64)
+		}
+		field.SetInt(n)
+	default:
+		err = fmt.Errorf("不支持的字段类型: %s", field.Type())
+	}
+	return
+}
+
+func trimFields(fields []string) []string {
+	for i, field := range fields {
+		fields[i] = strings.TrimSpace(field)
+	}
+	return fields
+}
+
+// parseFen 将以元为单位的金额(如: 1.01、-0.5)转换为分, 空字符串为0
+func parseFen(value string) (fen int64, err error) {
+	value = strings.Replace(value, ",", "", -1)
+	if value == "" {
+		return
+	}
+	negative := strings.HasPrefix(value, "-")
+	value = strings.TrimLeft(value, "+-")
+	yuan, fraction := value, ""
+	if i := strings.IndexByte(value, '.'); i >= 0 {
+		yuan, fraction = value[:i], value[i+1:]
+	}
+	// 超过两位的小数只允许为0
+	if len(fraction) > 2 {
+		if strings.Trim(fraction[2:], "0") != "" {
+			return 0, fmt.Errorf("金额%s的精度超过了分", value)
+		}
+		fraction = fraction[:2]
+	}
+	fraction += strings.Repeat("0", 2-len(fraction))
+	if yuan == "" {
+		yuan = "0"
+	}
+	if fen, err = strconv.ParseInt(yuan+fraction, 10, 64); err != nil {
+		return 0, fmt.Errorf("无效的金额: %s", value)
+	}
+	if negative {
+		fen = -fen
+	}
+	return
+}
+
+// parseTime 解析北京时间, 空字符串返回零值
+func parseTime(value string) (t time.Time, err error) {
+	if value == "" {
+		return
+	}
+	return time.ParseInLocation(billTimeLayout, value, cst)
+}
@@ -0,0 +1,119 @@
+package bills
+
+import (
+	"io"
+	"time"
+)
+
+// TradeBillRecord 交易账单明细, ALL、SUCCESS、REFUND类型的账单列不同, 账单中不存在的列为零值, 金额单位为分
+// 账单格式说明: https://pay.weixin.qq.com/wiki/doc/apiv3/apis/chapter3_1_6.shtml
+type TradeBillRecord struct {
+	TradeTime           time.Time         `bill:"交易时间"`       // 交易时间(北京时间)
+	AppId               string            `bill:"公众账号ID"`     // 公众账号ID
+	MchId               string            `bill:"商户号"`        // 商户号
+	SubMchId            string            `bill:"特约商户号|子商户号"` // 特约商户号
+	DeviceInfo          string            `bill:"设备号"`        // 设备号
+	TransactionId       string            `bill:"微信订单号"`      // 微信订单号
+	OutTradeNo          string            `bill:"商户订单号"`      // 商户订单号
+	OpenId              string            `bill:"用户标识"`       // 用户标识
+	TradeType           string            `bill:"交易类型"`       // 交易类型, 如: JSAPI
+	TradeState          string            `bill:"交易状态"`       // 交易状态, 如: SUCCESS、REFUND
+	BankType            string            `bill:"付款银行"`       // 付款银行
+	Currency            string            `bill:"货币种类"`       // 货币种类
+	SettlementTotalFee  int64             `bill:"应结订单金额"`     // 应结订单金额
+	CouponFee           int64             `bill:"代金券金额"`      // 代金券金额
+	RefundApplyTime     time.Time         `bill:"退款申请时间"`     // 退款申请时间, 仅REFUND账单
+	RefundSuccessTime   time.Time         `bill:"退款成功时间"`     // 退款成功时间, 仅REFUND账单
+	RefundId            string            `bill:"微信退款单号"`     // 微信退款单号
+	OutRefundNo         string            `bill:"商户退款单号"`     // 商户退款单号
+	SettlementRefundFee int64             `bill:"退款金额"`       // 退款金额
+	CouponRefundFee     int64             `bill:"充值券退款金额"`    // 充值券退款金额
+	RefundType          string            `bill:"退款类型"`       // 退款类型
+	RefundStatus        string            `bill:"退款状态"`       // 退款状态
+	Description         string            `bill:"商品名称"`       // 商品名称
+	Attach              string            `bill:"商户数据包"`      // 商户数据包
+	Fee                 int64             `bill:"手续费"`        // 手续费
+	Rate                string            `bill:"费率"`         // 费率, 如: 0.60%
+	TotalFee            int64             `bill:"订单金额"`       // 订单金额
+	RefundFee           int64             `bill:"申请退款金额"`     // 申请退款金额
+	RateRemark          string            `bill:"费率备注"`       // 费率备注
+	Extra               map[string]string // 没有对应字段的列, key为列名
+}
+
+// TradeBillSummary 交易账单汇总, 金额单位为分
+type TradeBillSummary struct {
+	TotalCount          int   `bill:"总交易单数"`    // 总交易单数
+	SettlementTotalFee  int64 `bill:"应结订单总金额"`  // 应结订单总金额
+	SettlementRefundFee int64 `bill:"退款总金额"`    // 退款总金额
+	CouponRefundFee     int64 `bill:"充值券退款总金额"` // 充值券退款总金额
+	Fee                 int64 `bill:"手续费总金额"`   // 手续费总金额
+	TotalFee            int64 `bill:"订单总金额"`    // 订单总金额
+	RefundFee           int64 `bill:"申请退款总金额"`  // 申请退款总金额
+}
+
+// TradeBill 解析后的交易账单
+type TradeBill struct {
+	Records []*TradeBillRecord // 明细
+	Summary *TradeBillSummary  // 汇总
+}
+
+// TradeBillReader 流式读取交易账单, 用于逐行处理较大的账单文件
+type TradeBillReader struct {
+	reader  *billReader
+	summary *TradeBillSummary
+}
+
+// NewTradeBillReader 创建交易账单读取器, r为下载得到的账单内容(已解压), 会立即读取表头
+func NewTradeBillReader(r io.Reader) (reader *TradeBillReader, err error) {
+	b, err := newBillReader(r)
+	if err != nil {
+		return
+	}
+	reader = &TradeBillReader{reader: b}
+	return
+}
+
+// Next 读取下一条明细, 明细读取完毕后返回io.EOF, 之后可以通过Summary获取汇总数据
+func (t *TradeBillReader) Next() (record *TradeBillRecord, err error) {
+	row, err := t.reader.next()
+	if err == io.EOF && t.summary == nil {
+		t.summary = new(TradeBillSummary)
+		if decodeErr := t.reader.decode(t.reader.summary, t.summary); decodeErr != nil {
+			err = decodeErr
+		}
+		return
+	}
+	if err != nil {
+		return
+	}
+	record = new(TradeBillRecord)
+	if err = t.reader.decode(row, record); err != nil {
+		record = nil
+	}
+	return
+}
+
+// Summary 获取账单汇总, Next返回io.EOF之前为nil
+func (t *TradeBillReader) Summary() *TradeBillSummary {
+	return t.summary
+}
+
+// ParseTradeBill 解析完整的交易账单, 较大的账单请使用NewTradeBillReader逐行处理
+func ParseTradeBill(r io.Reader) (records []*TradeBillRecord, summary *TradeBillSummary, err error) {
+	reader, err := NewTradeBillReader(r)
+	if err != nil {
+		return
+	}
+	for {
+		var record *TradeBillRecord
+		if record, err = reader.Next(); err == io.EOF {
+			break
+		}
+		if err != nil {
+			return
+		}
+		records = append(records, record)
+	}
+	summary, err = reader.Summary(), nil
+	return
+}
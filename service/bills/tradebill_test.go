@@ -0,0 +1,130 @@
+package bills
+
+import (
+	"crypto/sha1"
+	"encoding/hex"
+	stderrors "errors"
+	"io"
+	"net/http"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/pyihe/wechat-sdk/v3/pkg/errors"
+	"github.com/pyihe/wechat-sdk/v3/service/wechatpaytest"
+)
+
+const allTradeBill = "\ufeff交易时间,公众账号ID,商户号,特约商户号,设备号,微信订单号,商户订单号,用户标识,交易类型,交易状态,付款银行,货币种类,应结订单金额,代金券金额,微信退款单号,商户退款单号,退款金额,充值券退款金额,退款类型,退款状态,商品名称,商户数据包,手续费,费率,订单金额,申请退款金额,费率备注\n" +
+	"`2021-11-30 10:00:00,`wx8888888888888888,`1900000001,`0,`,`4200001234202111301234567890,`1217752501201407033233368018,`oUpF8uMuAJO_M2pxb1Q9zNjWeS6o,`JSAPI,`SUCCESS,`OTHERS,`CNY,`100.01,`0.00,`0,`0,`0.00,`0.00,`,`,`Image形象店-深圳腾大-QQ公仔,`,`0.60000,`0.60%,`100.01,`0.00,`\n" +
+	"`2021-11-30 12:30:00,`wx8888888888888888,`1900000001,`0,`,`4200001234202111301234567891,`1217752501201407033233368019,`oUpF8uMuAJO_M2pxb1Q9zNjWeS6o,`JSAPI,`REFUND,`OTHERS,`CNY,`0.00,`0.00,`50000000000000000000000000,`1217752501201407033233368020,`0.50,`0.00,`ORIGINAL,`SUCCESS,`QQ公仔,`attach,`-0.01,`0.60%,`0.00,`0.50,`\n" +
+	"总交易单数,应结订单总金额,退款总金额,充值券退款总金额,手续费总金额,订单总金额,申请退款总金额\n" +
+	"`2,`100.01,`0.50,`0.00,`0.59,`100.01,`0.50\n"
+
+func TestParseTradeBill(t *testing.T) {
+	records, summary, err := ParseTradeBill(strings.NewReader(allTradeBill))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(records) != 2 {
+		t.Fatalf("unexpected records: %d", len(records))
+	}
+	paid, refund := records[0], records[1]
+	if !paid.TradeTime.Equal(time.Date(2021, 11, 30, 2, 0, 0, 0, time.UTC)) || paid.SettlementTotalFee != 10001 || paid.Fee != 60 ||
+		paid.OpenId != "oUpF8uMuAJO_M2pxb1Q9zNjWeS6o" || paid.Rate != "0.60%" || paid.Description != "Image形象店-深圳腾大-QQ公仔" {
+		t.Fatalf("unexpected record: %+v", paid)
+	}
+	if refund.TradeState != "REFUND" || refund.SettlementRefundFee != 50 || refund.RefundFee != 50 || refund.Fee != -1 || refund.OutRefundNo != "1217752501201407033233368020" {
+		t.Fatalf("unexpected refund record: %+v", refund)
+	}
+	if *summary != (TradeBillSummary{TotalCount: 2, SettlementTotalFee: 10001, SettlementRefundFee: 50, Fee: 59, TotalFee: 10001, RefundFee: 50}) {
+		t.Fatalf("unexpected summary: %+v", summary)
+	}
+}
+
+func TestTradeBillReader(t *testing.T) {
+	bill := "交易时间,公众账号ID,商户号,特约商户号,设备号,微信订单号,商户订单号,用户标识,交易类型,交易状态,付款银行,货币种类,应结订单金额,代金券金额,退款申请时间,退款成功时间,微信退款单号,商户退款单号,退款金额,充值券退款金额,退款类型,退款状态,商品名称,商户数据包,手续费,费率,订单金额,申请退款金额,费率备注,新增列\n" +
+		"`2021-11-30 10:00:00,`wx8888888888888888,`1900000001,`0,`,`4200001234202111301234567891,`1217752501201407033233368019,`oUpF8uMuAJO_M2pxb1Q9zNjWeS6o,`JSAPI,`REFUND,`OTHERS,`CNY,`0.00,`0.00,`2021-11-30 12:00:00,`2021-11-30 12:00:05,`50000000000000000000000000,`1217752501201407033233368020,`1,`0.00,`ORIGINAL,`SUCCESS,`QQ公仔,`,`0.00,`0.60%,`0.00,`1.00,`,`x\n" +
+		"`2021-11-30 10:00:00,`wx8888888888888888,`1900000001\n"
+	reader, err := NewTradeBillReader(strings.NewReader(bill))
+	if err != nil {
+		t.Fatal(err)
+	}
+	record, err := reader.Next()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if record.RefundSuccessTime.Sub(record.RefundApplyTime) != 5*time.Second || record.SettlementRefundFee != 100 || record.Extra["新增列"] != "x" {
+		t.Fatalf("unexpected record: %+v", record)
+	}
+	if reader.Summary() != nil {
+		t.Fatal("summary should be nil before EOF")
+	}
+	// 列数不匹配的行返回错误
+	if _, err = reader.Next(); err == nil || err == io.EOF {
+		t.Fatalf("expected column count error, got: %v", err)
+	}
+}
+
+func TestDownloadTradeBillParse(t *testing.T) {
+	server := wechatpaytest.NewServer()
+	defer server.Close()
+
+	sum := sha1.Sum([]byte(allTradeBill))
+	hashValue := hex.EncodeToString(sum[:])
+	server.HandleFunc(http.MethodGet, "/v3/bill/tradebill", func(r *http.Request, body []byte) (int, interface{}) {
+		return http.StatusOK, map[string]string{
+			"hash_type":    "SHA1",
+			"hash_value":   hashValue,
+			"download_url": server.URL + "/v3/billdownload/file?token=" + r.URL.Query().Get("bill_date"),
+		}
+	})
+	server.HandleFunc(http.MethodGet, "/v3/billdownload/file", func(r *http.Request, body []byte) (int, interface{}) {
+		if r.URL.Query().Get("token") != "2021-11-30" {
+			// 内容与摘要值不一致
+			return http.StatusOK, []byte(allTradeBill + "\n")
+		}
+		return http.StatusOK, []byte(allTradeBill)
+	})
+	config := server.NewConfig()
+
+	response, err := DownloadTradeBill(config, &TradeBillRequest{BillDate: "2021-11-30", BillType: "ALL", Parse: true})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if response.TradeBill == nil || len(response.TradeBill.Records) != 2 || response.TradeBill.Summary.TotalFee != 10001 || response.FundFlowBill != nil {
+		t.Fatalf("unexpected bill: %+v", response.TradeBill)
+	}
+
+	response, err = DownloadTradeBill(config, &TradeBillRequest{BillDate: "2021-12-01", Parse: true})
+	if err != errors.ErrCheckHashValueFail || response.TradeBill != nil {
+		t.Fatalf("expected ErrCheckHashValueFail, got: %v", err)
+	}
+
+	// 边下载边逐行处理
+	var count int
+	_, err = DownloadTradeBill(config, &TradeBillRequest{BillDate: "2021-11-30", Handler: func(bill io.Reader) error {
+		reader, err := NewTradeBillReader(bill)
+		if err != nil {
+			return err
+		}
+		for {
+			if _, err = reader.Next(); err != nil {
+				break
+			}
+			count++
+		}
+		if err == io.EOF {
+			err = nil
+		}
+		return err
+	}})
+	if err != nil || count != 2 {
+		t.Fatalf("unexpected records: %d, %v", count, err)
+	}
+	handlerErr := stderrors.New("handler failed")
+	if _, err = DownloadTradeBill(config, &TradeBillRequest{BillDate: "2021-11-30", Handler: func(bill io.Reader) error {
+		return handlerErr
+	}}); err != handlerErr {
+		t.Fatalf("expected handler error, got: %v", err)
+	}
+}
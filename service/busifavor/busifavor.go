@@ -12,6 +12,7 @@ import (
 // CreateStock 创建商家券API
 // 商户平台文档: https://pay.weixin.qq.com/wiki/doc/apiv3/apis/chapter9_2_1.shtml
 // 服务商平台文档: https://pay.weixin.qq.com/wiki/doc/apiv3_partner/apis/chapter9_2_1.shtml
+// request: *CreateStockRequest(签名前会校验参数)或者map等可序列化的参数
 func CreateStock(config *service.Config, request interface{}) (createResponse *CreateStockResponse, err error) {
 	if config == nil {
 		err = errors.ErrNoConfig
@@ -114,6 +115,21 @@ func QueryUserCouponsByFilter(config *service.Config, openId string, filter *Que
 	return
 }
 
+// RangeUserCouponsByFilter 遍历根据过滤条件查询到的用户券的所有分页, filter.Offset为起始页码, filter.Limit为分页大小(最大为50),
+// fn返回false时停止遍历, requestId为最后一次查询的唯一请求ID
+func RangeUserCouponsByFilter(config *service.Config, openId string, filter *QueryUserCouponsByFilterRequest, fn func(coupon *UserCoupon) bool) (requestId string, err error) {
+	if filter == nil {
+		err = errors.ErrNoSDKRequest
+		return
+	}
+	pageRequest := *filter
+	pager := service.Pager{Offset: filter.Offset, PageSize: filter.Limit, MaxPageSize: 50, ByPage: true}
+	return service.RangePages(config, pager, func(offset, limit uint32) (interface{}, error) {
+		pageRequest.Offset, pageRequest.Limit = offset, limit
+		return QueryUserCouponsByFilter(config, openId, &pageRequest)
+	}, fn)
+}
+
 // QueryUserCoupon 查询用户单张券详情
 // 商户平台文档: https://pay.weixin.qq.com/wiki/doc/apiv3/apis/chapter9_2_5.shtml
 // 服务商平台文档: https://pay.weixin.qq.com/wiki/doc/apiv3_partner/apis/chapter9_2_5.shtml
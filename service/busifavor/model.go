@@ -4,8 +4,110 @@ import (
 	"time"
 
 	"github.com/pyihe/wechat-sdk/v3/model"
+	"github.com/pyihe/wechat-sdk/v3/pkg/validate"
 )
 
+// CreateStockRequest 创建商家券请求参数
+type CreateStockRequest struct {
+	StockName          string              `json:"stock_name"`                     // 商家券批次名称
+	BelongMerchant     string              `json:"belong_merchant"`                // 批次归属商户号
+	Comment            string              `json:"comment,omitempty"`              // 批次备注
+	GoodsName          string              `json:"goods_name"`                     // 适用商品范围
+	StockType          string              `json:"stock_type"`                     // 批次类型: NORMAL、DISCOUNT、EXCHANGE
+	CouponUseRule      *CouponUseRule      `json:"coupon_use_rule"`                // 核销规则
+	StockSendRule      *StockSendRule      `json:"stock_send_rule"`                // 发放规则
+	OutRequestNo       string              `json:"out_request_no"`                 // 商户请求单号
+	CustomEntrance     *CustomEntrance     `json:"custom_entrance,omitempty"`      // 自定义入口
+	DisplayPatternInfo *DisplayPatternInfo `json:"display_pattern_info,omitempty"` // 样式信息
+	CouponCodeMode     string              `json:"coupon_code_mode"`               // 券code模式: WECHATPAY_MODE、MERCHANT_API、MERCHANT_UPLOAD
+	NotifyConfig       *NotifyConfig       `json:"notify_config,omitempty"`        // 事件通知配置
+}
+
+func (r *CreateStockRequest) Validate() (err error) {
+	err = validate.First(
+		validate.Required("stock_name", r.StockName),
+		validate.MaxChars("stock_name", r.StockName, 21),
+		validate.Required("belong_merchant", r.BelongMerchant),
+		validate.MaxLength("belong_merchant", r.BelongMerchant, 64),
+		validate.MaxChars("comment", r.Comment, 20),
+		validate.Required("goods_name", r.GoodsName),
+		validate.MaxChars("goods_name", r.GoodsName, 15),
+		validate.Required("stock_type", r.StockType),
+		validate.OneOf("stock_type", r.StockType, "NORMAL", "DISCOUNT", "EXCHANGE"),
+		validate.Required("coupon_use_rule", r.CouponUseRule),
+		validate.Required("stock_send_rule", r.StockSendRule),
+		validate.Required("out_request_no", r.OutRequestNo),
+		validate.MaxLength("out_request_no", r.OutRequestNo, 128),
+		validate.Required("coupon_code_mode", r.CouponCodeMode),
+		validate.OneOf("coupon_code_mode", r.CouponCodeMode, "WECHATPAY_MODE", "MERCHANT_API", "MERCHANT_UPLOAD"),
+	)
+	if err != nil {
+		return
+	}
+	if err = r.validateCouponUseRule(); err != nil {
+		return
+	}
+	rule := r.StockSendRule
+	return validate.First(
+		validate.Min("stock_send_rule.max_coupons", int64(rule.MaxCoupons), 1),
+		validate.Min("stock_send_rule.max_coupons_per_user", int64(rule.MaxCouponsPerUser), 1),
+		validate.Min("stock_send_rule.max_amount", rule.MaxAmount, 0),
+	)
+}
+
+// validateCouponUseRule 校验核销规则, 不同的批次类型需要设置对应的使用规则
+func (r *CreateStockRequest) validateCouponUseRule() (err error) {
+	rule := r.CouponUseRule
+	if err = validate.Required("coupon_use_rule.coupon_available_time", rule.CouponAvailableTime); err != nil {
+		return
+	}
+	availableTime := rule.CouponAvailableTime
+	if availableTime.AvailableBeginTime.IsZero() {
+		return validate.Required("coupon_use_rule.coupon_available_time.available_begin_time", "")
+	}
+	if availableTime.AvailableEndTime.IsZero() {
+		return validate.Required("coupon_use_rule.coupon_available_time.available_end_time", "")
+	}
+	if !availableTime.AvailableBeginTime.Before(availableTime.AvailableEndTime) {
+		return &validate.Error{Field: "coupon_use_rule.coupon_available_time.available_end_time", Reason: "开始时间必须早于结束时间"}
+	}
+
+	switch r.StockType {
+	case "NORMAL":
+		if err = validate.Required("coupon_use_rule.fixed_normal_coupon", rule.FixedNormalCoupon); err != nil {
+			return
+		}
+		err = validate.First(
+			validate.Min("coupon_use_rule.fixed_normal_coupon.discount_amount", rule.FixedNormalCoupon.DiscountAmount, 1),
+			validate.Min("coupon_use_rule.fixed_normal_coupon.transaction_minimum", rule.FixedNormalCoupon.TransactionMinimum, rule.FixedNormalCoupon.DiscountAmount),
+		)
+	case "DISCOUNT":
+		if err = validate.Required("coupon_use_rule.discount_coupon", rule.DiscountCoupon); err != nil {
+			return
+		}
+		err = validate.Range("coupon_use_rule.discount_coupon.discount_percent", int64(rule.DiscountCoupon.DiscountPercent), 1, 99)
+	case "EXCHANGE":
+		if err = validate.Required("coupon_use_rule.exchange_coupon", rule.ExchangeCoupon); err != nil {
+			return
+		}
+		err = validate.Min("coupon_use_rule.exchange_coupon.exchange_price", rule.ExchangeCoupon.ExchangePrice, 0)
+	}
+	if err != nil {
+		return
+	}
+	err = validate.First(
+		validate.Required("coupon_use_rule.use_method", rule.UseMethod),
+		validate.OneOf("coupon_use_rule.use_method", rule.UseMethod, "OFF_LINE", "MINI_PROGRAMS", "SELF_CONSUME", "PAYMENT_CODE"),
+	)
+	if err == nil && rule.UseMethod == "MINI_PROGRAMS" {
+		err = validate.First(
+			validate.Required("coupon_use_rule.mini_programs_appid", rule.MiniProgramsAppId),
+			validate.Required("coupon_use_rule.mini_programs_path", rule.MiniProgramsPath),
+		)
+	}
+	return
+}
+
 // CreateStockResponse 创建商家券应答参数
 type CreateStockResponse struct {
 	model.WechatError
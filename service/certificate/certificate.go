@@ -1,16 +1,17 @@
 package certificate
 
 import (
+	"crypto/rsa"
 	"crypto/x509"
 	"encoding/json"
 	"fmt"
 	"io/ioutil"
 	"net/http"
+	"strings"
 
 	"github.com/pyihe/wechat-sdk/v3/pkg"
-	"github.com/pyihe/wechat-sdk/v3/pkg/errors"
-
 	"github.com/pyihe/wechat-sdk/v3/pkg/aess"
+	"github.com/pyihe/wechat-sdk/v3/pkg/errors"
 	"github.com/pyihe/wechat-sdk/v3/pkg/files"
 	"github.com/pyihe/wechat-sdk/v3/service"
 )
@@ -24,6 +25,38 @@ import (
 // err: error
 // API详细介绍: https://pay.weixin.qq.com/wiki/doc/apiv3_partner/wechatpay/wechatpay5_1.shtml
 func DownloadCertificates(config *service.Config, savePath string) (certsResponse *CertResponse, err error) {
+	certsResponse, plainTexts, err := downloadCertificates(config)
+	if err != nil {
+		return
+	}
+
+	// 同步到内存
+	if config.GetSyncCertificateTag() {
+		for serialNo, value := range certsResponse.Certificates {
+			if certificate, ok := value.(*x509.Certificate); ok {
+				config.AddCertificate(serialNo, certificate)
+			}
+		}
+	}
+	// 同步到本地
+	err = saveCertificates(savePath, certsResponse, plainTexts)
+	return
+}
+
+// saveCertificates 将解密后的证书写入本地目录
+func saveCertificates(savePath string, certsResponse *CertResponse, plainTexts map[string][]byte) (err error) {
+	for _, encryptData := range certsResponse.Data {
+		fileName := fmt.Sprintf("public_key_%s.pem", encryptData.ExpireTime.Format("2006_01_02"))
+		if err = files.WritToFile(savePath, fileName, plainTexts[strings.ToUpper(encryptData.SerialNo)]); err != nil {
+			return
+		}
+	}
+	return
+}
+
+// downloadCertificates 下载并解密微信平台证书, 同时验证下载应答的签名
+// 返回的plainTexts中key为证书序列号, value为解密后的证书内容
+func downloadCertificates(config *service.Config) (certsResponse *CertResponse, plainTexts map[string][]byte, err error) {
 	if config == nil {
 		err = errors.ErrNoConfig
 		return
@@ -38,19 +71,34 @@ func DownloadCertificates(config *service.Config, savePath string) (certsRespons
 		return
 	}
 	certsResponse = new(CertResponse)
+	// 请求失败时不会验证签名, 直接返回微信的错误信息
+	if response.StatusCode != http.StatusOK {
+		certsResponse.RequestId, err = config.ParseWechatResponse(response, certsResponse)
+		if err == nil {
+			err = errors.New(response.StatusCode)
+		}
+		return
+	}
 	certsResponse.RequestId = response.Header.Get("Request-ID")
-	certsResponse.Certificates = pkg.NewParam()
 	content, err := ioutil.ReadAll(response.Body)
 	_ = response.Body.Close()
+	if err != nil {
+		return
+	}
 	if err = json.Unmarshal(content, &certsResponse); err != nil {
 		return
 	}
+	certsResponse.Certificates = pkg.NewParam()
 
 	var cipher = config.GetMerchantCipher()
 	var key = config.GetApiKey()
-	var syncTag = config.GetSyncCertificateTag()
 
+	plainTexts = make(map[string][]byte, len(certsResponse.Data))
 	for _, encryptData := range certsResponse.Data {
+		if encryptData.EncryptCertificate == nil {
+			err = errors.ErrInvalidResource
+			return
+		}
 		// 解密
 		cipherText := encryptData.EncryptCertificate.CipherText
 		associateData := encryptData.EncryptCertificate.AssociatedData
@@ -63,21 +111,24 @@ func DownloadCertificates(config *service.Config, savePath string) (certsRespons
 		if err != nil {
 			return
 		}
-
-		// 同步公钥信息到内存
 		serialNo, certificate, err = files.UnmarshalCertificate(plainText)
 		if err != nil {
 			return
 		}
 		certsResponse.Certificates.Add(serialNo, certificate)
-		if syncTag {
-			config.AddCertificate(serialNo, certificate)
-		}
-		// 同步到本地
-		fileName := fmt.Sprintf("public_key_%s.pem", encryptData.ExpireTime.Format("2006_01_02"))
-		if err = files.WritToFile(savePath, fileName, plainText); err != nil {
-			return
-		}
+		plainTexts[serialNo] = plainText
+	}
+
+	// 验证应答签名, 签名证书可能是本次下载的新证书
+	serialNo := strings.ToUpper(response.Header.Get("Wechatpay-Serial"))
+	publicKey := config.GetRSAPublicKey(serialNo)
+	if certificate, ok := certsResponse.Certificates[serialNo].(*x509.Certificate); ok && publicKey == nil {
+		publicKey, _ = certificate.PublicKey.(*rsa.PublicKey)
+	}
+	if publicKey == nil {
+		err = fmt.Errorf("下载证书失败: Wechatpay-Serial[%s]不存在", serialNo)
+		return
 	}
+	err = config.VerifyWechatSignature(response.Header, content, publicKey)
 	return
 }
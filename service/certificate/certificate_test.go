@@ -0,0 +1,207 @@
+package certificate
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/x509"
+	stderrors "errors"
+	"math/big"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/pyihe/wechat-sdk/v3/service"
+	"github.com/pyihe/wechat-sdk/v3/service/wechatpaytest"
+)
+
+func TestDownloadCertificates(t *testing.T) {
+	server := wechatpaytest.NewServer()
+	defer server.Close()
+
+	config := service.NewConfig(append(server.MerchantOptions(), service.WithSyncCertificate())...)
+	response, err := DownloadCertificates(config, t.TempDir())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(response.Data) != 1 || response.RequestId == "" {
+		t.Fatalf("unexpected response: %+v", response)
+	}
+	if _, ok := config.GetCertificates()[server.PlatformSerialNo()]; !ok {
+		t.Fatalf("certificate %s not synced", server.PlatformSerialNo())
+	}
+}
+
+func TestManagerRotation(t *testing.T) {
+	server := wechatpaytest.NewServer()
+	defer server.Close()
+
+	config := service.NewConfig(server.MerchantOptions()...)
+	manager := NewManager(nil, WithMinRefreshInterval(0))
+	if err := manager.Add(config); err != nil {
+		t.Fatal(err)
+	}
+
+	// 平台证书轮换后, 遇到未知的Wechatpay-Serial时自动下载新证书
+	serialNo := server.RotatePlatformCertificate()
+	response, err := config.RequestWithSign(http.MethodGet, "/v3/certificates", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err = config.ParseWechatResponse(response, new(CertResponse)); err != nil {
+		t.Fatal(err)
+	}
+	if _, ok := config.GetCertificates()[serialNo]; !ok {
+		t.Fatalf("rotated certificate %s not downloaded", serialNo)
+	}
+
+	// 签名错误的请求会被模拟服务器拒绝
+	other := wechatpaytest.NewServer()
+	defer other.Close()
+	config = service.NewConfig(append(other.Options(), service.WithDomain(server.URL))...)
+	response, err = config.RequestWithSign(http.MethodGet, "/v3/certificates", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	_ = response.Body.Close()
+	if response.StatusCode != http.StatusUnauthorized {
+		t.Fatalf("unexpected status: %d", response.StatusCode)
+	}
+}
+
+func TestDownloadCertificatesContext(t *testing.T) {
+	server := wechatpaytest.NewServer()
+	defer server.Close()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+	server.HandleFunc(http.MethodGet, "/v3/certificates", func(r *http.Request, body []byte) (int, interface{}) {
+		select {
+		case <-r.Context().Done():
+		case <-time.After(10 * time.Second):
+		}
+		return http.StatusServiceUnavailable, nil
+	})
+	config := service.NewConfig(server.MerchantOptions()...).WithContext(ctx)
+	if _, err := DownloadCertificates(config, t.TempDir()); !stderrors.Is(err, context.DeadlineExceeded) {
+		t.Fatalf("expected context.DeadlineExceeded, got: %v", err)
+	}
+}
+
+func TestManagerAddDuplicate(t *testing.T) {
+	server := wechatpaytest.NewServer()
+	defer server.Close()
+
+	config := service.NewConfig(server.MerchantOptions()...)
+	manager := NewManager(config)
+	for i := 0; i < 2; i++ {
+		if err := manager.Add(config); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if len(manager.configs) != 1 || len(manager.states) != 1 {
+		t.Fatalf("config should be managed once, got: %d", len(manager.configs))
+	}
+}
+
+func TestManagerRefreshIsolation(t *testing.T) {
+	slow := wechatpaytest.NewServer()
+	defer slow.Close()
+	fast := wechatpaytest.NewServer()
+	defer fast.Close()
+
+	started, release := make(chan struct{}), make(chan struct{})
+	slow.HandleFunc(http.MethodGet, "/v3/certificates", func(r *http.Request, body []byte) (int, interface{}) {
+		close(started)
+		select {
+		case <-release:
+		case <-time.After(10 * time.Second):
+		}
+		return http.StatusServiceUnavailable, nil
+	})
+
+	manager := NewManager(nil)
+	done := make(chan error, 1)
+	go func() {
+		done <- manager.Add(service.NewConfig(slow.MerchantOptions()...))
+	}()
+	<-started
+
+	// 一个商户下载证书缓慢时, 不影响其他商户添加和刷新证书
+	finished := make(chan error, 1)
+	go func() {
+		finished <- manager.Add(service.NewConfig(fast.MerchantOptions()...))
+	}()
+	select {
+	case err := <-finished:
+		if err != nil {
+			t.Fatal(err)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("refresh of one merchant should not block the others")
+	}
+	close(release)
+	if err := <-done; err == nil {
+		t.Fatal("expected error from the slow merchant")
+	}
+}
+
+func TestManagerRemove(t *testing.T) {
+	server := wechatpaytest.NewServer()
+	defer server.Close()
+
+	config := service.NewConfig(server.MerchantOptions()...)
+	manager := NewManager(nil)
+	if err := manager.Add(config); err != nil {
+		t.Fatal(err)
+	}
+	manager.Remove(config)
+	if len(manager.configs) != 0 || len(manager.states) != 0 {
+		t.Fatalf("config should be removed, got: %d", len(manager.configs))
+	}
+	// 移除后再次添加只会管理一次
+	if err := manager.Add(config); err != nil {
+		t.Fatal(err)
+	}
+	if len(manager.configs) != 1 {
+		t.Fatalf("config should be managed once, got: %d", len(manager.configs))
+	}
+}
+
+func TestManagerPruneExpired(t *testing.T) {
+	server := wechatpaytest.NewServer()
+	defer server.Close()
+
+	key, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatal(err)
+	}
+	now := time.Now()
+	template := &x509.Certificate{SerialNumber: big.NewInt(1), NotBefore: now.Add(-48 * time.Hour), NotAfter: now.Add(-time.Hour)}
+	der, err := x509.CreateCertificate(rand.Reader, template, template, &key.PublicKey, key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	expired, err := x509.ParseCertificate(der)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	config := service.NewConfig(server.MerchantOptions()...)
+	config.AddCertificate("EXPIRED", expired)
+	var warned []string
+	manager := NewManager(nil, WithExpiryWarning(time.Hour, func(serialNo string, notAfter time.Time) {
+		warned = append(warned, serialNo)
+	}))
+	if err = manager.Add(config); err != nil {
+		t.Fatal(err)
+	}
+	// 过期的证书先触发预警, 之后从内存中移除
+	certs := config.GetCertificates()
+	if _, ok := certs["EXPIRED"]; ok || len(certs) != 1 {
+		t.Fatalf("expired certificate should be removed, got: %d", len(certs))
+	}
+	if len(warned) != 1 || warned[0] != "EXPIRED" {
+		t.Fatalf("unexpected expiry warnings: %v", warned)
+	}
+}
@@ -0,0 +1,261 @@
+package certificate
+
+import (
+	"context"
+	"crypto/x509"
+	"sync"
+	"time"
+
+	"github.com/pyihe/wechat-sdk/v3/pkg/errors"
+	"github.com/pyihe/wechat-sdk/v3/service"
+)
+
+const (
+	defaultRefreshInterval    = 12 * time.Hour
+	defaultMinRefreshInterval = time.Minute
+	defaultExpiryWarning      = 7 * 24 * time.Hour
+)
+
+// ManagerOption 证书管理器配置项
+type ManagerOption func(*Manager)
+
+// WithRefreshInterval 设置定时刷新证书的间隔, 默认12小时
+func WithRefreshInterval(interval time.Duration) ManagerOption {
+	return func(m *Manager) {
+		if interval > 0 {
+			m.refreshInterval = interval
+		}
+	}
+}
+
+// WithMinRefreshInterval 设置两次刷新证书的最小间隔, 用于避免遇到未知的Wechatpay-Serial时频繁下载证书, 默认1分钟
+func WithMinRefreshInterval(interval time.Duration) ManagerOption {
+	return func(m *Manager) {
+		if interval >= 0 {
+			m.minRefreshInterval = interval
+		}
+	}
+}
+
+// WithExpiryWarning 设置证书过期预警, 每次刷新后, 如果内存中的证书在before时间内过期, 则调用fn, 默认提前7天预警,
+// 已经过期的证书同样会调用fn, 之后从内存中移除
+func WithExpiryWarning(before time.Duration, fn func(serialNo string, notAfter time.Time)) ManagerOption {
+	return func(m *Manager) {
+		if before > 0 {
+			m.expiryWarning = before
+		}
+		m.onExpiring = fn
+	}
+}
+
+// WithErrorHandler 设置后台刷新证书失败时的回调
+func WithErrorHandler(fn func(err error)) ManagerOption {
+	return func(m *Manager) {
+		m.onError = fn
+	}
+}
+
+// WithSavePath 设置证书的本地存放目录, 每次刷新成功后都会将证书写入该目录, 默认不写入本地
+func WithSavePath(savePath string) ManagerOption {
+	return func(m *Manager) {
+		m.savePath = savePath
+	}
+}
+
+// Manager 微信平台证书管理器
+// 启动时下载微信平台证书并同步到Config, 之后定时刷新, 在解析微信应答或者通知遇到未知的Wechatpay-Serial时也会立即刷新,
+// 以保证微信更换平台证书时不影响API调用和回调通知, 一个管理器可以同时管理多个商户的Config
+type Manager struct {
+	refreshInterval    time.Duration
+	minRefreshInterval time.Duration
+	expiryWarning      time.Duration
+	savePath           string
+	onExpiring         func(serialNo string, notAfter time.Time)
+	onError            func(err error)
+
+	mu      sync.Mutex
+	configs []*service.Config
+	states  map[*service.Config]*configState
+	stop    chan struct{}
+	done    chan struct{}
+}
+
+// configState 单个Config的刷新状态, 下载证书时只持有该Config的锁, 一个商户下载缓慢不会阻塞其他商户
+type configState struct {
+	mu          sync.Mutex
+	lastRefresh time.Time
+}
+
+// NewManager 创建证书管理器, 同时将管理器设置为config的证书刷新函数, config为nil时可以之后通过Add添加
+func NewManager(config *service.Config, opts ...ManagerOption) *Manager {
+	m := &Manager{
+		refreshInterval:    defaultRefreshInterval,
+		minRefreshInterval: defaultMinRefreshInterval,
+		expiryWarning:      defaultExpiryWarning,
+		states:             make(map[*service.Config]*configState),
+	}
+	for _, op := range opts {
+		op(m)
+	}
+	if config != nil {
+		m.register(config)
+	}
+	return m
+}
+
+// Add 添加需要管理的Config, 并立即为其下载证书, 重复添加同一个Config时只会管理一次
+func (m *Manager) Add(config *service.Config) error {
+	if config == nil {
+		return errors.ErrNoConfig
+	}
+	m.register(config)
+	return m.refresh(config.Context(), config, true)
+}
+
+// Remove 移除config, 之后不再为其刷新证书, 遇到未知的Wechatpay-Serial时也不再自动下载
+func (m *Manager) Remove(config *service.Config) {
+	m.mu.Lock()
+	_, ok := m.states[config]
+	if ok {
+		delete(m.states, config)
+		for i, c := range m.configs {
+			if c == config {
+				m.configs = append(m.configs[:i], m.configs[i+1:]...)
+				break
+			}
+		}
+	}
+	m.mu.Unlock()
+	if ok {
+		config.SetCertificateRefresher(nil)
+	}
+}
+
+// Start 同步下载一次证书, 成功后在后台定时刷新
+func (m *Manager) Start() (err error) {
+	if err = m.Refresh(); err != nil {
+		return
+	}
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if m.stop != nil {
+		return
+	}
+	m.stop = make(chan struct{})
+	m.done = make(chan struct{})
+	go m.loop(m.stop, m.done)
+	return
+}
+
+// Stop 停止后台刷新
+func (m *Manager) Stop() {
+	m.mu.Lock()
+	stop, done := m.stop, m.done
+	m.stop, m.done = nil, nil
+	m.mu.Unlock()
+	if stop == nil {
+		return
+	}
+	close(stop)
+	<-done
+}
+
+// Refresh 立即为所有Config下载证书, 返回遇到的第一个错误
+func (m *Manager) Refresh() (err error) {
+	m.mu.Lock()
+	configs := append([]*service.Config(nil), m.configs...)
+	m.mu.Unlock()
+	for _, config := range configs {
+		if e := m.refresh(config.Context(), config, true); e != nil && err == nil {
+			err = e
+		}
+	}
+	return
+}
+
+func (m *Manager) register(config *service.Config) {
+	m.mu.Lock()
+	_, ok := m.states[config]
+	if !ok {
+		m.configs = append(m.configs, config)
+		m.states[config] = new(configState)
+	}
+	m.mu.Unlock()
+	if ok {
+		return
+	}
+	config.SetCertificateRefresher(func(ctx context.Context, serialNo string) error {
+		return m.refresh(ctx, config, false)
+	})
+}
+
+func (m *Manager) loop(stop, done chan struct{}) {
+	defer close(done)
+	ticker := time.NewTicker(m.refreshInterval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-stop:
+			return
+		case <-ticker.C:
+			if err := m.Refresh(); err != nil && m.onError != nil {
+				m.onError(err)
+			}
+		}
+	}
+}
+
+// refresh 使用ctx为config下载证书, force为false时, 距离上次刷新不足minRefreshInterval则直接返回
+// 同一个Config的刷新串行执行, 不同Config之间互不影响, 下载期间不持有管理器的锁
+func (m *Manager) refresh(ctx context.Context, config *service.Config, force bool) (err error) {
+	m.mu.Lock()
+	state, ok := m.states[config]
+	m.mu.Unlock()
+	if !ok {
+		return
+	}
+	state.mu.Lock()
+	defer state.mu.Unlock()
+	if !force && time.Since(state.lastRefresh) < m.minRefreshInterval {
+		return
+	}
+	lastRefresh := state.lastRefresh
+	state.lastRefresh = time.Now()
+
+	certsResponse, plainTexts, err := downloadCertificates(config.WithContext(ctx))
+	if err != nil {
+		// 调用方取消导致的失败不计入刷新间隔, 以便其他请求可以立即重试
+		if ctx.Err() != nil {
+			state.lastRefresh = lastRefresh
+		}
+		return
+	}
+	for serialNo, value := range certsResponse.Certificates {
+		certificate, ok := value.(*x509.Certificate)
+		if !ok {
+			continue
+		}
+		config.AddCertificate(serialNo, certificate)
+	}
+	if m.savePath != "" {
+		if err = saveCertificates(m.savePath, certsResponse, plainTexts); err != nil {
+			return
+		}
+	}
+	m.checkExpiry(config)
+	return
+}
+
+// checkExpiry 检查config中即将过期的证书, 并移除已经过期的证书
+func (m *Manager) checkExpiry(config *service.Config) {
+	now := time.Now()
+	deadline := now.Add(m.expiryWarning)
+	for serialNo, certificate := range config.GetCertificates() {
+		if certificate.NotAfter.Before(deadline) && m.onExpiring != nil {
+			m.onExpiring(serialNo, certificate.NotAfter)
+		}
+		if now.After(certificate.NotAfter) {
+			config.RemoveCertificate(serialNo)
+		}
+	}
+}
@@ -9,7 +9,6 @@ import (
 
 	"github.com/pyihe/wechat-sdk/v3/pkg/errors"
 	"github.com/pyihe/wechat-sdk/v3/pkg/files"
-	"github.com/pyihe/wechat-sdk/v3/pkg/rsas"
 	"github.com/pyihe/wechat-sdk/v3/service"
 )
 
@@ -41,22 +40,22 @@ func QueryComplaintList(config *service.Config, request *QueryComplaintListReque
 	}
 	queryResponse = new(QueryComplaintListResponse)
 	queryResponse.RequestId, err = config.ParseWechatResponse(response, queryResponse)
-	if err != nil {
+	return
+}
+
+// RangeComplaintList 遍历投诉单列表的所有分页, request.Offset为起始位置, request.Limit为分页大小(最大为50),
+// fn返回false时停止遍历, requestId为最后一次查询的唯一请求ID
+func RangeComplaintList(config *service.Config, request *QueryComplaintListRequest, fn func(complaint *Complaint) bool) (requestId string, err error) {
+	if request == nil {
+		err = errors.ErrNoSDKRequest
 		return
 	}
-	cipher := config.GetMerchantCipher()
-	for _, complaint := range queryResponse.Data {
-		if complaint == nil || complaint.PayerPhone == "" {
-			continue
-		}
-		var phone []byte
-		phone, err = rsas.DecryptOAEP(cipher, complaint.PayerPhone)
-		if err != nil {
-			return
-		}
-		complaint.PayerPhone = string(phone)
-	}
-	return
+	pageRequest := *request
+	pager := service.Pager{Offset: request.Offset, PageSize: request.Limit, MaxPageSize: 50}
+	return service.RangePages(config, pager, func(offset, limit uint32) (interface{}, error) {
+		pageRequest.Offset, pageRequest.Limit = offset, limit
+		return QueryComplaintList(config, &pageRequest)
+	}, fn)
 }
 
 // QueryComplaintDetail 查询投诉单详情
@@ -77,14 +76,6 @@ func QueryComplaintDetail(config *service.Config, complaintId string) (queryResp
 	}
 	queryResponse = new(QueryComplaintDetailResponse)
 	queryResponse.RequestId, err = config.ParseWechatResponse(response, queryResponse)
-	if err == nil && queryResponse.PayerPhone != "" {
-		var phone []byte
-		phone, err = rsas.DecryptOAEP(config.GetMerchantCipher(), queryResponse.PayerPhone)
-		if err != nil {
-			return
-		}
-		queryResponse.PayerPhone = string(phone)
-	}
 	return
 }
 
@@ -116,6 +107,18 @@ func QueryNegotiationHistory(config *service.Config, complaintId string, limit,
 	return
 }
 
+// RangeNegotiationHistory 遍历投诉协商历史的所有分页(每页最多300条),
+// fn返回false时停止遍历, requestId为最后一次查询的唯一请求ID
+func RangeNegotiationHistory(config *service.Config, complaintId string, fn func(history *NegotiationHistory) bool) (requestId string, err error) {
+	if complaintId == "" {
+		err = errors.ErrParam
+		return
+	}
+	return service.RangePages(config, service.Pager{MaxPageSize: 300}, func(offset, limit uint32) (interface{}, error) {
+		return QueryNegotiationHistory(config, complaintId, limit, offset)
+	}, fn)
+}
+
 // ParseComplaintNotify 解析投诉通知回调
 // 商户平台文档: https://pay.weixin.qq.com/wiki/doc/apiv3/apis/chapter10_2_16.shtml
 // 服务商平台文档: https://pay.weixin.qq.com/wiki/doc/apiv3_partner/apis/chapter10_2_16.shtml
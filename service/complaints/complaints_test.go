@@ -0,0 +1,48 @@
+package complaints
+
+import (
+	"net/http"
+	"strconv"
+	"testing"
+
+	"github.com/pyihe/wechat-sdk/v3/service/wechatpaytest"
+)
+
+func TestRangeComplaintList(t *testing.T) {
+	server := wechatpaytest.NewServer()
+	defer server.Close()
+	server.HandleFunc(http.MethodGet, "/v3/merchant-service/complaints-v2", func(r *http.Request, body []byte) (int, interface{}) {
+		offset, _ := strconv.Atoi(r.URL.Query().Get("offset"))
+		limit, _ := strconv.Atoi(r.URL.Query().Get("limit"))
+		if limit > 50 {
+			return http.StatusBadRequest, wechatpaytest.Error("PARAM_ERROR", "limit超过上限")
+		}
+		var data []map[string]string
+		for i := offset; i < offset+limit && i < 120; i++ {
+			data = append(data, map[string]string{"complaint_id": strconv.Itoa(i)})
+		}
+		return http.StatusOK, map[string]interface{}{"data": data, "limit": limit, "offset": offset, "total_count": 120}
+	})
+	config := server.NewConfig()
+
+	var ids []string
+	requestId, err := RangeComplaintList(config, &QueryComplaintListRequest{BeginDate: "2021-11-01", EndDate: "2021-11-30", Limit: 100}, func(complaint *Complaint) bool {
+		ids = append(ids, complaint.ComplaintId)
+		return true
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(ids) != 120 || ids[0] != "0" || ids[119] != "119" || requestId == "" {
+		t.Fatalf("unexpected complaints: %d, request id: %s", len(ids), requestId)
+	}
+
+	ids = nil
+	_, err = RangeComplaintList(config, &QueryComplaintListRequest{BeginDate: "2021-11-01", EndDate: "2021-11-30", Offset: 10}, func(complaint *Complaint) bool {
+		ids = append(ids, complaint.ComplaintId)
+		return len(ids) < 60
+	})
+	if err != nil || len(ids) != 60 || ids[0] != "10" {
+		t.Fatalf("unexpected complaints: %v, %v", ids, err)
+	}
+}
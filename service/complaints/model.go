@@ -94,19 +94,19 @@ type NegotiationHistory struct {
 
 // Complaint 单条投诉信息
 type Complaint struct {
-	ComplaintId           string            `json:"complaint_id,omitempty"`            // 投诉单号
-	ComplaintTime         time.Time         `json:"complaint_time,omitempty"`          // 投诉时间
-	ComplaintDetail       string            `json:"complaint_detail,omitempty"`        // 投诉详情
-	ComplaintState        string            `json:"complaint_state,omitempty"`         // 投诉单状态
-	ComplaintMchId        string            `json:"complaint_mchid,omitempty"`         // 被投诉商户号
-	PayerPhone            string            `json:"payer_phone,omitempty"`             // 投诉着联系方式
-	PayerOpenId           string            `json:"payer_openid,omitempty"`            // 投诉人openid
-	ComplaintMediaList    []*ComplaintMedia `json:"complaint_media_list,omitempty"`    // 投诉资料列表
-	ComplaintOrderInfo    []*ComplaintOrder `json:"complaint_order_info,omitempty"`    // 投诉单关联订单信息
-	ComplaintFullRefunded bool              `json:"complaint_full_refunded,omitempty"` // 投诉单是否已经全额退款
-	ProblemDescription    string            `json:"problem_description,omitempty"`     // 问题描述
-	IncomingUserResponse  bool              `json:"incoming_user_response,omitempty"`  // 是否有待回复的用户留言
-	UserComplaintTimes    int32             `json:"user_complaint_times,omitempty"`    // 用户投诉次数
+	ComplaintId           string            `json:"complaint_id,omitempty"`                    // 投诉单号
+	ComplaintTime         time.Time         `json:"complaint_time,omitempty"`                  // 投诉时间
+	ComplaintDetail       string            `json:"complaint_detail,omitempty"`                // 投诉详情
+	ComplaintState        string            `json:"complaint_state,omitempty"`                 // 投诉单状态
+	ComplaintMchId        string            `json:"complaint_mchid,omitempty"`                 // 被投诉商户号
+	PayerPhone            string            `json:"payer_phone,omitempty" wechatpay:"encrypt"` // 投诉着联系方式
+	PayerOpenId           string            `json:"payer_openid,omitempty"`                    // 投诉人openid
+	ComplaintMediaList    []*ComplaintMedia `json:"complaint_media_list,omitempty"`            // 投诉资料列表
+	ComplaintOrderInfo    []*ComplaintOrder `json:"complaint_order_info,omitempty"`            // 投诉单关联订单信息
+	ComplaintFullRefunded bool              `json:"complaint_full_refunded,omitempty"`         // 投诉单是否已经全额退款
+	ProblemDescription    string            `json:"problem_description,omitempty"`             // 问题描述
+	IncomingUserResponse  bool              `json:"incoming_user_response,omitempty"`          // 是否有待回复的用户留言
+	UserComplaintTimes    int32             `json:"user_complaint_times,omitempty"`            // 用户投诉次数
 }
 
 // ComplaintMedia 投诉资料列表
@@ -0,0 +1,131 @@
+package service
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/rsa"
+	stderrors "errors"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+// newBlockingServer 返回一个直到客户端断开连接才结束的服务器, hits记录收到的请求数
+func newBlockingServer(hits *int32) *httptest.Server {
+	return httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddInt32(hits, 1)
+		// 读完body之后服务器才能感知到连接断开
+		_, _ = io.Copy(ioutil.Discard, r.Body)
+		select {
+		case <-r.Context().Done():
+		case <-time.After(10 * time.Second):
+		}
+	}))
+}
+
+func TestRequestWithSignContext(t *testing.T) {
+	var hits int32
+	server := newBlockingServer(&hits)
+	defer server.Close()
+	config := newTestConfig(t, server.URL)
+
+	// 超时
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+	start := time.Now()
+	_, err := config.WithContext(ctx).RequestWithSign(http.MethodGet, "/v3/certificates", nil)
+	if !stderrors.Is(err, context.DeadlineExceeded) {
+		t.Fatalf("expected context.DeadlineExceeded, got: %v", err)
+	}
+	if time.Since(start) > 5*time.Second {
+		t.Fatal("request should be aborted by the deadline")
+	}
+
+	// 已经取消的ctx不会发出请求
+	ctx, cancel = context.WithCancel(context.Background())
+	cancel()
+	atomic.StoreInt32(&hits, 0)
+	if _, err = config.WithContext(ctx).RequestWithSign(http.MethodGet, "/v3/certificates", nil); !stderrors.Is(err, context.Canceled) {
+		t.Fatalf("expected context.Canceled, got: %v", err)
+	}
+	if atomic.LoadInt32(&hits) != 0 {
+		t.Fatal("cancelled request should not reach the server")
+	}
+
+	// nil ctx不改变Config
+	if config.WithContext(nil) != config {
+		t.Fatal("WithContext(nil) should return the receiver")
+	}
+}
+
+func TestRetrySleepContext(t *testing.T) {
+	var attempts int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddInt32(&attempts, 1)
+		w.Header().Set("Retry-After", "10")
+		w.WriteHeader(http.StatusServiceUnavailable)
+	}))
+	defer server.Close()
+
+	config := newTestConfig(t, server.URL, WithRetryPolicy(RetryPolicy{MaxAttempts: 3, MaxDelay: 10 * time.Second}))
+	ctx, cancel := context.WithTimeout(context.Background(), 100*time.Millisecond)
+	defer cancel()
+	start := time.Now()
+	_, err := config.WithContext(ctx).RequestWithSign(http.MethodGet, "/v3/certificates", nil)
+	if !stderrors.Is(err, context.DeadlineExceeded) {
+		t.Fatalf("expected context.DeadlineExceeded, got: %v", err)
+	}
+	if time.Since(start) > 5*time.Second || atomic.LoadInt32(&attempts) != 1 {
+		t.Fatalf("retry sleep should be interrupted, attempts: %d", attempts)
+	}
+}
+
+func TestUploadMediaContext(t *testing.T) {
+	var hits int32
+	server := newBlockingServer(&hits)
+	defer server.Close()
+	config := newTestConfig(t, server.URL)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	go func() {
+		for atomic.LoadInt32(&hits) == 0 {
+			time.Sleep(time.Millisecond)
+		}
+		cancel()
+	}()
+	_, err := config.WithContext(ctx).UploadMedia("/v3/merchant/media/upload", "image/png", "a.png", []byte("png"))
+	if !stderrors.Is(err, context.Canceled) {
+		t.Fatalf("expected context.Canceled, got: %v", err)
+	}
+}
+
+type contextKey struct{}
+
+func TestWithContextSharedKeys(t *testing.T) {
+	key, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatal(err)
+	}
+	config := newTestConfig(t, "")
+	// 拷贝上添加的公钥对原Config同样生效
+	ctx := context.WithValue(context.Background(), contextKey{}, "caller")
+	copied := config.WithContext(ctx)
+	copied.AddPublicKey("PUB_KEY_ID_NEW", &key.PublicKey)
+	if serialNo, _ := config.GetValidPublicKey(); serialNo != "PUB_KEY_ID_NEW" {
+		t.Fatalf("public key added on the copy should be shared, got: %s", serialNo)
+	}
+
+	// 拷贝之后设置的刷新函数对拷贝同样生效, 并且使用拷贝绑定的上下文
+	var got interface{}
+	config.SetCertificateRefresher(func(ctx context.Context, serialNo string) error {
+		got = ctx.Value(contextKey{})
+		return nil
+	})
+	if copied.lookupPublicKey("UNKNOWN_SERIAL") != nil || got != "caller" {
+		t.Fatalf("refresher should use the caller's context, got: %v", got)
+	}
+}
@@ -0,0 +1,152 @@
+package service
+
+import (
+	"bufio"
+	"bytes"
+	"compress/gzip"
+	"crypto/sha1"
+	"crypto/sha256"
+	"encoding/hex"
+	"hash"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"os"
+	"path"
+	"strings"
+
+	"github.com/pyihe/wechat-sdk/v3/pkg/errors"
+	"github.com/pyihe/wechat-sdk/v3/pkg/files"
+)
+
+// 下载失败时最多读取的应答body长度
+const maxDownloadErrorBody = 64 << 10
+
+// DownloadOptions 下载时的摘要校验选项, 微信的下载接口应答不包含签名, 需要通过申请下载时返回的摘要值校验文件完整性
+type DownloadOptions struct {
+	HashType  string // 摘要类型: SHA1、SHA256, 为空时不计算摘要
+	HashValue string // 期望的摘要值, 为空时只计算不校验, gzip压缩的账单为解压后内容的摘要值
+}
+
+// DownloadResult 下载结果
+type DownloadResult struct {
+	RequestId string // 唯一请求ID
+	Size      int64  // 写入的字节数(gzip压缩的数据为解压后的字节数)
+	Gzip      bool   // 下载的数据是否为gzip压缩(如申请账单时tar_type为GZIP)
+	HashValue string // 计算得到的摘要值(小写十六进制), 未指定HashType时为空
+}
+
+// Download 下载URL对应的数据, 大文件(如账单)请使用DownloadTo或者DownloadToFile
+func (c *Config) Download(url string) (data []byte, err error) {
+	var buf bytes.Buffer
+	if _, err = c.DownloadTo(url, &buf, nil); err != nil {
+		return
+	}
+	data = buf.Bytes()
+	return
+}
+
+// DownloadTo 将URL对应的数据流式写入w, 同时计算摘要值, gzip压缩的数据会自动解压
+// http状态码不为200时返回*errors.APIError, 摘要值不匹配时返回errors.ErrCheckHashValueFail, 此时数据已经写入w, 调用方需要丢弃
+func (c *Config) DownloadTo(url string, w io.Writer, options *DownloadOptions) (result *DownloadResult, err error) {
+	if options == nil {
+		options = new(DownloadOptions)
+	}
+	hasher, err := newDownloadHash(options.HashType)
+	if err != nil {
+		return
+	}
+	if strs := strings.Split(url, c.domain); len(strs) > 1 {
+		url = strs[1]
+	}
+	response, err := c.requestWithSign(OperationDownload, http.MethodGet, url, nil)
+	if err != nil {
+		return
+	}
+	defer response.Body.Close()
+
+	result = &DownloadResult{RequestId: response.Header.Get("Request-ID")}
+	defer func() {
+		if c.logger != nil {
+			c.debug("微信支付下载", "status", response.StatusCode, "request_id", result.RequestId, "headers", c.redactHeader(response.Header),
+				"size", result.Size, "gzip", result.Gzip, "error", c.redactError(err))
+		}
+	}()
+	if response.StatusCode != http.StatusOK {
+		body, _ := ioutil.ReadAll(io.LimitReader(response.Body, maxDownloadErrorBody))
+		err = errors.NewAPIError(response.StatusCode, result.RequestId, body)
+		return
+	}
+
+	// 根据gzip的魔数判断是否需要解压
+	reader := bufio.NewReader(response.Body)
+	var src io.Reader = reader
+	if magic, _ := reader.Peek(2); len(magic) == 2 && magic[0] == 0x1f && magic[1] == 0x8b {
+		var gz *gzip.Reader
+		if gz, err = gzip.NewReader(reader); err != nil {
+			return
+		}
+		defer gz.Close()
+		src = gz
+		result.Gzip = true
+	}
+	if hasher != nil {
+		w = io.MultiWriter(w, hasher)
+	}
+	if result.Size, err = io.Copy(w, src); err != nil {
+		return
+	}
+	if hasher != nil {
+		result.HashValue = hex.EncodeToString(hasher.Sum(nil))
+		if options.HashValue != "" && !strings.EqualFold(options.HashValue, result.HashValue) {
+			err = errors.ErrCheckHashValueFail
+		}
+	}
+	return
+}
+
+// DownloadToFile 将URL对应的数据下载到filePath目录下的fileName文件中
+// 数据先写入临时文件, 下载成功且摘要值校验通过后才会重命名为目标文件, 失败时不会留下不完整的文件
+func (c *Config) DownloadToFile(url, filePath, fileName string, options *DownloadOptions) (result *DownloadResult, err error) {
+	if filePath == "" {
+		filePath = "files"
+	}
+	if fileName == "" {
+		fileName = "newfile"
+	}
+	if err = files.MakeNewPath(filePath); err != nil {
+		return
+	}
+	target := path.Join(filePath, fileName)
+	f, err := ioutil.TempFile(filePath, fileName+".*.tmp")
+	if err != nil {
+		return
+	}
+	defer func() {
+		if err != nil {
+			_ = os.Remove(f.Name())
+		}
+	}()
+	result, err = c.DownloadTo(url, f, options)
+	if closeErr := f.Close(); err == nil {
+		err = closeErr
+	}
+	if err != nil {
+		return
+	}
+	err = os.Rename(f.Name(), target)
+	return
+}
+
+// newDownloadHash 根据摘要类型创建hash, hashType为空时返回nil
+func newDownloadHash(hashType string) (hash.Hash, error) {
+	switch strings.ToUpper(hashType) {
+	case "":
+		return nil, nil
+	case "SHA1":
+		return sha1.New(), nil
+	case "SHA256":
+		return sha256.New(), nil
+	}
+	return nil, errors.ErrInvalidHashType
+}
@@ -0,0 +1,92 @@
+package service
+
+import (
+	"bytes"
+	"compress/gzip"
+	"crypto/sha1"
+	"crypto/sha256"
+	"encoding/hex"
+	stderrors "errors"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/pyihe/wechat-sdk/v3/pkg/errors"
+)
+
+func TestDownloadTo(t *testing.T) {
+	bill := []byte("交易时间,公众账号ID,商户号\n`2021-11-30 10:00:00,`wx8888888888888888,`1900000001\n")
+	var compressed bytes.Buffer
+	gz := gzip.NewWriter(&compressed)
+	_, _ = gz.Write(bill)
+	_ = gz.Close()
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Query().Get("token") {
+		case "plain":
+			_, _ = w.Write(bill)
+		case "gzip":
+			_, _ = w.Write(compressed.Bytes())
+		default:
+			w.Header().Set("Request-ID", "08F78BB5AF0610D302A6E8BE1E")
+			w.WriteHeader(http.StatusBadRequest)
+			_, _ = w.Write([]byte(`{"code":"INVALID_REQUEST","message":"token已过期"}`))
+		}
+	}))
+	defer server.Close()
+	config := newTestConfig(t, server.URL)
+
+	sum1 := sha1.Sum(bill)
+	sum256 := sha256.Sum256(bill)
+	var buf bytes.Buffer
+	result, err := config.DownloadTo(server.URL+"/v3/billdownload/file?token=plain", &buf, &DownloadOptions{HashType: "SHA1", HashValue: hex.EncodeToString(sum1[:])})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(buf.Bytes(), bill) || result.Gzip || result.Size != int64(len(bill)) {
+		t.Fatalf("unexpected result: %+v", result)
+	}
+
+	// gzip压缩的账单自动解压, 摘要值为解压后内容的摘要
+	buf.Reset()
+	result, err = config.DownloadTo(server.URL+"/v3/billdownload/file?token=gzip", &buf, &DownloadOptions{HashType: "SHA256", HashValue: hex.EncodeToString(sum256[:])})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(buf.Bytes(), bill) || !result.Gzip {
+		t.Fatalf("unexpected gzip result: %+v", result)
+	}
+
+	if _, err = config.DownloadTo(server.URL+"/v3/billdownload/file?token=plain", ioutil.Discard, &DownloadOptions{HashType: "SHA1", HashValue: "0000"}); err != errors.ErrCheckHashValueFail {
+		t.Fatalf("expected ErrCheckHashValueFail, got: %v", err)
+	}
+	if _, err = config.DownloadTo(server.URL+"/v3/billdownload/file?token=plain", ioutil.Discard, &DownloadOptions{HashType: "SM3"}); err != errors.ErrInvalidHashType {
+		t.Fatalf("expected ErrInvalidHashType, got: %v", err)
+	}
+	var apiErr *errors.APIError
+	if _, err = config.Download(server.URL + "/v3/billdownload/file?token=expired"); !stderrors.As(err, &apiErr) || apiErr.Code != "INVALID_REQUEST" || apiErr.RequestId == "" {
+		t.Fatalf("expected APIError, got: %v", err)
+	}
+
+	// 写入文件失败时不会留下不完整的文件
+	dir, err := ioutil.TempDir("", "bills")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	if _, err = config.DownloadToFile(server.URL+"/v3/billdownload/file?token=gzip", dir, "bad.csv", &DownloadOptions{HashType: "SHA1", HashValue: "0000"}); err == nil {
+		t.Fatal("expected hash error")
+	}
+	if entries, _ := ioutil.ReadDir(dir); len(entries) != 0 {
+		t.Fatalf("unexpected files: %v", entries)
+	}
+	if _, err = config.DownloadToFile(server.URL+"/v3/billdownload/file?token=gzip", dir, "bill.csv", &DownloadOptions{HashType: "SHA1", HashValue: hex.EncodeToString(sum1[:])}); err != nil {
+		t.Fatal(err)
+	}
+	if data, _ := ioutil.ReadFile(filepath.Join(dir, "bill.csv")); !bytes.Equal(data, bill) {
+		t.Fatalf("unexpected file content: %s", data)
+	}
+}
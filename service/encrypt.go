@@ -0,0 +1,331 @@
+package service
+
+import (
+	"fmt"
+	"reflect"
+	"sync"
+
+	"github.com/pyihe/wechat-sdk/v3/pkg/rsas"
+)
+
+const (
+	// TagName 敏感字段使用的struct tag名称, 如: Name string `json:"name" wechatpay:"encrypt"`
+	TagName = "wechatpay"
+	// TagEncrypt 敏感字段: 请求参数中的字段会使用微信支付平台公钥加密, 应答中的字段会使用商户私钥解密,
+	// 请求与应答共用该tag, 因此不要将带有该tag的请求类型用于解析明文返回对应字段的应答, 否则解密会失败
+	TagEncrypt = "encrypt"
+)
+
+// 记录每个类型是否包含需要加密的字段, value为*encryptType
+var encryptTypes sync.Map
+
+// encryptType 类型中需要加密的字段信息
+type encryptType struct {
+	tagged  bool // 包含tag为wechatpay:"encrypt"的字段
+	dynamic bool // 包含interface, 是否需要加密取决于实际的值
+}
+
+// NeedEncrypt 判断request中是否包含tag为wechatpay:"encrypt"的字段, 包括interface中实际的值
+func NeedEncrypt(request interface{}) bool {
+	if request == nil {
+		return false
+	}
+	value := reflect.ValueOf(request)
+	if value.Kind() == reflect.Ptr && value.IsNil() {
+		return false
+	}
+	return needEncrypt(value)
+}
+
+// EncryptRequest 使用微信支付平台公钥加密request中所有tag为wechatpay:"encrypt"的字符串字段(包括嵌套的结构体、指针、切片、map、interface),
+// 只拷贝通往敏感字段的路径, 其余部分(如nil切片、time.Time)与request共享且保持原样, request本身不会被修改,
+// 返回加密后的拷贝以及需要放在请求头Wechatpay-Serial中的平台证书序列号或公钥ID
+// 空字符串不会被加密, tag为wechatpay:"encrypt"的字段不是字符串时返回error
+func (c *Config) EncryptRequest(request interface{}) (encrypted interface{}, serialNo string, err error) {
+	serialNo, cipher, err := c.GetEncryptCipher()
+	if err != nil {
+		return
+	}
+	value, _, err := encryptSensitive(reflect.ValueOf(request), func(plainText string) (string, error) {
+		return rsas.EncryptOAEP(cipher, plainText)
+	})
+	if err != nil {
+		return
+	}
+	encrypted = value.Interface()
+	return
+}
+
+// WithKeepCiphertext 解析应答时不解密tag为wechatpay:"encrypt"的字段, 保留密文, 如用于合规日志
+func WithKeepCiphertext() Option {
+	return func(config *Config) {
+		config.keepCiphertext = true
+	}
+}
+
+// KeepCiphertext 返回一个解析应答时保留密文的Config浅拷贝, 原Config不受影响
+func (c *Config) KeepCiphertext() *Config {
+	c2 := new(Config)
+	*c2 = *c
+	c2.keepCiphertext = true
+	return c2
+}
+
+// DecryptResponse 使用商户私钥(或者Decrypter)解密dst中所有tag为wechatpay:"encrypt"的字符串字段, dst必须为指针
+func (c *Config) DecryptResponse(dst interface{}) error {
+	return walkSensitive(reflect.ValueOf(dst), func(cipherText string) (string, error) {
+		plainText, err := c.DecryptOAEP(cipherText)
+		return string(plainText), err
+	})
+}
+
+// Encrypt 使用微信支付平台公钥加密单个敏感信息, 返回密文以及对应的平台证书序列号或公钥ID
+func (c *Config) Encrypt(plainText string) (cipherText string, serialNo string, err error) {
+	serialNo, cipher, err := c.GetEncryptCipher()
+	if err != nil {
+		return
+	}
+	cipherText, err = rsas.EncryptOAEP(cipher, plainText)
+	return
+}
+
+// walkSensitive 遍历value中所有tag为wechatpay:"encrypt"的非空字符串字段, 并使用fn的返回值替换字段的值
+func walkSensitive(value reflect.Value, fn func(string) (string, error)) (err error) {
+	switch value.Kind() {
+	case reflect.Ptr:
+		if value.IsNil() {
+			return
+		}
+		return walkSensitive(value.Elem(), fn)
+	case reflect.Interface:
+		if value.IsNil() {
+			return
+		}
+		elem := value.Elem()
+		if elem.Kind() == reflect.Ptr || !value.CanSet() || !needEncrypt(elem) {
+			return walkSensitive(elem, fn)
+		}
+		// interface中的非指针值不可修改, 拷贝后遍历再写回
+		copied := reflect.New(elem.Type()).Elem()
+		copied.Set(elem)
+		if err = walkSensitive(copied, fn); err != nil {
+			return
+		}
+		value.Set(copied)
+	case reflect.Map:
+		if value.IsNil() || !needEncrypt(value) {
+			return
+		}
+		// map的值不可修改, 拷贝后遍历再写回
+		for _, key := range value.MapKeys() {
+			elem := reflect.New(value.Type().Elem()).Elem()
+			elem.Set(value.MapIndex(key))
+			if err = walkSensitive(elem, fn); err != nil {
+				return
+			}
+			value.SetMapIndex(key, elem)
+		}
+	case reflect.Slice, reflect.Array:
+		for i := 0; i < value.Len(); i++ {
+			if err = walkSensitive(value.Index(i), fn); err != nil {
+				return
+			}
+		}
+	case reflect.Struct:
+		valueType := value.Type()
+		for i := 0; i < value.NumField(); i++ {
+			field, fieldType := value.Field(i), valueType.Field(i)
+			if !field.CanSet() {
+				continue
+			}
+			if fieldType.Tag.Get(TagName) == TagEncrypt && field.Kind() == reflect.String {
+				if field.String() == "" {
+					continue
+				}
+				var text string
+				if text, err = fn(field.String()); err != nil {
+					return
+				}
+				field.SetString(text)
+				continue
+			}
+			if err = walkSensitive(field, fn); err != nil {
+				return
+			}
+		}
+	}
+	return
+}
+
+// encryptSensitive 返回value加密后的值, 只有包含需要加密字段的指针、切片、map、interface以及结构体会被拷贝, changed为false时返回value本身
+func encryptSensitive(value reflect.Value, fn func(string) (string, error)) (result reflect.Value, changed bool, err error) {
+	result = value
+	if !value.IsValid() || !needEncrypt(value) {
+		return
+	}
+	switch value.Kind() {
+	case reflect.Ptr:
+		var elem reflect.Value
+		if elem, changed, err = encryptSensitive(value.Elem(), fn); err != nil || !changed {
+			return
+		}
+		result = reflect.New(elem.Type())
+		result.Elem().Set(elem)
+	case reflect.Interface:
+		var elem reflect.Value
+		if elem, changed, err = encryptSensitive(value.Elem(), fn); err != nil || !changed {
+			return
+		}
+		result = reflect.New(value.Type()).Elem()
+		result.Set(elem)
+	case reflect.Slice, reflect.Array:
+		for i := 0; i < value.Len(); i++ {
+			var elem reflect.Value
+			var elemChanged bool
+			if elem, elemChanged, err = encryptSensitive(value.Index(i), fn); err != nil {
+				return
+			}
+			if !elemChanged {
+				continue
+			}
+			if !changed {
+				changed = true
+				if value.Kind() == reflect.Slice {
+					result = reflect.MakeSlice(value.Type(), value.Len(), value.Len())
+				} else {
+					result = reflect.New(value.Type()).Elem()
+				}
+				reflect.Copy(result, value)
+			}
+			result.Index(i).Set(elem)
+		}
+	case reflect.Map:
+		iter := value.MapRange()
+		for iter.Next() {
+			var elem reflect.Value
+			var elemChanged bool
+			if elem, elemChanged, err = encryptSensitive(iter.Value(), fn); err != nil {
+				return
+			}
+			if !elemChanged {
+				continue
+			}
+			if !changed {
+				changed = true
+				result = reflect.MakeMapWithSize(value.Type(), value.Len())
+				copyIter := value.MapRange()
+				for copyIter.Next() {
+					result.SetMapIndex(copyIter.Key(), copyIter.Value())
+				}
+			}
+			result.SetMapIndex(iter.Key(), elem)
+		}
+	case reflect.Struct:
+		valueType := value.Type()
+		for i := 0; i < value.NumField(); i++ {
+			fieldType := valueType.Field(i)
+			if fieldType.PkgPath != "" {
+				continue
+			}
+			field := value.Field(i)
+			var fieldChanged bool
+			if fieldType.Tag.Get(TagName) == TagEncrypt {
+				if field.Kind() != reflect.String {
+					err = fmt.Errorf("加密敏感信息失败: %s.%s的类型必须为string", valueType.Name(), fieldType.Name)
+					return
+				}
+				if field.String() != "" {
+					var text string
+					if text, err = fn(field.String()); err != nil {
+						return
+					}
+					field, fieldChanged = reflect.ValueOf(text).Convert(field.Type()), true
+				}
+			} else if field, fieldChanged, err = encryptSensitive(field, fn); err != nil {
+				return
+			}
+			if !fieldChanged {
+				continue
+			}
+			if !changed {
+				changed = true
+				result = reflect.New(valueType).Elem()
+				result.Set(value)
+			}
+			result.Field(i).Set(field)
+		}
+	}
+	return
+}
+
+// needEncrypt 判断value中是否包含需要加密的字段, 类型中包含interface时检查实际的值
+func needEncrypt(value reflect.Value) bool {
+	info := lookupEncryptType(value.Type())
+	if info.tagged {
+		return true
+	}
+	if !info.dynamic {
+		return false
+	}
+	switch value.Kind() {
+	case reflect.Ptr, reflect.Interface:
+		return !value.IsNil() && needEncrypt(value.Elem())
+	case reflect.Slice, reflect.Array:
+		for i := 0; i < value.Len(); i++ {
+			if needEncrypt(value.Index(i)) {
+				return true
+			}
+		}
+	case reflect.Map:
+		iter := value.MapRange()
+		for iter.Next() {
+			if needEncrypt(iter.Value()) {
+				return true
+			}
+		}
+	case reflect.Struct:
+		for i := 0; i < value.NumField(); i++ {
+			if value.Type().Field(i).PkgPath == "" && needEncrypt(value.Field(i)) {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// lookupEncryptType 获取类型t中需要加密的字段信息
+func lookupEncryptType(t reflect.Type) *encryptType {
+	if cached, ok := encryptTypes.Load(t); ok {
+		return cached.(*encryptType)
+	}
+	info := new(encryptType)
+	scanEncryptType(t, make(map[reflect.Type]bool), info)
+	encryptTypes.Store(t, info)
+	return info
+}
+
+func scanEncryptType(t reflect.Type, visited map[reflect.Type]bool, info *encryptType) {
+	if visited[t] {
+		return
+	}
+	visited[t] = true
+
+	switch t.Kind() {
+	case reflect.Interface:
+		info.dynamic = true
+	case reflect.Ptr, reflect.Slice, reflect.Array, reflect.Map:
+		scanEncryptType(t.Elem(), visited, info)
+	case reflect.Struct:
+		for i := 0; i < t.NumField(); i++ {
+			field := t.Field(i)
+			if field.PkgPath != "" {
+				continue
+			}
+			if field.Tag.Get(TagName) == TagEncrypt {
+				info.tagged = true
+				continue
+			}
+			scanEncryptType(field.Type, visited, info)
+		}
+	}
+}
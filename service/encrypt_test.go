@@ -0,0 +1,264 @@
+package service
+
+import (
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha1"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/json"
+	"encoding/pem"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+)
+
+type testContact struct {
+	Name   string `json:"name" wechatpay:"encrypt"`
+	Mobile string `json:"mobile,omitempty" wechatpay:"encrypt"`
+	OpenId string `json:"openid"`
+}
+
+type testApplyRequest struct {
+	BusinessCode string         `json:"business_code"`
+	Contact      *testContact   `json:"contact"`
+	Receivers    []*testContact `json:"receivers"`
+}
+
+func TestEncryptRequest(t *testing.T) {
+	const keyId = "PUB_KEY_ID_0114232134912410000000000000"
+	platformKey, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatal(err)
+	}
+	der, err := x509.MarshalPKIXPublicKey(&platformKey.PublicKey)
+	if err != nil {
+		t.Fatal(err)
+	}
+	publicKeyPEM := string(pem.EncodeToMemory(&pem.Block{Type: "PUBLIC KEY", Bytes: der}))
+	decrypt := func(cipherText string) string {
+		data, err := base64.StdEncoding.DecodeString(cipherText)
+		if err != nil {
+			t.Fatal(err)
+		}
+		plainText, err := rsa.DecryptOAEP(sha1.New(), rand.Reader, platformKey, data, nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return string(plainText)
+	}
+
+	var serialNo string
+	var received testApplyRequest
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		serialNo = r.Header.Get("Wechatpay-Serial")
+		body, _ := ioutil.ReadAll(r.Body)
+		_ = json.Unmarshal(body, &received)
+		w.WriteHeader(http.StatusNoContent)
+	}))
+	defer server.Close()
+
+	config := newTestConfig(t, server.URL, WithPlatformPublicKey(keyId, publicKeyPEM))
+	request := &testApplyRequest{
+		BusinessCode: "1900013511_10000",
+		Contact:      &testContact{Name: "张三", Mobile: "13800138000", OpenId: "oUpF8uMuAJO_M2pxb1Q9zNjWeS6o"},
+		Receivers:    []*testContact{{Name: "李四"}, nil},
+	}
+	if !NeedEncrypt(request) || NeedEncrypt(map[string]string{}) || NeedEncrypt((*testApplyRequest)(nil)) {
+		t.Fatal("unexpected NeedEncrypt result")
+	}
+
+	response, err := config.RequestWithSign(http.MethodPost, "/v3/applyment4sub/applyment/", request)
+	if err != nil {
+		t.Fatal(err)
+	}
+	discardResponse(response)
+	if serialNo != keyId {
+		t.Fatalf("unexpected Wechatpay-Serial: %s", serialNo)
+	}
+	// 原请求不会被修改
+	if request.Contact.Name != "张三" || request.Receivers[0].Name != "李四" {
+		t.Fatalf("request modified: %+v", request.Contact)
+	}
+	if decrypt(received.Contact.Name) != "张三" || decrypt(received.Contact.Mobile) != "13800138000" || decrypt(received.Receivers[0].Name) != "李四" {
+		t.Fatalf("unexpected encrypted request: %+v", received.Contact)
+	}
+	if received.Contact.OpenId != request.Contact.OpenId || received.BusinessCode != request.BusinessCode {
+		t.Fatalf("untagged fields should not be encrypted: %+v", received)
+	}
+
+	// 非指针类型, 空字符串不加密
+	encrypted, _, err := config.EncryptRequest(testContact{Name: "王五"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	contact := encrypted.(testContact)
+	if decrypt(contact.Name) != "王五" || contact.Mobile != "" {
+		t.Fatalf("unexpected encrypted contact: %+v", contact)
+	}
+}
+
+func TestDecryptResponse(t *testing.T) {
+	const keyId = "PUB_KEY_ID_0114232134912410000000000000"
+	platformKey, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatal(err)
+	}
+	der, err := x509.MarshalPKIXPublicKey(&platformKey.PublicKey)
+	if err != nil {
+		t.Fatal(err)
+	}
+	publicKeyPEM := string(pem.EncodeToMemory(&pem.Block{Type: "PUBLIC KEY", Bytes: der}))
+	merchantKey, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatal(err)
+	}
+	data, err := rsa.EncryptOAEP(sha1.New(), rand.Reader, &merchantKey.PublicKey, []byte("13800138000"), nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	cipherText := base64.StdEncoding.EncodeToString(data)
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := json.Marshal(testApplyRequest{
+			BusinessCode: "1900013511_10000",
+			Receivers:    []*testContact{{Mobile: cipherText, OpenId: "oUpF8uMuAJO_M2pxb1Q9zNjWeS6o"}},
+		})
+		signWechatResponse(t, w, platformKey, keyId, body)
+	}))
+	defer server.Close()
+
+	// 测试服务器使用固定的随机串, 因此关闭重放校验
+	config := newTestConfig(t, server.URL, WithPlatformPublicKey(keyId, publicKeyPEM), WithNonceStore(nil))
+	if err = config.GetMerchantCipher().SetRSAPrivateKey(merchantKey, 0); err != nil {
+		t.Fatal(err)
+	}
+	query := func(config *Config) *testApplyRequest {
+		response, err := config.RequestWithSign(http.MethodGet, "/v3/applyment4sub/applyment/business_code/1900013511_10000", nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+		result := new(testApplyRequest)
+		if _, err = config.ParseWechatResponse(response, result); err != nil {
+			t.Fatal(err)
+		}
+		return result
+	}
+
+	if result := query(config); result.Receivers[0].Mobile != "13800138000" || result.Receivers[0].Name != "" {
+		t.Fatalf("unexpected decrypted response: %+v", result.Receivers[0])
+	}
+	// 保留密文
+	if result := query(config.KeepCiphertext()); result.Receivers[0].Mobile != cipherText {
+		t.Fatalf("ciphertext should be kept: %+v", result.Receivers[0])
+	}
+	if result := query(config); result.Receivers[0].Mobile != "13800138000" {
+		t.Fatal("KeepCiphertext should not modify the original config")
+	}
+}
+
+func TestEncryptRequestClone(t *testing.T) {
+	const keyId = "PUB_KEY_ID_0114232134912410000000000000"
+	platformKey, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatal(err)
+	}
+	der, err := x509.MarshalPKIXPublicKey(&platformKey.PublicKey)
+	if err != nil {
+		t.Fatal(err)
+	}
+	publicKeyPEM := string(pem.EncodeToMemory(&pem.Block{Type: "PUBLIC KEY", Bytes: der}))
+	config := newTestConfig(t, "", WithPlatformPublicKey(keyId, publicKeyPEM))
+	decrypt := func(cipherText string) string {
+		data, _ := base64.StdEncoding.DecodeString(cipherText)
+		plainText, err := rsa.DecryptOAEP(sha1.New(), rand.Reader, platformKey, data, nil)
+		if err != nil {
+			t.Fatalf("decrypt %q: %v", cipherText, err)
+		}
+		return string(plainText)
+	}
+
+	// 未包含敏感字段的部分保持原样: nil切片、time.Time
+	type withTime struct {
+		Contact   testContact    `json:"contact"`
+		Receivers []*testContact `json:"receivers"`
+		Extra     map[string]int `json:"extra"`
+		CreatedAt time.Time      `json:"created_at"`
+	}
+	createdAt := time.Date(2021, 11, 30, 10, 0, 0, 0, time.FixedZone("CST", 8*3600))
+	encrypted, _, err := config.EncryptRequest(&withTime{Contact: testContact{Name: "张三"}, CreatedAt: createdAt})
+	if err != nil {
+		t.Fatal(err)
+	}
+	request := encrypted.(*withTime)
+	if decrypt(request.Contact.Name) != "张三" || !request.CreatedAt.Equal(createdAt) || request.Receivers != nil || request.Extra != nil {
+		t.Fatalf("unexpected encrypted request: %+v", request)
+	}
+	body, _ := json.Marshal(request)
+	if !strings.Contains(string(body), `"receivers":null`) || !strings.Contains(string(body), `"extra":null`) {
+		t.Fatalf("nil fields should be kept: %s", body)
+	}
+
+	// map以及interface中的敏感字段
+	type dynamic struct {
+		Receivers map[string]*testContact `json:"receivers"`
+		Payload   interface{}             `json:"payload"`
+		Untagged  interface{}             `json:"untagged"`
+	}
+	original := &dynamic{
+		Receivers: map[string]*testContact{"a": {Name: "李四"}, "b": {OpenId: "oUpF8uMuAJO_M2pxb1Q9zNjWeS6o"}},
+		Payload:   []testContact{{Name: "王五"}},
+		Untagged:  "plain",
+	}
+	type payload struct {
+		Data interface{} `json:"data"`
+	}
+	if !NeedEncrypt(&payload{Data: []*testContact{{}}}) || NeedEncrypt(&payload{Data: "plain"}) || NeedEncrypt(payload{}) {
+		t.Fatal("unexpected NeedEncrypt result for interface")
+	}
+	if encrypted, _, err = config.EncryptRequest(original); err != nil {
+		t.Fatal(err)
+	}
+	result := encrypted.(*dynamic)
+	if decrypt(result.Receivers["a"].Name) != "李四" || result.Receivers["b"] != original.Receivers["b"] ||
+		decrypt(result.Payload.([]testContact)[0].Name) != "王五" || result.Untagged != "plain" {
+		t.Fatalf("unexpected encrypted request: %+v", result)
+	}
+	if original.Receivers["a"].Name != "李四" || original.Payload.([]testContact)[0].Name != "王五" {
+		t.Fatal("request modified")
+	}
+
+	// 敏感字段不是字符串
+	type invalid struct {
+		Names []string `json:"names" wechatpay:"encrypt"`
+	}
+	if _, _, err = config.EncryptRequest(&invalid{Names: []string{"张三"}}); err == nil {
+		t.Fatal("non-string sensitive field should fail")
+	}
+}
+
+func TestWalkSensitiveMap(t *testing.T) {
+	type response struct {
+		Contacts map[string]testContact `json:"contacts"`
+		Payload  interface{}            `json:"payload"`
+	}
+	dst := &response{
+		Contacts: map[string]testContact{"a": {Name: "name", OpenId: "openid"}},
+		Payload:  map[string]interface{}{"b": testContact{Mobile: "mobile"}},
+	}
+	if err := walkSensitive(reflect.ValueOf(dst), func(s string) (string, error) {
+		return strings.ToUpper(s), nil
+	}); err != nil {
+		t.Fatal(err)
+	}
+	if contact := dst.Contacts["a"]; contact.Name != "NAME" || contact.OpenId != "openid" {
+		t.Fatalf("unexpected contact in map: %+v", contact)
+	}
+	if contact := dst.Payload.(map[string]interface{})["b"].(testContact); contact.Mobile != "MOBILE" {
+		t.Fatalf("unexpected contact in interface map: %+v", contact)
+	}
+}
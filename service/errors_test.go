@@ -0,0 +1,121 @@
+package service
+
+import (
+	stderrors "errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/pyihe/wechat-sdk/v3/model"
+	"github.com/pyihe/wechat-sdk/v3/pkg/errors"
+)
+
+func TestAPIError(t *testing.T) {
+	body := `{"code":"PARAM_ERROR","message":"参数错误","detail":{"field":"/amount/total","value":0,"issue":"total必须大于0","location":"body"}}`
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Request-ID", "08F78BB5AF0610")
+		if r.URL.Path == "/gateway" {
+			w.WriteHeader(http.StatusBadGateway)
+			_, _ = w.Write([]byte("<html>502 Bad Gateway</html>"))
+			return
+		}
+		w.WriteHeader(http.StatusBadRequest)
+		_, _ = w.Write([]byte(body))
+	}))
+	defer server.Close()
+
+	config := newTestConfig(t, server.URL)
+	response, err := config.RequestWithSign(http.MethodPost, "/v3/pay/transactions/jsapi", map[string]int{"total": 0})
+	if err != nil {
+		t.Fatal(err)
+	}
+	var dst model.JSAPIResponse
+	requestId, err := config.ParseWechatResponse(response, &dst)
+
+	var apiErr *errors.APIError
+	if !stderrors.As(err, &apiErr) {
+		t.Fatalf("expected *errors.APIError, got: %v", err)
+	}
+	if apiErr.StatusCode != http.StatusBadRequest || apiErr.Code != "PARAM_ERROR" || apiErr.Message != "参数错误" ||
+		apiErr.RequestId != requestId || requestId != "08F78BB5AF0610" || string(apiErr.Body) != body {
+		t.Fatalf("unexpected APIError: %+v", apiErr)
+	}
+	if apiErr.Detail == nil || apiErr.Detail.Field != "/amount/total" || apiErr.Detail.Issue != "total必须大于0" || apiErr.Detail.Location != "body" {
+		t.Fatalf("unexpected detail: %+v", apiErr.Detail)
+	}
+	if !stderrors.Is(err, errors.ErrParamError) || stderrors.Is(err, errors.ErrOrderPaid) || !stderrors.Is(err, errors.New(http.StatusBadRequest)) {
+		t.Fatal("unexpected errors.Is result")
+	}
+	// 应答结构体中同样可以获取错误信息
+	if dst.Code != "PARAM_ERROR" {
+		t.Fatalf("unexpected response: %+v", dst)
+	}
+
+	// 非json的应答
+	response, err = config.RequestWithSign(http.MethodGet, "/gateway", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	_, err = config.ParseWechatResponse(response, &dst)
+	if !stderrors.As(err, &apiErr) || apiErr.StatusCode != http.StatusBadGateway || apiErr.Code != "" || !stderrors.Is(err, errors.New(http.StatusBadGateway)) {
+		t.Fatalf("unexpected error: %v", err)
+	}
+}
+
+func TestClassify(t *testing.T) {
+	var status int
+	var body string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(status)
+		_, _ = w.Write([]byte(body))
+	}))
+	config := newTestConfig(t, server.URL)
+	request := func() error {
+		response, err := config.RequestWithSign(http.MethodPost, "/v3/refund/domestic/refunds", map[string]string{"out_refund_no": "1217752501201407033233368018"})
+		if err != nil {
+			return err
+		}
+		_, err = config.ParseWechatResponse(response, &model.JSAPIResponse{})
+		return err
+	}
+
+	cases := []struct {
+		status int
+		body   string
+		class  errors.Class
+	}{
+		{http.StatusInternalServerError, `{"code":"SYSTEM_ERROR","message":"系统错误"}`, errors.ClassRetryable},
+		{http.StatusTooManyRequests, `{"code":"FREQUENCY_LIMITED","message":"频率超限"}`, errors.ClassRetryable},
+		{http.StatusBadGateway, `<html></html>`, errors.ClassRetryable},
+		{http.StatusForbidden, `{"code":"NOT_ENOUGH","message":"余额不足"}`, errors.ClassTerminal},
+		{http.StatusBadRequest, `{"code":"PARAM_ERROR","message":"参数错误"}`, errors.ClassTerminal},
+		{http.StatusAccepted, `{"code":"USERPAYING","message":"用户支付中"}`, errors.ClassNeedQuery},
+		{http.StatusForbidden, `{"code":"UNKNOWN_CODE","message":"未知错误"}`, errors.ClassTerminal},
+		// 应答缺少签名, 无法确认请求结果
+		{http.StatusOK, `{}`, errors.ClassNeedQuery},
+	}
+	for _, c := range cases {
+		status, body = c.status, c.body
+		if class := errors.Classify(request()); class != c.class {
+			t.Fatalf("%d %s: expected %s, got %s", c.status, c.body, c.class, class)
+		}
+	}
+	if errors.Classify(nil) != errors.ClassNone || errors.IsRetryable(nil) {
+		t.Fatal("unexpected class for nil error")
+	}
+	if !errors.IsTerminal(errors.ErrInvalidRequest) || !errors.NeedQuery(errors.ErrTimestampExpired) {
+		t.Fatal("unexpected class for sdk error")
+	}
+
+	errors.RegisterCode("UNKNOWN_CODE", errors.ClassRetryable)
+	status, body = http.StatusForbidden, `{"code":"UNKNOWN_CODE","message":"未知错误"}`
+	if err := request(); !errors.IsRetryable(err) {
+		t.Fatalf("expected retryable: %v", err)
+	}
+
+	// 连接失败时请求没有送达, 可以重试
+	server.Close()
+	if err := request(); !errors.IsRetryable(err) {
+		t.Fatalf("expected retryable: %v", err)
+	}
+}
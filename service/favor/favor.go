@@ -1,7 +1,6 @@
 package favor
 
 import (
-	"crypto"
 	"encoding/json"
 	"fmt"
 	"io"
@@ -11,13 +10,13 @@ import (
 
 	"github.com/pyihe/wechat-sdk/v3/pkg"
 	"github.com/pyihe/wechat-sdk/v3/pkg/errors"
-	"github.com/pyihe/wechat-sdk/v3/pkg/files"
 	"github.com/pyihe/wechat-sdk/v3/service"
 )
 
 // CreateStock 创建代金券批次API
 // 商户平台文档: https://pay.weixin.qq.com/wiki/doc/apiv3/apis/chapter9_1_1.shtml
 // 服务商平台API: https://pay.weixin.qq.com/wiki/doc/apiv3_partner/apis/chapter9_1_1.shtml
+// request: *CreateStockRequest(签名前会校验参数)或者map等可序列化的参数
 func CreateStock(config *service.Config, request interface{}) (createResponse *CreateStockResponse, err error) {
 	if config == nil {
 		err = errors.ErrNoConfig
@@ -181,6 +180,21 @@ func QueryStockList(config *service.Config, request *QueryStockListRequest) (que
 	return
 }
 
+// RangeStockList 遍历条件查询批次列表的所有分页, request.Offset为起始页码, request.Limit为分页大小(最大为10),
+// fn返回false时停止遍历, requestId为最后一次查询的唯一请求ID
+func RangeStockList(config *service.Config, request *QueryStockListRequest, fn func(stock *Stock) bool) (requestId string, err error) {
+	if request == nil {
+		err = errors.ErrNoSDKRequest
+		return
+	}
+	pageRequest := *request
+	pager := service.Pager{Offset: request.Offset, PageSize: request.Limit, MaxPageSize: 10, ByPage: true}
+	return service.RangePages(config, pager, func(offset, limit uint32) (interface{}, error) {
+		pageRequest.Offset, pageRequest.Limit = offset, limit
+		return QueryStockList(config, &pageRequest)
+	}, fn)
+}
+
 // QueryStock 查询批次详情
 // 商户平台文档: https://pay.weixin.qq.com/wiki/doc/apiv3/apis/chapter9_1_5.shtml
 // 服务商平台文档: https://pay.weixin.qq.com/wiki/doc/apiv3_partner/apis/chapter9_1_5.shtml
@@ -268,6 +282,21 @@ func QueryStockMerchants(config *service.Config, request *QueryStockMerchantRequ
 	return
 }
 
+// RangeStockMerchants 遍历代金券可用商户的所有分页, request.Offset为起始页码, request.Limit为分页大小(最大为50),
+// fn返回false时停止遍历, requestId为最后一次查询的唯一请求ID
+func RangeStockMerchants(config *service.Config, request *QueryStockMerchantRequest, fn func(merchantId string) bool) (requestId string, err error) {
+	if request == nil {
+		err = errors.ErrNoSDKRequest
+		return
+	}
+	pageRequest := *request
+	pager := service.Pager{Offset: request.Offset, PageSize: request.Limit, MaxPageSize: 50, ByPage: true}
+	return service.RangePages(config, pager, func(offset, limit uint32) (interface{}, error) {
+		pageRequest.Offset, pageRequest.Limit = offset, limit
+		return QueryStockMerchants(config, &pageRequest)
+	}, fn)
+}
+
 // QueryStockItems 查询代金券可用单品列表API
 // 商户平台文档: https://pay.weixin.qq.com/wiki/doc/apiv3/apis/chapter9_1_8.shtml
 // 服务商平台文档: https://pay.weixin.qq.com/wiki/doc/apiv3_partner/apis/chapter9_1_8.shtml
@@ -330,7 +359,7 @@ func QueryUserCoupons(config *service.Config, request *QueryUserCouponsRequest)
 		param.Add("available_mchid", request.AvailableMchId)
 	}
 
-	response, err := config.RequestWithSign(http.MethodGet, fmt.Sprintf("/v3/marketing/favor/users/%s/coupons%s", request.OpenId, param.Encode()), nil)
+	response, err := config.RequestWithSign(http.MethodGet, fmt.Sprintf("/v3/marketing/favor/users/%s/coupons?%s", request.OpenId, param.Encode()), nil)
 	if err != nil {
 		return
 	}
@@ -339,6 +368,21 @@ func QueryUserCoupons(config *service.Config, request *QueryUserCouponsRequest)
 	return
 }
 
+// RangeUserCoupons 遍历用户券的所有分页, request.Offset为起始页码, request.Limit为分页大小(最大为20),
+// fn返回false时停止遍历, requestId为最后一次查询的唯一请求ID
+func RangeUserCoupons(config *service.Config, request *QueryUserCouponsRequest, fn func(coupon *Coupon) bool) (requestId string, err error) {
+	if request == nil {
+		err = errors.ErrNoSDKRequest
+		return
+	}
+	pageRequest := *request
+	pager := service.Pager{Offset: request.Offset, PageSize: request.Limit, MaxPageSize: 20, ByPage: true}
+	return service.RangePages(config, pager, func(offset, limit uint32) (interface{}, error) {
+		pageRequest.Offset, pageRequest.Limit = offset, limit
+		return QueryUserCoupons(config, &pageRequest)
+	}, fn)
+}
+
 // DownloadStockUseFlow 下载批次核销明细API
 // 商户平台文档: https://pay.weixin.qq.com/wiki/doc/apiv3/apis/chapter9_1_10.shtml
 // 服务商平台文档: https://pay.weixin.qq.com/wiki/doc/apiv3_partner/apis/chapter9_1_10.shtml
@@ -366,26 +410,15 @@ func DownloadStockUseFlow(config *service.Config, request *DownloadRequest) (dow
 		return
 	}
 
-	// 下载账单
-	content, err := config.Download(downloadResponse.Url)
-	if err != nil {
-		return
-	}
-	// 校验hash值
-	switch downloadResponse.HashType {
-	case "SHA1":
-		if err = config.VerifyHashValue(crypto.SHA1, content, downloadResponse.HashValue); err != nil {
-			return
-		}
-	default:
-		err = errors.ErrInvalidHashType
+	// 流式下载并校验摘要值
+	options := &service.DownloadOptions{HashType: downloadResponse.HashType, HashValue: downloadResponse.HashValue}
+	if request.Writer != nil {
+		_, err = config.DownloadTo(downloadResponse.Url, request.Writer, options)
 		return
 	}
-
-	// 写入文件
 	fileName := request.FileName
 	filePath := request.FilePath
-	err = files.WritToFile(filePath, fileName, content)
+	_, err = config.DownloadToFile(downloadResponse.Url, filePath, fileName, options)
 	return
 }
 
@@ -416,23 +449,12 @@ func DownloadStockRefundFlow(config *service.Config, request *DownloadRequest) (
 		return
 	}
 
-	// 下载账单
-	content, err := config.Download(downloadResponse.Url)
-	if err != nil {
+	// 流式下载并校验摘要值
+	options := &service.DownloadOptions{HashType: downloadResponse.HashType, HashValue: downloadResponse.HashValue}
+	if request.Writer != nil {
+		_, err = config.DownloadTo(downloadResponse.Url, request.Writer, options)
 		return
 	}
-	// 校验hash值
-	switch downloadResponse.HashType {
-	case "SHA1":
-		if err = config.VerifyHashValue(crypto.SHA1, content, downloadResponse.HashValue); err != nil {
-			return
-		}
-	default:
-		err = errors.ErrInvalidHashType
-		return
-	}
-
-	// 写入文件
 	fileName := request.FileName
 	filePath := request.FilePath
 	if fileName == "" {
@@ -441,7 +463,7 @@ func DownloadStockRefundFlow(config *service.Config, request *DownloadRequest) (
 	if filePath == "" {
 		filePath = "./stockfundflow"
 	}
-	err = files.WritToFile(filePath, fileName, content)
+	_, err = config.DownloadToFile(downloadResponse.Url, filePath, fileName, options)
 	return
 }
 
@@ -2,11 +2,124 @@ package favor
 
 import (
 	"encoding/json"
+	"io"
 	"time"
 
 	"github.com/pyihe/wechat-sdk/v3/model"
+	"github.com/pyihe/wechat-sdk/v3/pkg/validate"
 )
 
+// CreateStockRequest 创建代金券批次请求参数
+type CreateStockRequest struct {
+	StockName          string               `json:"stock_name"`             // 批次名称
+	Comment            string               `json:"comment,omitempty"`      // 批次备注
+	BelongMerchant     string               `json:"belong_merchant"`        // 归属商户号
+	AvailableBeginTime string               `json:"available_begin_time"`   // 可用时间开始时间, RFC3339格式
+	AvailableEndTime   string               `json:"available_end_time"`     // 可用时间结束时间, RFC3339格式
+	StockUseRule       *CreateStockUseRule  `json:"stock_use_rule"`         // 发放规则
+	PatternInfo        *PatternInfo         `json:"pattern_info,omitempty"` // 样式设置
+	CouponUseRule      *CreateCouponUseRule `json:"coupon_use_rule"`        // 核销规则
+	NoCash             bool                 `json:"no_cash"`                // 营销经费
+	StockType          string               `json:"stock_type"`             // 批次类型, 仅支持: NORMAL
+	OutRequestNo       string               `json:"out_request_no"`         // 商户单据号
+	ExtInfo            string               `json:"ext_info,omitempty"`     // 扩展属性
+}
+
+func (r *CreateStockRequest) Validate() error {
+	return validate.First(
+		validate.Required("stock_name", r.StockName),
+		validate.MaxChars("stock_name", r.StockName, 20),
+		validate.MaxChars("comment", r.Comment, 20),
+		validate.Required("belong_merchant", r.BelongMerchant),
+		validate.MaxLength("belong_merchant", r.BelongMerchant, 20),
+		validate.Required("available_begin_time", r.AvailableBeginTime),
+		validate.Time("available_begin_time", r.AvailableBeginTime),
+		validate.Required("available_end_time", r.AvailableEndTime),
+		validate.Time("available_end_time", r.AvailableEndTime),
+		validate.Before("available_end_time", r.AvailableBeginTime, r.AvailableEndTime),
+		validate.Required("stock_use_rule", r.StockUseRule),
+		validate.Nested("stock_use_rule", r.StockUseRule),
+		validate.Nested("pattern_info", r.PatternInfo),
+		validate.Required("coupon_use_rule", r.CouponUseRule),
+		validate.Nested("coupon_use_rule", r.CouponUseRule),
+		validate.Required("stock_type", r.StockType),
+		validate.OneOf("stock_type", r.StockType, "NORMAL"),
+		validate.Required("out_request_no", r.OutRequestNo),
+		validate.MaxLength("out_request_no", r.OutRequestNo, 128),
+		validate.MaxLength("ext_info", r.ExtInfo, 2048),
+	)
+}
+
+// CreateStockUseRule 创建批次时的发放规则
+type CreateStockUseRule struct {
+	MaxCoupons         int64 `json:"max_coupons"`                 // 发放总上限
+	MaxAmount          int64 `json:"max_amount"`                  // 总预算
+	MaxAmountByDay     int64 `json:"max_amount_by_day,omitempty"` // 单天预算发放上限
+	MaxCouponsPerUser  int64 `json:"max_coupons_per_user"`        // 单个用户可领个数
+	NaturalPersonLimit bool  `json:"natural_person_limit"`        // 是否开启自然人限制
+	PreventApiAbuse    bool  `json:"prevent_api_abuse"`           // 是否开启防刷拦截
+}
+
+func (r *CreateStockUseRule) Validate() error {
+	return validate.First(
+		validate.Range("max_coupons", r.MaxCoupons, 1, 1000000000),
+		validate.Min("max_amount", r.MaxAmount, 1),
+		validate.Range("max_amount_by_day", r.MaxAmountByDay, 0, r.MaxAmount),
+		validate.Range("max_coupons_per_user", r.MaxCouponsPerUser, 1, 100),
+	)
+}
+
+// PatternInfo 代金券样式
+type PatternInfo struct {
+	Description     string `json:"description"`                // 使用说明
+	MerchantLogo    string `json:"merchant_logo,omitempty"`    // 商户logo
+	MerchantName    string `json:"merchant_name,omitempty"`    // 品牌名称
+	BackgroundColor string `json:"background_color,omitempty"` // 背景颜色, 如: COLOR010
+	CouponImage     string `json:"coupon_image,omitempty"`     // 券详情图片
+}
+
+func (p *PatternInfo) Validate() error {
+	return validate.First(
+		validate.Required("description", p.Description),
+		validate.MaxChars("description", p.Description, 1000),
+		validate.MaxLength("merchant_logo", p.MerchantLogo, 128),
+		validate.MaxChars("merchant_name", p.MerchantName, 16),
+		validate.OneOf("background_color", p.BackgroundColor,
+			"COLOR010", "COLOR020", "COLOR030", "COLOR040", "COLOR050",
+			"COLOR060", "COLOR070", "COLOR080", "COLOR090", "COLOR100"),
+		validate.MaxLength("coupon_image", p.CouponImage, 128),
+	)
+}
+
+// CreateCouponUseRule 创建批次时的核销规则
+type CreateCouponUseRule struct {
+	FixedNormalCoupon  *FixedNormalCoupon `json:"fixed_normal_coupon,omitempty"` // 固定面额满减券使用规则
+	GoodsTag           []string           `json:"goods_tag,omitempty"`           // 订单优惠标记
+	TradeType          []string           `json:"trade_type,omitempty"`          // 支付方式: MICROAPP、APPPAY、PPAY、CARD、FACE、OTHER
+	CombineUse         bool               `json:"combine_use,omitempty"`         // 是否可叠加其他优惠
+	AvailableItems     []string           `json:"available_items,omitempty"`     // 可核销商品编码
+	UnavailableItems   []string           `json:"unavailable_items,omitempty"`   // 不参与优惠商品编码
+	AvailableMerchants []string           `json:"available_merchants"`           // 可用商户号
+}
+
+func (r *CreateCouponUseRule) Validate() (err error) {
+	if err = validate.Size("available_merchants", len(r.AvailableMerchants), 1, 50); err != nil {
+		return
+	}
+	for _, tradeType := range r.TradeType {
+		if err = validate.OneOf("trade_type", tradeType, "MICROAPP", "APPPAY", "PPAY", "CARD", "FACE", "OTHER"); err != nil {
+			return
+		}
+	}
+	if coupon := r.FixedNormalCoupon; coupon != nil {
+		err = validate.First(
+			validate.Range("fixed_normal_coupon.coupon_amount", int64(coupon.CouponAmount), 1, 100000),
+			validate.Min("fixed_normal_coupon.transaction_minimum", int64(coupon.TransactionMinimum), int64(coupon.CouponAmount)+1),
+		)
+	}
+	return
+}
+
 // CreateStockResponse 创建代金券批次API应答参数
 type CreateStockResponse struct {
 	model.WechatError
@@ -166,9 +279,10 @@ type QueryUserCouponsResponse struct {
 
 // DownloadRequest 明细下载请求参数
 type DownloadRequest struct {
-	StockId  string `json:"stock_id,omitempty"`  // 批次ID
-	FileName string `json:"file_name,omitempty"` // 明细文件名
-	FilePath string `json:"file_path,omitempty"` // 文件存放路径
+	StockId  string    `json:"stock_id,omitempty"`  // 批次ID
+	FileName string    `json:"file_name,omitempty"` // 明细文件名
+	FilePath string    `json:"file_path,omitempty"` // 文件存放路径
+	Writer   io.Writer `json:"-"`                   // 明细写入的目标, 不为nil时写入Writer而不是文件
 }
 
 // DownloadResponse 明细下载应答参数
@@ -0,0 +1,221 @@
+package service
+
+import (
+	"crypto/rsa"
+	"crypto/x509"
+	"encoding/base64"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+	"regexp"
+	"strings"
+
+	"github.com/pyihe/wechat-sdk/v3/pkg/errors"
+	"github.com/pyihe/wechat-sdk/v3/pkg/files"
+)
+
+var serialNoPattern = regexp.MustCompile(`^[0-9A-F]+$`)
+
+// KeySource 密钥、证书的来源, 返回PEM格式的内容
+type KeySource func() ([]byte, error)
+
+// FromFile 从文件中读取
+func FromFile(file string) KeySource {
+	return func() ([]byte, error) {
+		return ioutil.ReadFile(file)
+	}
+}
+
+// FromBytes 直接使用PEM格式的内容
+func FromBytes(data []byte) KeySource {
+	return func() ([]byte, error) {
+		return data, nil
+	}
+}
+
+// FromString 直接使用PEM格式的字符串
+func FromString(data string) KeySource {
+	return FromBytes([]byte(data))
+}
+
+// FromReader 从io.Reader中读取
+func FromReader(reader io.Reader) KeySource {
+	return func() ([]byte, error) {
+		return ioutil.ReadAll(reader)
+	}
+}
+
+// FromEnv 从环境变量中读取, 环境变量的值可以是PEM格式的内容, 也可以是base64编码后的PEM内容
+func FromEnv(name string) KeySource {
+	return func() ([]byte, error) {
+		value, ok := os.LookupEnv(name)
+		if !ok || value == "" {
+			return nil, fmt.Errorf("环境变量[%s]不存在", name)
+		}
+		if strings.Contains(value, "-----BEGIN") {
+			return []byte(value), nil
+		}
+		return base64.StdEncoding.DecodeString(strings.TrimSpace(value))
+	}
+}
+
+// fromPEMOrFile value包含PEM头时视为PEM内容, 否则视为文件路径
+func fromPEMOrFile(value string) KeySource {
+	if strings.Contains(value, "-----BEGIN") {
+		return FromString(value)
+	}
+	return FromFile(value)
+}
+
+// WithPrivateKeyFrom 从source加载商户API私钥, 自动识别PKCS#1和PKCS#8格式
+func WithPrivateKeyFrom(source KeySource) Option {
+	return func(config *Config) {
+		data, err := source()
+		if err != nil {
+			config.setErr(fmt.Errorf("加载商户私钥失败: %v", err))
+			return
+		}
+		privateKey, err := files.ParseRSAPrivateKey(data)
+		if err != nil {
+			config.setErr(fmt.Errorf("加载商户私钥失败: %v", err))
+			return
+		}
+		config.setPrivateKey(privateKey)
+	}
+}
+
+// WithMerchantCertificateFrom 从source加载商户API证书(apiclient_cert.pem), 用于校验证书序列号与私钥是否匹配,
+// 未设置证书序列号时使用证书的序列号
+func WithMerchantCertificateFrom(source KeySource) Option {
+	return func(config *Config) {
+		data, err := source()
+		if err != nil {
+			config.setErr(fmt.Errorf("加载商户证书失败: %v", err))
+			return
+		}
+		_, cert, err := files.ParseCertificate(data)
+		if err != nil {
+			config.setErr(fmt.Errorf("加载商户证书失败: %v", err))
+			return
+		}
+		config.merchantCert = cert
+	}
+}
+
+// WithPublicKeyFrom 从source加载微信支付平台证书
+func WithPublicKeyFrom(source KeySource) Option {
+	return func(config *Config) {
+		data, err := source()
+		if err != nil {
+			config.setErr(fmt.Errorf("加载平台证书失败: %v", err))
+			return
+		}
+		serialNo, cert, err := files.ParseCertificate(data)
+		if err != nil {
+			config.setErr(fmt.Errorf("加载平台证书失败: %v", err))
+			return
+		}
+		publicKey, ok := cert.PublicKey.(*rsa.PublicKey)
+		if !ok {
+			config.setErr(fmt.Errorf("加载证书失败: 请确认证书是否为RSA PublicKey!"))
+			return
+		}
+		if err = config.wechatCipher.SetRSAPublicKey(publicKey, 0); err != nil {
+			config.setErr(err)
+			return
+		}
+		config.AddCertificate(serialNo, cert)
+	}
+}
+
+// WithPlatformPublicKeyFrom 从source加载微信支付平台公钥(公钥模式), id为微信支付公钥ID
+func WithPlatformPublicKeyFrom(id string, source KeySource) Option {
+	return func(config *Config) {
+		data, err := source()
+		if err != nil {
+			config.setErr(fmt.Errorf("加载平台公钥失败: %v", err))
+			return
+		}
+		publicKey, err := files.ParseRSAPublicKey(data)
+		if err != nil {
+			config.setErr(fmt.Errorf("加载平台公钥失败: %v", err))
+			return
+		}
+		if err = config.wechatCipher.SetRSAPublicKey(publicKey, 0); err != nil {
+			config.setErr(err)
+			return
+		}
+		config.AddPublicKey(id, publicKey)
+		config.publicKeyId = strings.ToUpper(id)
+	}
+}
+
+// NewConfigE 与NewConfig相同, 但是在配置项加载失败或者校验不通过时返回error而不是panic
+func NewConfigE(opts ...Option) (*Config, error) {
+	c := newConfig(opts...)
+	if c.err != nil {
+		return nil, c.err
+	}
+	if err := c.Validate(); err != nil {
+		return nil, err
+	}
+	return c, nil
+}
+
+// Validate 校验配置是否可以用于调用微信支付API:
+// 1. 加载了商户私钥时, 必须设置商户号以及证书序列号(或者商户API证书)
+// 2. 加载了商户API证书时, 证书序列号必须与证书一致, 证书公钥必须与私钥匹配
+func (c *Config) Validate() error {
+	if c.err != nil {
+		return c.err
+	}
+	if c.merchantCert != nil {
+		certSerialNo := strings.ToUpper(c.merchantCert.SerialNumber.Text(16))
+		if strings.ToUpper(c.serialNo) != certSerialNo {
+			return fmt.Errorf("商户证书序列号[%s]与商户API证书[%s]不一致", c.serialNo, certSerialNo)
+		}
+		if c.privateKey != nil && !publicKeyMatches(c.merchantCert, c.privateKey) {
+			return fmt.Errorf("商户私钥与商户API证书[%s]不匹配", certSerialNo)
+		}
+	}
+	if c.privateKey == nil && c.signer == nil {
+		return nil
+	}
+	if c.mchId == "" {
+		return errors.ErrNoMchId
+	}
+	serialNo := c.GetSerialNo()
+	if serialNo == "" {
+		return errors.ErrNoSerialNo
+	}
+	if !serialNoPattern.MatchString(strings.ToUpper(serialNo)) {
+		return fmt.Errorf("商户证书序列号[%s]格式错误: 必须为16进制字符串", serialNo)
+	}
+	return nil
+}
+
+// setErr 记录加载配置时遇到的第一个错误
+func (c *Config) setErr(err error) {
+	if c.err == nil {
+		c.err = err
+	}
+}
+
+// setPrivateKey 设置商户API私钥
+func (c *Config) setPrivateKey(privateKey *rsa.PrivateKey) {
+	if err := c.merchantCipher.SetRSAPrivateKey(privateKey, 0); err != nil {
+		c.setErr(err)
+		return
+	}
+	c.privateKey = privateKey
+}
+
+// publicKeyMatches 判断证书公钥与私钥是否匹配
+func publicKeyMatches(cert *x509.Certificate, privateKey *rsa.PrivateKey) bool {
+	publicKey, ok := cert.PublicKey.(*rsa.PublicKey)
+	if !ok {
+		return false
+	}
+	return publicKey.N.Cmp(privateKey.N) == 0 && publicKey.E == privateKey.E
+}
@@ -0,0 +1,91 @@
+package service
+
+import (
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/base64"
+	"encoding/pem"
+	"math/big"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+func newTestCertificate(t *testing.T, privateKey *rsa.PrivateKey, serialNo int64) []byte {
+	template := &x509.Certificate{
+		SerialNumber: big.NewInt(serialNo),
+		Subject:      pkix.Name{CommonName: "1900000001"},
+		NotBefore:    time.Now().Add(-time.Hour),
+		NotAfter:     time.Now().Add(24 * time.Hour),
+	}
+	der, err := x509.CreateCertificate(rand.Reader, template, template, &privateKey.PublicKey, privateKey)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der})
+}
+
+func TestNewConfigE(t *testing.T) {
+	privateKey, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatal(err)
+	}
+	pkcs1 := pem.EncodeToMemory(&pem.Block{Type: "RSA PRIVATE KEY", Bytes: x509.MarshalPKCS1PrivateKey(privateKey)})
+	der, err := x509.MarshalPKCS8PrivateKey(privateKey)
+	if err != nil {
+		t.Fatal(err)
+	}
+	pkcs8 := pem.EncodeToMemory(&pem.Block{Type: "PRIVATE KEY", Bytes: der})
+	cert := newTestCertificate(t, privateKey, 0x1DDE55AD98ED71D6)
+
+	// PKCS#1、PKCS#8均可自动识别, 证书序列号从商户API证书中获取
+	for _, source := range []KeySource{FromBytes(pkcs1), FromString(string(pkcs8)), FromReader(strings.NewReader(string(pkcs8)))} {
+		config, err := NewConfigE(WithMchId("1900000001"), WithPrivateKeyFrom(source), WithMerchantCertificateFrom(FromBytes(cert)))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if config.GetSerialNo() != "1DDE55AD98ED71D6" {
+			t.Fatalf("unexpected serial no: %s", config.GetSerialNo())
+		}
+	}
+
+	// 环境变量中的base64编码内容
+	_ = os.Setenv("WECHAT_SDK_TEST_PRIVATE_KEY", base64.StdEncoding.EncodeToString(pkcs8))
+	defer os.Unsetenv("WECHAT_SDK_TEST_PRIVATE_KEY")
+	if _, err = NewConfigE(WithMchId("1900000001"), WithSerialNo("1DDE55AD98ED71D6"), WithPrivateKeyFrom(FromEnv("WECHAT_SDK_TEST_PRIVATE_KEY"))); err != nil {
+		t.Fatal(err)
+	}
+
+	// 文件
+	file := filepath.Join(t.TempDir(), "apiclient_key.pem")
+	if err = os.WriteFile(file, pkcs8, 0600); err != nil {
+		t.Fatal(err)
+	}
+	if _, err = NewConfigE(WithMchId("1900000001"), WithSerialNo("1DDE55AD98ED71D6"), WithPrivateKey(file, 0)); err != nil {
+		t.Fatal(err)
+	}
+
+	otherKey, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatal(err)
+	}
+	failures := map[string][]Option{
+		"missing file":       {WithMchId("1900000001"), WithSerialNo("1DDE55AD98ED71D6"), WithPrivateKey(file+".missing", 0)},
+		"missing env":        {WithMchId("1900000001"), WithSerialNo("1DDE55AD98ED71D6"), WithPrivateKeyFrom(FromEnv("WECHAT_SDK_TEST_MISSING"))},
+		"invalid pem":        {WithMchId("1900000001"), WithSerialNo("1DDE55AD98ED71D6"), WithPrivateKeyFrom(FromString("invalid"))},
+		"missing serial no":  {WithMchId("1900000001"), WithPrivateKeyFrom(FromBytes(pkcs8))},
+		"invalid serial no":  {WithMchId("1900000001"), WithSerialNo("NOT-A-SERIAL"), WithPrivateKeyFrom(FromBytes(pkcs8))},
+		"serial no mismatch": {WithMchId("1900000001"), WithSerialNo("1DDE55AD98ED71D7"), WithPrivateKeyFrom(FromBytes(pkcs8)), WithMerchantCertificateFrom(FromBytes(cert))},
+		"key mismatch":       {WithMchId("1900000001"), WithPrivateKeyFrom(FromBytes(pkcs8)), WithMerchantCertificateFrom(FromBytes(newTestCertificate(t, otherKey, 1)))},
+		"missing mchid":      {WithSerialNo("1DDE55AD98ED71D6"), WithPrivateKeyFrom(FromBytes(pkcs8))},
+	}
+	for name, opts := range failures {
+		if _, err = NewConfigE(opts...); err == nil {
+			t.Fatalf("%s: expected error", name)
+		}
+	}
+}
@@ -0,0 +1,149 @@
+package service
+
+import (
+	"bytes"
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"net/http"
+	"regexp"
+	"strings"
+)
+
+// Logger 调试日志接口, 方法签名与*slog.Logger一致, 可以直接使用slog.Default()
+// args为交替出现的key、value, SDK输出的所有内容均已脱敏
+type Logger interface {
+	DebugContext(ctx context.Context, msg string, args ...interface{})
+}
+
+// WithLogger 设置调试日志, 记录请求的方法、路径、签名串, 以及应答、通知的请求头和body, 为nil时不记录
+// 日志中会隐藏Authorization中的签名、APIv3密钥、AppSecret、使用平台公钥加密的字段、openid以及手机号
+func WithLogger(logger Logger) Option {
+	return func(config *Config) {
+		config.logger = logger
+	}
+}
+
+const redacted = "***"
+
+var (
+	authSignaturePattern = regexp.MustCompile(`signature="[^"]*"`)
+	openIdPattern        = regexp.MustCompile(`\bo[0-9A-Za-z_-]{27}\b`)
+	phonePattern         = regexp.MustCompile(`\b1[3-9][0-9]{9}\b`)
+
+	// 需要脱敏的json字段, 加密字段在明文状态下(如解密后的通知)同样需要隐藏
+	sensitiveKeys = map[string]bool{
+		"openid":         true,
+		"sp_openid":      true,
+		"sub_openid":     true,
+		"payer_phone":    true,
+		"phone":          true,
+		"phone_number":   true,
+		"mobile":         true,
+		"mobile_phone":   true,
+		"contact_phone":  true,
+		"id_card_number": true,
+		"id_doc_number":  true,
+		"account_number": true,
+		"account_name":   true,
+		"user_name":      true,
+		"contact_name":   true,
+	}
+)
+
+func (c *Config) debug(msg string, args ...interface{}) {
+	if c.logger != nil {
+		c.logger.DebugContext(c.Context(), msg, args...)
+	}
+}
+
+// redactText 隐藏文本中的密钥、openid以及手机号
+func (c *Config) redactText(text string) string {
+	for _, secretValue := range []string{c.apiKey, c.secret} {
+		if secretValue != "" {
+			text = strings.Replace(text, secretValue, redacted, -1)
+		}
+	}
+	text = openIdPattern.ReplaceAllStringFunc(text, func(s string) string { return mask(s, 4, 4) })
+	return phonePattern.ReplaceAllStringFunc(text, func(s string) string { return mask(s, 3, 4) })
+}
+
+// redactBody json格式的body按字段脱敏, 否则按文本脱敏
+func (c *Config) redactBody(body []byte) string {
+	var data interface{}
+	decoder := json.NewDecoder(bytes.NewReader(body))
+	decoder.UseNumber()
+	if err := decoder.Decode(&data); err != nil {
+		return c.redactText(string(body))
+	}
+	result, err := json.Marshal(c.redactValue("", data))
+	if err != nil {
+		return redacted
+	}
+	return string(result)
+}
+
+func (c *Config) redactValue(key string, value interface{}) interface{} {
+	switch v := value.(type) {
+	case map[string]interface{}:
+		for k, item := range v {
+			v[k] = c.redactValue(k, item)
+		}
+	case []interface{}:
+		for i, item := range v {
+			v[i] = c.redactValue(key, item)
+		}
+	case string:
+		switch {
+		case isCiphertext(v):
+			return redacted
+		case sensitiveKeys[key]:
+			return mask(v, 3, 4)
+		}
+		return c.redactText(v)
+	}
+	return value
+}
+
+// redactError 错误信息中可能包含应答body, 同样需要脱敏
+func (c *Config) redactError(err error) interface{} {
+	if err == nil {
+		return nil
+	}
+	return c.redactText(err.Error())
+}
+
+// redactHeader 复制header并隐藏Authorization中的签名
+func (c *Config) redactHeader(header http.Header) http.Header {
+	result := make(http.Header, len(header))
+	for key, values := range header {
+		result[key] = append([]string(nil), values...)
+	}
+	if auth := result.Get("Authorization"); auth != "" {
+		result.Set("Authorization", authSignaturePattern.ReplaceAllString(auth, `signature="`+redacted+`"`))
+	}
+	return result
+}
+
+// redactSigningString 构造脱敏后的签名串, 用于排查签名错误
+func (c *Config) redactSigningString(method, url, timestamp, nonceStr string, data []byte) string {
+	return strings.Join([]string{method, c.redactText(url), timestamp, nonceStr, c.redactBody(data), ""}, "\n")
+}
+
+// isCiphertext 判断是否为使用平台公钥(RSA 2048及以上)加密后的密文
+func isCiphertext(s string) bool {
+	if len(s) < 344 {
+		return false
+	}
+	data, err := base64.StdEncoding.DecodeString(s)
+	return err == nil && len(data)%256 == 0
+}
+
+// mask 保留前prefix个以及后suffix个字符, 其余使用*代替, 过短时全部隐藏
+func mask(s string, prefix, suffix int) string {
+	runes := []rune(s)
+	if len(runes) <= prefix+suffix {
+		return redacted
+	}
+	return string(runes[:prefix]) + strings.Repeat("*", len(runes)-prefix-suffix) + string(runes[len(runes)-suffix:])
+}
@@ -0,0 +1,66 @@
+package service
+
+import (
+	"context"
+	"encoding/base64"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+type recordLogger struct {
+	lines []string
+}
+
+func (l *recordLogger) DebugContext(_ context.Context, msg string, args ...interface{}) {
+	l.lines = append(l.lines, fmt.Sprintln(append([]interface{}{msg}, args...)...))
+}
+
+func TestLoggerRedaction(t *testing.T) {
+	const (
+		apiKey = "a1b2c3d4e5f6a1b2c3d4e5f6a1b2c3d4"
+		openId = "oUpF8uMuAJO_M2pxb1Q9zNjWeS6o"
+		phone  = "13800138000"
+	)
+	ciphertext := base64.StdEncoding.EncodeToString(make([]byte, 256))
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Request-ID", "08F78BB5AF0610D302A6E8BE1E")
+		w.WriteHeader(http.StatusBadRequest)
+		_, _ = w.Write([]byte(`{"code":"PARAM_ERROR","message":"用户` + openId + `手机号` + phone + `错误"}`))
+	}))
+	defer server.Close()
+
+	logger := new(recordLogger)
+	config := newTestConfig(t, server.URL, WithApiV3Key(apiKey), WithLogger(logger))
+	body := map[string]interface{}{
+		"payer":  map[string]string{"openid": openId},
+		"name":   ciphertext,
+		"remark": "联系电话" + phone + ", key: " + apiKey,
+		"out_no": "1217752501201407033233368018",
+	}
+	response, err := config.RequestWithSign(http.MethodPost, "/v3/marketing/favor/users/"+openId+"/coupons", body)
+	if err != nil {
+		t.Fatal(err)
+	}
+	_, _ = config.ParseWechatResponse(response, nil)
+
+	if len(logger.lines) != 2 {
+		t.Fatalf("expected 2 log lines, got: %v", logger.lines)
+	}
+	output := strings.Join(logger.lines, "")
+	for _, leaked := range []string{openId, phone, apiKey, ciphertext[:64]} {
+		if strings.Contains(output, leaked) {
+			t.Fatalf("log leaked %s: %s", leaked, output)
+		}
+	}
+	if strings.Contains(output, `signature="`) && !strings.Contains(output, `signature="***"`) {
+		t.Fatalf("authorization signature is not redacted: %s", output)
+	}
+	for _, expected := range []string{"POST\n/v3/marketing/favor/users/oUpF", "1217752501201407033233368018", "138****8000", "PARAM_ERROR", "08F78BB5AF0610D302A6E8BE1E"} {
+		if !strings.Contains(output, expected) {
+			t.Fatalf("log should contain %q: %s", expected, output)
+		}
+	}
+}
@@ -0,0 +1,50 @@
+package service
+
+import (
+	"net/http"
+)
+
+// Handler 发送请求并返回微信的原始应答
+type Handler func(request *http.Request) (*http.Response, error)
+
+// Middleware 请求中间件, 可以在请求发送前后做额外的处理, 如添加链路追踪的请求头、审计、注入测试故障、记录耗时等
+// RequestWithSign发起的请求在中间件中可以看到已经签名的*http.Request, 如果开启了重试, 每次重试都会经过中间件
+type Middleware func(next Handler) Handler
+
+// ParseInterceptor 应答解析拦截器, 在ParseWechatResponse解析完成后调用
+// response: 微信的原始应答(body已被读取并关闭)
+// body: 应答的原始body
+// dst: 反序列化的目标
+// err: 解析结果, 拦截器返回的error会替换该结果, 如果不需要修改, 原样返回即可
+type ParseInterceptor func(response *http.Response, body []byte, dst interface{}, err error) error
+
+// WithMiddleware 添加请求中间件, 对RequestWithSign、Request、UploadMedia发起的请求生效, 先添加的中间件在最外层
+func WithMiddleware(middlewares ...Middleware) Option {
+	return func(config *Config) {
+		config.middlewares = append(config.middlewares, middlewares...)
+	}
+}
+
+// WithParseInterceptor 添加应答解析拦截器, 按照添加的顺序依次调用
+func WithParseInterceptor(interceptors ...ParseInterceptor) Option {
+	return func(config *Config) {
+		config.parseInterceptors = append(config.parseInterceptors, interceptors...)
+	}
+}
+
+// do 经过中间件发送请求
+func (c *Config) do(request *http.Request) (*http.Response, error) {
+	var handler Handler = c.httpClient.Do
+	for i := len(c.middlewares) - 1; i >= 0; i-- {
+		handler = c.middlewares[i](handler)
+	}
+	return handler(request)
+}
+
+// intercept 依次调用应答解析拦截器
+func (c *Config) intercept(response *http.Response, body []byte, dst interface{}, err error) error {
+	for _, interceptor := range c.parseInterceptors {
+		err = interceptor(response, body, dst, err)
+	}
+	return err
+}
@@ -0,0 +1,62 @@
+package service
+
+import (
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestMiddleware(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("X-Trace-Id") != "trace" {
+			w.WriteHeader(http.StatusBadRequest)
+			return
+		}
+		w.WriteHeader(http.StatusNotFound)
+		_, _ = w.Write([]byte(`{"code":"RESOURCE_NOT_EXISTS","message":"订单不存在"}`))
+	}))
+	defer server.Close()
+
+	var order []string
+	trace := func(next Handler) Handler {
+		return func(request *http.Request) (*http.Response, error) {
+			order = append(order, "trace")
+			if !strings.HasPrefix(request.Header.Get("Authorization"), "WECHATPAY2-SHA256-RSA2048 ") {
+				t.Fatalf("middleware should see the signed request")
+			}
+			request.Header.Set("X-Trace-Id", "trace")
+			return next(request)
+		}
+	}
+	audit := func(next Handler) Handler {
+		return func(request *http.Request) (*http.Response, error) {
+			order = append(order, "audit")
+			response, err := next(request)
+			if err == nil && response.StatusCode != http.StatusNotFound {
+				t.Fatalf("unexpected status: %d", response.StatusCode)
+			}
+			return response, err
+		}
+	}
+	replaced := errors.New("replaced")
+	intercept := func(response *http.Response, body []byte, dst interface{}, err error) error {
+		if err == nil || !strings.Contains(string(body), "RESOURCE_NOT_EXISTS") {
+			t.Fatalf("interceptor should see the raw body and the decoded error")
+		}
+		return replaced
+	}
+
+	config := newTestConfig(t, server.URL, WithMiddleware(trace, audit), WithParseInterceptor(intercept))
+	response, err := config.RequestWithSign(http.MethodGet, "/v3/pay/transactions/out-trade-no/1217752501201407033233368018", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err = config.ParseWechatResponse(response, nil); err != replaced {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if strings.Join(order, ",") != "trace,audit" {
+		t.Fatalf("unexpected middleware order: %v", order)
+	}
+}
@@ -0,0 +1,203 @@
+package notify
+
+import (
+	"github.com/pyihe/wechat-sdk/v3/service/busifavor"
+	"github.com/pyihe/wechat-sdk/v3/service/complaints"
+	"github.com/pyihe/wechat-sdk/v3/service/favor"
+	"github.com/pyihe/wechat-sdk/v3/service/parking"
+	"github.com/pyihe/wechat-sdk/v3/service/payment/combine"
+	"github.com/pyihe/wechat-sdk/v3/service/payment/merchant"
+	"github.com/pyihe/wechat-sdk/v3/service/payment/partner"
+	"github.com/pyihe/wechat-sdk/v3/service/payscore"
+	"github.com/pyihe/wechat-sdk/v3/service/profitsharing"
+	"github.com/pyihe/wechat-sdk/v3/service/refunds"
+	"github.com/pyihe/wechat-sdk/v3/service/violation"
+)
+
+// 微信通知类型(event_type)
+const (
+	EventTransactionSuccess       = "TRANSACTION.SUCCESS"         // 支付成功
+	EventRefund                   = "REFUND.*"                    // 退款, 包括REFUND.SUCCESS、REFUND.ABNORMAL、REFUND.CLOSED
+	EventRefundSuccess            = "REFUND.SUCCESS"              // 退款成功
+	EventRefundAbnormal           = "REFUND.ABNORMAL"             // 退款异常
+	EventRefundClosed             = "REFUND.CLOSED"               // 退款关闭
+	EventPayScoreUserOpenService  = "PAYSCORE.USER_OPEN_SERVICE"  // 支付分: 用户授权
+	EventPayScoreUserCloseService = "PAYSCORE.USER_CLOSE_SERVICE" // 支付分: 用户解除授权
+	EventPayScoreUserConfirm      = "PAYSCORE.USER_CONFIRM"       // 支付分: 用户确认订单
+	EventPayScoreUserPaid         = "PAYSCORE.USER_PAID"          // 支付分: 用户支付成功
+	EventParkingStateChange       = "VEHICLE.ENTRY_STATE_CHANGE"  // 停车入场状态变更
+	EventParkingTransaction       = "TRANSACTION.*"               // 停车服务扣费结果
+	EventComplaint                = "COMPLAINT.*"                 // 消费者投诉, 包括COMPLAINT.CREATE、COMPLAINT.STATE_CHANGE
+	EventProfitSharing            = "PROFITSHARING*"              // 分账动账
+	EventCouponUse                = "COUPON.USE"                  // 代金券核销
+	EventCouponSend               = "COUPON.SEND"                 // 商家券领券
+	EventViolation                = "VIOLATION.*"                 // 商户平台处置记录
+)
+
+// OnTransactionSuccess 注册直连商户支付成功通知的处理函数
+func (h *Handler) OnTransactionSuccess(fn func(notify *Notify, order *merchant.PrepayOrder) error) {
+	h.Handle(EventTransactionSuccess, func(notify *Notify) (err error) {
+		order := new(merchant.PrepayOrder)
+		if err = notify.Unmarshal(order); err != nil {
+			return
+		}
+		order.Id = notify.Id
+		return fn(notify, order)
+	})
+}
+
+// OnPartnerTransactionSuccess 注册服务商支付成功通知的处理函数, 与OnTransactionSuccess注册的是同一个通知类型, 后注册的生效
+func (h *Handler) OnPartnerTransactionSuccess(fn func(notify *Notify, order *partner.PrepayOrder) error) {
+	h.Handle(EventTransactionSuccess, func(notify *Notify) (err error) {
+		order := new(partner.PrepayOrder)
+		if err = notify.Unmarshal(order); err != nil {
+			return
+		}
+		order.Id = notify.Id
+		return fn(notify, order)
+	})
+}
+
+// OnCombineTransactionSuccess 注册合单支付成功通知的处理函数, 与OnTransactionSuccess注册的是同一个通知类型, 后注册的生效
+func (h *Handler) OnCombineTransactionSuccess(fn func(notify *Notify, order *combine.PrepayOrder) error) {
+	h.Handle(EventTransactionSuccess, func(notify *Notify) (err error) {
+		order := new(combine.PrepayOrder)
+		if err = notify.Unmarshal(order); err != nil {
+			return
+		}
+		order.Id = notify.Id
+		return fn(notify, order)
+	})
+}
+
+// OnRefund 注册退款通知(成功、异常、关闭)的处理函数, 可以通过notify.EventType区分
+func (h *Handler) OnRefund(fn func(notify *Notify, refundOrder *refunds.RefundOrder) error) {
+	h.Handle(EventRefund, func(notify *Notify) (err error) {
+		refundOrder := new(refunds.RefundOrder)
+		if err = notify.Unmarshal(refundOrder); err != nil {
+			return
+		}
+		refundOrder.Id = notify.Id
+		return fn(notify, refundOrder)
+	})
+}
+
+// OnPayScoreOpenOrClose 注册支付分授权、解除授权通知的处理函数
+func (h *Handler) OnPayScoreOpenOrClose(fn func(notify *Notify, response *payscore.OpenOrCloseResponse) error) {
+	handler := func(notify *Notify) (err error) {
+		response := new(payscore.OpenOrCloseResponse)
+		if err = notify.Unmarshal(response); err != nil {
+			return
+		}
+		response.NotifyId = notify.Id
+		return fn(notify, response)
+	}
+	h.Handle(EventPayScoreUserOpenService, handler)
+	h.Handle(EventPayScoreUserCloseService, handler)
+}
+
+// OnPayScoreConfirm 注册支付分确认订单通知的处理函数
+func (h *Handler) OnPayScoreConfirm(fn func(notify *Notify, order *payscore.ServiceOrder) error) {
+	h.Handle(EventPayScoreUserConfirm, payScoreOrderHandler(fn))
+}
+
+// OnPayScorePaid 注册支付分支付成功通知的处理函数
+func (h *Handler) OnPayScorePaid(fn func(notify *Notify, order *payscore.ServiceOrder) error) {
+	h.Handle(EventPayScoreUserPaid, payScoreOrderHandler(fn))
+}
+
+func payScoreOrderHandler(fn func(notify *Notify, order *payscore.ServiceOrder) error) HandlerFunc {
+	return func(notify *Notify) (err error) {
+		order := new(payscore.ServiceOrder)
+		if err = notify.Unmarshal(order); err != nil {
+			return
+		}
+		order.Id = notify.Id
+		return fn(notify, order)
+	}
+}
+
+// OnParkingStateChange 注册停车入场状态变更通知的处理函数
+func (h *Handler) OnParkingStateChange(fn func(notify *Notify, response *parking.ParkStateResponse) error) {
+	h.Handle(EventParkingStateChange, func(notify *Notify) (err error) {
+		response := new(parking.ParkStateResponse)
+		if err = notify.Unmarshal(response); err != nil {
+			return
+		}
+		response.NotifyId = notify.Id
+		return fn(notify, response)
+	})
+}
+
+// OnParkingPayment 注册停车服务扣费结果通知的处理函数, 匹配所有TRANSACTION.*通知, 如果同时注册了OnTransactionSuccess,
+// 支付成功通知由OnTransactionSuccess处理, 因此停车服务请使用单独的通知地址
+func (h *Handler) OnParkingPayment(fn func(notify *Notify, response *parking.PaymentResponse) error) {
+	h.Handle(EventParkingTransaction, func(notify *Notify) (err error) {
+		response := new(parking.PaymentResponse)
+		if err = notify.Unmarshal(response); err != nil {
+			return
+		}
+		response.NotifyId = notify.Id
+		return fn(notify, response)
+	})
+}
+
+// OnComplaint 注册消费者投诉通知的处理函数
+func (h *Handler) OnComplaint(fn func(notify *Notify, response *complaints.ComplaintNotifyResponse) error) {
+	h.Handle(EventComplaint, func(notify *Notify) (err error) {
+		response := new(complaints.ComplaintNotifyResponse)
+		if err = notify.Unmarshal(response); err != nil {
+			return
+		}
+		response.NotifyId = notify.Id
+		return fn(notify, response)
+	})
+}
+
+// OnProfitSharing 注册分账动账通知的处理函数
+func (h *Handler) OnProfitSharing(fn func(notify *Notify, response *profitsharing.SharingNotifyResponse) error) {
+	h.Handle(EventProfitSharing, func(notify *Notify) (err error) {
+		response := new(profitsharing.SharingNotifyResponse)
+		if err = notify.Unmarshal(response); err != nil {
+			return
+		}
+		response.NotifyId = notify.Id
+		return fn(notify, response)
+	})
+}
+
+// OnCouponUse 注册代金券核销通知的处理函数
+func (h *Handler) OnCouponUse(fn func(notify *Notify, response *favor.UseResponse) error) {
+	h.Handle(EventCouponUse, func(notify *Notify) (err error) {
+		response := new(favor.UseResponse)
+		if err = notify.Unmarshal(response); err != nil {
+			return
+		}
+		response.NotifyId = notify.Id
+		return fn(notify, response)
+	})
+}
+
+// OnCouponSend 注册商家券领券通知的处理函数
+func (h *Handler) OnCouponSend(fn func(notify *Notify, response *busifavor.ReceiveCouponResponse) error) {
+	h.Handle(EventCouponSend, func(notify *Notify) (err error) {
+		response := new(busifavor.ReceiveCouponResponse)
+		if err = notify.Unmarshal(response); err != nil {
+			return
+		}
+		response.NotifyId = notify.Id
+		return fn(notify, response)
+	})
+}
+
+// OnViolation 注册商户平台处置记录通知的处理函数
+func (h *Handler) OnViolation(fn func(notify *Notify, response *violation.NotifyResponse) error) {
+	h.Handle(EventViolation, func(notify *Notify) (err error) {
+		response := new(violation.NotifyResponse)
+		if err = notify.Unmarshal(response); err != nil {
+			return
+		}
+		response.NotifyId = notify.Id
+		return fn(notify, response)
+	})
+}
@@ -0,0 +1,170 @@
+package notify
+
+import (
+	"encoding/json"
+	stderrors "errors"
+	"io/ioutil"
+	"net/http"
+	"strings"
+	"sync"
+
+	"github.com/pyihe/wechat-sdk/v3/model"
+	"github.com/pyihe/wechat-sdk/v3/pkg/errors"
+	"github.com/pyihe/wechat-sdk/v3/service"
+)
+
+// Notify 验签、解密后的微信通知
+type Notify struct {
+	*model.WechatNotifyResponse               // 通知的公共信息, 如通知ID、通知类型等
+	Request                     *http.Request // 原始的通知请求, 可以通过Request.Context()获取请求的ctx
+	PlainData                   []byte        // 解密后的通知资源数据明文
+}
+
+// Unmarshal 将解密后的通知资源数据反序列化到dst中
+func (n *Notify) Unmarshal(dst interface{}) error {
+	return json.Unmarshal(n.PlainData, dst)
+}
+
+// HandlerFunc 通知处理函数, 返回nil时回复微信处理成功, 否则回复处理失败, 微信会重新发送通知
+type HandlerFunc func(notify *Notify) error
+
+// Option Handler配置项
+type Option func(*Handler)
+
+// WithErrorHandler 设置通知处理失败时的回调, 如验签失败、解密失败、处理函数返回error等, 可用于记录日志
+func WithErrorHandler(fn func(request *http.Request, err error)) Option {
+	return func(h *Handler) {
+		h.onError = fn
+	}
+}
+
+// Handler 微信通知的http.Handler, 统一完成验签、解密, 并根据event_type将通知分发给对应的处理函数,
+// 最后根据处理结果回复微信
+type Handler struct {
+	config  *service.Config
+	onError func(request *http.Request, err error)
+
+	mu       sync.RWMutex
+	handlers map[string]HandlerFunc // key为event_type
+	patterns []string               // 以*结尾的event_type, 按注册顺序匹配
+}
+
+// NewHandler 创建通知处理器, config用于验签、解密
+func NewHandler(config *service.Config, opts ...Option) *Handler {
+	h := &Handler{
+		config:   config,
+		handlers: make(map[string]HandlerFunc),
+	}
+	for _, op := range opts {
+		op(h)
+	}
+	return h
+}
+
+// Handle 注册eventType对应的处理函数, 重复注册时后注册的生效
+// eventType以*结尾时按前缀匹配(如REFUND.*匹配所有退款通知), 单独的*匹配所有通知, 精确匹配优先于前缀匹配
+func (h *Handler) Handle(eventType string, fn HandlerFunc) {
+	if fn == nil {
+		return
+	}
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	if _, ok := h.handlers[eventType]; !ok && strings.HasSuffix(eventType, "*") {
+		h.patterns = append(h.patterns, eventType)
+	}
+	h.handlers[eventType] = fn
+}
+
+// ServeHTTP 实现http.Handler
+// 验签或解密失败时回复401或400, 没有对应的处理函数时回复404, 处理函数返回error或者同一通知正在处理中时回复500,
+// 处理成功或者通知已处理过时回复200, 处理成功后通知ID才会被标记为已处理
+func (h *Handler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if h.config == nil {
+		h.fail(w, r, http.StatusInternalServerError, errors.ErrNoConfig)
+		return
+	}
+	body, err := ioutil.ReadAll(r.Body)
+	_ = r.Body.Close()
+	if err != nil {
+		h.fail(w, r, http.StatusBadRequest, err)
+		return
+	}
+
+	notifyResponse, plainData, err := h.config.DecodeWechatNotify(r.Header, body)
+	if stderrors.Is(err, errors.ErrDuplicateNotify) {
+		// 已经处理过的通知(微信未收到回复时会重复发送), 直接回复成功
+		reply(w, http.StatusOK, "SUCCESS", "成功")
+		return
+	}
+	if stderrors.Is(err, errors.ErrNotifyProcessing) {
+		// 上一次投递尚未处理完成, 回复失败让微信稍后重发, 避免上一次处理失败时通知丢失
+		h.fail(w, r, http.StatusInternalServerError, err)
+		return
+	}
+	if err != nil {
+		status := http.StatusBadRequest
+		if notifyResponse == nil {
+			// 公共信息尚未解析说明签名校验未通过
+			status = http.StatusUnauthorized
+		}
+		h.fail(w, r, status, err)
+		return
+	}
+
+	fn := h.match(notifyResponse.EventType)
+	if fn == nil {
+		h.config.ForgetNotify(r.Header, notifyResponse.Id)
+		h.fail(w, r, http.StatusNotFound, &unhandledError{eventType: notifyResponse.EventType})
+		return
+	}
+	if err = fn(&Notify{WechatNotifyResponse: notifyResponse, Request: r, PlainData: plainData}); err != nil {
+		// 处理失败时删除重放记录, 以便微信重新发送的通知可以再次被处理
+		h.config.ForgetNotify(r.Header, notifyResponse.Id)
+		h.fail(w, r, http.StatusInternalServerError, err)
+		return
+	}
+	if err = h.config.AckNotify(notifyResponse.Id); err != nil && h.onError != nil {
+		// 业务已经处理成功, 标记失败时仍然回复成功
+		h.onError(r, err)
+	}
+	reply(w, http.StatusOK, "SUCCESS", "成功")
+}
+
+// match 查找eventType对应的处理函数
+func (h *Handler) match(eventType string) HandlerFunc {
+	h.mu.RLock()
+	defer h.mu.RUnlock()
+	if fn, ok := h.handlers[eventType]; ok {
+		return fn
+	}
+	for _, pattern := range h.patterns {
+		if strings.HasPrefix(eventType, strings.TrimSuffix(pattern, "*")) {
+			return h.handlers[pattern]
+		}
+	}
+	return nil
+}
+
+func (h *Handler) fail(w http.ResponseWriter, r *http.Request, status int, err error) {
+	if h.onError != nil {
+		h.onError(r, err)
+	}
+	reply(w, status, "FAIL", err.Error())
+}
+
+// reply 按照微信要求的格式回复通知
+func reply(w http.ResponseWriter, status int, code, message string) {
+	body, _ := json.Marshal(map[string]string{"code": code, "message": message})
+	w.Header().Set("Content-Type", service.ContentTypeJSON)
+	w.WriteHeader(status)
+	_, _ = w.Write(body)
+}
+
+// unhandledError 没有注册处理函数的通知类型
+type unhandledError struct {
+	eventType string
+}
+
+func (e *unhandledError) Error() string {
+	return "未注册处理函数的通知类型: " + e.eventType
+}
@@ -0,0 +1,105 @@
+package notify
+
+import (
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/pyihe/wechat-sdk/v3/service/payment/merchant"
+	"github.com/pyihe/wechat-sdk/v3/service/refunds"
+	"github.com/pyihe/wechat-sdk/v3/service/wechatpaytest"
+)
+
+func TestHandler(t *testing.T) {
+	server := wechatpaytest.NewServer()
+	defer server.Close()
+
+	var failures int
+	handler := NewHandler(server.NewConfig(), WithErrorHandler(func(request *http.Request, err error) {
+		failures++
+	}))
+	var paid *merchant.PrepayOrder
+	handler.OnTransactionSuccess(func(notify *Notify, order *merchant.PrepayOrder) error {
+		paid = order
+		return nil
+	})
+	handler.OnRefund(func(notify *Notify, refundOrder *refunds.RefundOrder) error {
+		return errors.New("refund not handled")
+	})
+
+	cases := []struct {
+		request *http.Request
+		status  int
+		code    string
+	}{
+		{server.NewNotifyRequest(EventTransactionSuccess, map[string]string{"out_trade_no": "1217752501201407033233368018", "trade_state": "SUCCESS"}), http.StatusOK, "SUCCESS"},
+		{server.NewNotifyRequest(EventRefundAbnormal, map[string]string{"out_refund_no": "1217752501201407033233368019"}), http.StatusInternalServerError, "FAIL"},
+		{server.NewNotifyRequest("COUPON.USE", map[string]string{"stock_id": "9856000"}), http.StatusNotFound, "FAIL"},
+	}
+	// 篡改body后签名校验失败
+	tampered := server.NewNotifyRequest(EventTransactionSuccess, map[string]string{"out_trade_no": "1217752501201407033233368018"})
+	tampered.Header.Set("Wechatpay-Nonce", "tampered")
+	cases = append(cases, struct {
+		request *http.Request
+		status  int
+		code    string
+	}{tampered, http.StatusUnauthorized, "FAIL"})
+
+	for i, c := range cases {
+		recorder := httptest.NewRecorder()
+		handler.ServeHTTP(recorder, c.request)
+		if recorder.Code != c.status || !strings.Contains(recorder.Body.String(), `"code":"`+c.code+`"`) {
+			t.Fatalf("case %d: unexpected reply %d %s", i, recorder.Code, recorder.Body.String())
+		}
+	}
+	if paid == nil || paid.OutTradeNo != "1217752501201407033233368018" || paid.Id == "" {
+		t.Fatalf("unexpected order: %+v", paid)
+	}
+	if failures != 3 {
+		t.Fatalf("unexpected failures: %d", failures)
+	}
+}
+
+func TestHandlerRedelivery(t *testing.T) {
+	server := wechatpaytest.NewServer()
+	defer server.Close()
+
+	handler := NewHandler(server.NewConfig())
+	first := server.NewNotifyRequest("TEST.REDELIVERY", map[string]string{"id": "1"})
+	inFlight, retry, duplicate := server.ResendNotifyRequest(first), server.ResendNotifyRequest(first), server.ResendNotifyRequest(first)
+
+	var calls int
+	var inFlightCode int
+	handler.Handle("TEST.REDELIVERY", func(notify *Notify) error {
+		calls++
+		if calls == 1 {
+			// 第一次投递处理完成之前收到重发的通知, 不能回复成功
+			recorder := httptest.NewRecorder()
+			handler.ServeHTTP(recorder, inFlight)
+			inFlightCode = recorder.Code
+			return errors.New("first delivery failed")
+		}
+		return nil
+	})
+
+	for i, c := range []struct {
+		request *http.Request
+		status  int
+		calls   int
+	}{
+		{first, http.StatusInternalServerError, 1},
+		{retry, http.StatusOK, 2},
+		{duplicate, http.StatusOK, 2},
+	} {
+		recorder := httptest.NewRecorder()
+		handler.ServeHTTP(recorder, c.request)
+		if recorder.Code != c.status || calls != c.calls {
+			t.Fatalf("delivery %d: unexpected reply %d %s, calls: %d", i, recorder.Code, recorder.Body.String(), calls)
+		}
+	}
+	if inFlightCode != http.StatusInternalServerError {
+		t.Fatalf("in-flight redelivery should fail, got: %d", inFlightCode)
+	}
+}
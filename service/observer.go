@@ -0,0 +1,152 @@
+package service
+
+import (
+	"bytes"
+	"context"
+	"io/ioutil"
+	"net/http"
+	"strings"
+	"time"
+	"unicode"
+
+	"github.com/pyihe/wechat-sdk/v3/pkg/errors"
+)
+
+// 被观察的调用类型
+const (
+	OperationRequest  = "request"  // RequestWithSign发起的API请求
+	OperationUpload   = "upload"   // UploadMedia上传文件
+	OperationDownload = "download" // Download下载账单等数据
+	OperationNotify   = "notify"   // 解析微信通知
+)
+
+// Event 一次API调用(或者一次通知解析)的观察结果
+// 开启重试时每次尝试都会产生一个Event, Attempt为第几次尝试
+type Event struct {
+	Operation  string        // 调用类型, 如: OperationRequest
+	Method     string        // 请求方法, 通知为空
+	Endpoint   string        // 去掉参数后的接口模板, 如: /v3/pay/transactions/out-trade-no/{id}, 通知为通知类型, 如: TRANSACTION.SUCCESS
+	Path       string        // 实际请求的路径(不包含query参数)
+	MchId      string        // 发起请求的商户号
+	Attempt    int           // 第几次尝试, 从1开始
+	StatusCode int           // http状态码, 请求未完成(如网络错误)以及通知时为0
+	Code       string        // 微信返回的错误码, 如: ORDERPAID, 请求成功时为空
+	RequestId  string        // 应答头中的Request-ID, 通知为通知ID
+	Start      time.Time     // 开始时间
+	Duration   time.Duration // 耗时
+	Err        error         // 网络错误、限流错误(如errors.ErrRateLimited)或者通知解析错误, 微信返回的业务错误请使用StatusCode、Code判断
+}
+
+// Observer 观察每一次API调用, 用于统计指标或者记录链路追踪
+// Observe在请求所在的goroutine中同步调用, 实现不应该阻塞, 并且需要保证并发安全
+type Observer interface {
+	Observe(ctx context.Context, event *Event)
+}
+
+// ObserverFunc 函数形式的Observer
+type ObserverFunc func(ctx context.Context, event *Event)
+
+func (f ObserverFunc) Observe(ctx context.Context, event *Event) {
+	f(ctx, event)
+}
+
+// WithObserver 添加观察者, 对RequestWithSign、UploadMedia、Download以及通知解析生效, 按照添加的顺序依次调用
+func WithObserver(observers ...Observer) Option {
+	return func(config *Config) {
+		config.observers = append(config.observers, observers...)
+	}
+}
+
+// paramPrefixes 其后的路径段一定是参数的资源名, 如: /v3/pay/transactions/out-trade-no/{out_trade_no}
+var paramPrefixes = map[string]bool{
+	"out-trade-no":        true,
+	"out-refund-no":       true,
+	"id":                  true,
+	"refunds":             true,
+	"business_code":       true,
+	"applyment_id":        true,
+	"sub_merchants":       true,
+	"complaints-v2":       true,
+	"users":               true,
+	"user-authorizations": true,
+	"appids":              true,
+	"stocks":              true,
+	"pay-receipts":        true,
+	"activities":          true,
+	"guides":              true,
+	"serviceorder":        true,
+	"authorization-code":  true,
+	"openid":              true,
+	"return-orders":       true,
+	"brand-configs":       true,
+	"merchant-configs":    true,
+}
+
+// EndpointTemplate 去掉path中的query参数, 并将商户单号、微信单号、openid等路径参数替换为{id}, 用于指标的标签以避免基数爆炸
+// paramPrefixes中资源名之后的路径段, 以及包含大写字母、4个及以上数字或者特殊字符的路径段视为参数, 如:
+// /v3/pay/transactions/out-trade-no/1217752501201407033233368018?mchid=1230000109 => /v3/pay/transactions/out-trade-no/{id}
+func EndpointTemplate(path string) string {
+	if i := strings.IndexByte(path, '?'); i >= 0 {
+		path = path[:i]
+	}
+	segments := strings.Split(path, "/")
+	for i := len(segments) - 1; i >= 0; i-- {
+		if segments[i] == "" {
+			continue
+		}
+		if (i > 0 && paramPrefixes[segments[i-1]]) || isPathParam(segments[i]) {
+			segments[i] = "{id}"
+		}
+	}
+	return strings.Join(segments, "/")
+}
+
+func isPathParam(segment string) bool {
+	digits := 0
+	for _, r := range segment {
+		switch {
+		case unicode.IsDigit(r):
+			digits++
+		case r >= 'a' && r <= 'z', r == '-', r == '_':
+		default:
+			return true
+		}
+	}
+	return digits >= 4
+}
+
+// observeRequest 通知观察者一次http请求的结果, 请求失败时会读取应答body获取错误码, 并将body还原以便后续解析
+func (c *Config) observeRequest(operation string, request *http.Request, attempt int, start time.Time, response *http.Response, err error) {
+	if len(c.observers) == 0 {
+		return
+	}
+	event := &Event{
+		Operation: operation,
+		Method:    request.Method,
+		Endpoint:  EndpointTemplate(request.URL.Path),
+		Path:      request.URL.Path,
+		MchId:     c.mchId,
+		Attempt:   attempt,
+		Start:     start,
+		Duration:  time.Since(start),
+		Err:       err,
+	}
+	if response != nil {
+		event.StatusCode = response.StatusCode
+		event.RequestId = response.Header.Get("Request-ID")
+		if code := response.StatusCode; code != http.StatusOK && code != http.StatusNoContent && response.Body != nil {
+			body, _ := ioutil.ReadAll(response.Body)
+			_ = response.Body.Close()
+			response.Body = ioutil.NopCloser(bytes.NewReader(body))
+			event.Code = errors.NewAPIError(code, event.RequestId, body).Code
+		}
+	}
+	c.notifyObservers(event)
+}
+
+func (c *Config) notifyObservers(event *Event) {
+	ctx := c.Context()
+	for _, observer := range c.observers {
+		observer.Observe(ctx, event)
+	}
+}
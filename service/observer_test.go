@@ -0,0 +1,78 @@
+package service
+
+import (
+	"context"
+	stderrors "errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/pyihe/wechat-sdk/v3/pkg/errors"
+)
+
+func TestEndpointTemplate(t *testing.T) {
+	cases := map[string]string{
+		"/v3/pay/transactions/out-trade-no/1217752501201407033233368018?mchid=1230000109": "/v3/pay/transactions/out-trade-no/{id}",
+		"/v3/pay/transactions/jsapi":                                                          "/v3/pay/transactions/jsapi",
+		"/v3/marketing/favor/users/oUpF8uMuAJO_M2pxb1Q9zNjWeS6o/coupons":                      "/v3/marketing/favor/users/{id}/coupons",
+		"/v3/applyment4sub/applyment/business_code/1900013511_10000":                          "/v3/applyment4sub/applyment/business_code/{id}",
+		"/v3/merchant-service/complaints-v2/200201820200101080076610000/negotiation-historys": "/v3/merchant-service/complaints-v2/{id}/negotiation-historys",
+		"/v3/pay/transactions/out-trade-no/order_abc12/close":                                 "/v3/pay/transactions/out-trade-no/{id}/close",
+		"/v3/refund/domestic/refunds/refund_abc":                                              "/v3/refund/domestic/refunds/{id}",
+		"/v3/marketing/busifavor/users/user/coupons/code/appids/app":                          "/v3/marketing/busifavor/users/{id}/coupons/code/appids/{id}",
+		"/v3/marketing/busifavor/coupons/use":                                                 "/v3/marketing/busifavor/coupons/use",
+		"/v3/profitsharing/orders/unfreeze":                                                   "/v3/profitsharing/orders/unfreeze",
+	}
+	for path, want := range cases {
+		if got := EndpointTemplate(path); got != want {
+			t.Fatalf("EndpointTemplate(%s) = %s, want %s", path, got, want)
+		}
+	}
+}
+
+func TestObserver(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Request-ID", "08F78BB5AF0610D302A6E8BE1E")
+		w.WriteHeader(http.StatusNotFound)
+		_, _ = w.Write([]byte(`{"code":"ORDER_NOT_EXIST","message":"订单不存在"}`))
+	}))
+	defer server.Close()
+
+	var events []*Event
+	observer := ObserverFunc(func(ctx context.Context, event *Event) {
+		events = append(events, event)
+	})
+	config := newTestConfig(t, server.URL, WithObserver(observer))
+	response, err := config.RequestWithSign(http.MethodGet, "/v3/pay/transactions/id/4200000000000000000000000000?mchid=1900000001", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(events) != 1 {
+		t.Fatalf("expected 1 event, got %d", len(events))
+	}
+	event := events[0]
+	if event.Operation != OperationRequest || event.Method != http.MethodGet || event.Endpoint != "/v3/pay/transactions/id/{id}" ||
+		event.MchId != "1900000001" || event.StatusCode != http.StatusNotFound || event.Code != "ORDER_NOT_EXIST" ||
+		event.RequestId != "08F78BB5AF0610D302A6E8BE1E" || event.Attempt != 1 || event.Duration <= 0 {
+		t.Fatalf("unexpected event: %+v", event)
+	}
+
+	// 观察者读取了错误应答的body后, 调用方仍然可以正常解析
+	if _, err = config.ParseWechatResponse(response, nil); !stderrors.Is(err, errors.ErrOrderNotExist) {
+		t.Fatalf("expected ORDER_NOT_EXIST, got: %v", err)
+	}
+
+	if _, err = config.Download(server.URL + "/v3/billdownload/file?token=abc"); !stderrors.Is(err, errors.ErrOrderNotExist) {
+		t.Fatalf("expected ORDER_NOT_EXIST, got: %v", err)
+	}
+	if len(events) != 2 || events[1].Operation != OperationDownload || events[1].Endpoint != "/v3/billdownload/file" {
+		t.Fatalf("unexpected download event: %+v", events[len(events)-1])
+	}
+
+	if _, _, err = config.DecodeWechatNotify(http.Header{}, []byte("{}")); err == nil {
+		t.Fatal("expected notify error")
+	}
+	if len(events) != 3 || events[2].Operation != OperationNotify || events[2].Err == nil {
+		t.Fatalf("unexpected notify event: %+v", events[len(events)-1])
+	}
+}
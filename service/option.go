@@ -2,6 +2,7 @@ package service
 
 import (
 	"bytes"
+	"context"
 	"crypto"
 	"crypto/rsa"
 	"crypto/x509"
@@ -14,7 +15,9 @@ import (
 	"path/filepath"
 	"reflect"
 	"runtime"
+	"strconv"
 	"strings"
+	"sync"
 	"time"
 
 	"github.com/pyihe/secret"
@@ -23,8 +26,8 @@ import (
 	"github.com/pyihe/wechat-sdk/v3/pkg"
 	"github.com/pyihe/wechat-sdk/v3/pkg/aess"
 	"github.com/pyihe/wechat-sdk/v3/pkg/errors"
-	"github.com/pyihe/wechat-sdk/v3/pkg/files"
 	"github.com/pyihe/wechat-sdk/v3/pkg/rsas"
+	"github.com/pyihe/wechat-sdk/v3/pkg/validate"
 )
 
 const (
@@ -70,29 +73,28 @@ func WithSerialNo(serialNo string) Option {
 	}
 }
 
+// WithPrivateKey 从文件加载商户API私钥, 私钥格式(PKCS#1或PKCS#8)会自动识别, level仅为兼容保留
 func WithPrivateKey(file string, level secret.PKCSLevel) Option {
-	return func(config *Config) {
-		if err := config.merchantCipher.SetRSAPrivateKey(file, level); err != nil {
-			panic(err)
-		}
-	}
+	return WithPrivateKeyFrom(FromFile(file))
 }
 
+// WithPublicKey 从文件加载微信支付平台证书
 func WithPublicKey(file string) Option {
+	return WithPublicKeyFrom(FromFile(file))
+}
+
+// WithPlatformPublicKey 设置微信支付平台公钥(公钥模式), 用于验证微信应答和通知的签名以及加密敏感信息
+// id: 微信支付公钥ID, 形如PUB_KEY_ID_xxx, 对应应答和通知中的Wechatpay-Serial
+// pemOrFile: PEM格式的公钥内容或者公钥文件路径
+// 公钥模式可以与平台证书模式同时使用, 以便平滑迁移, 同时存在时加密敏感信息优先使用公钥模式
+func WithPlatformPublicKey(id string, pemOrFile string) Option {
+	return WithPlatformPublicKeyFrom(id, fromPEMOrFile(pemOrFile))
+}
+
+// WithDomain 设置微信支付API的域名, 默认为https://api.mch.weixin.qq.com, 可用于切换备用域名或者连接测试服务器
+func WithDomain(domain string) Option {
 	return func(config *Config) {
-		cert, err := files.LoadCertificate(file)
-		if err != nil {
-			panic(err)
-		}
-		publicKey, ok := cert.PublicKey.(*rsa.PublicKey)
-		if !ok {
-			panic("加载证书失败: 请确认证书是否为RSA PublicKey!")
-		}
-		serialNo := strings.ToUpper(cert.SerialNumber.Text(16))
-		if err = config.wechatCipher.SetRSAPublicKey(publicKey, 0); err != nil {
-			panic(err)
-		}
-		config.certificates.Add(serialNo, cert)
+		config.domain = strings.TrimRight(domain, "/")
 	}
 }
 
@@ -103,6 +105,9 @@ func WithHttpClient(client *http.Client) Option {
 }
 
 type Config struct {
+	// 发起请求时使用的上下文, 用于取消请求、设置超时时间以及传递值, 为nil时使用context.Background()
+	ctx context.Context
+
 	// v3版本API域名公共部分
 	domain string
 
@@ -136,25 +141,113 @@ type Config struct {
 	// 用于验证hash值
 	hasher secret.Hasher
 
-	// 微信平台公钥证书, key为serialNo, value为*x509.Certificate
-	certificates pkg.Param
+	// 商户API私钥, 用于校验私钥与商户API证书是否匹配
+	privateKey *rsa.PrivateKey
+
+	// 商户API证书, 用于校验商户证书序列号
+	merchantCert *x509.Certificate
+
+	// 加载配置项时遇到的第一个错误
+	err error
+
+	// 商户签名器, 为nil时使用merchantCipher签名
+	signer Signer
+
+	// 商户解密器, 为nil时使用merchantCipher解密
+	decrypter Decrypter
+
+	// 微信平台证书、公钥以及证书刷新函数, Config与其浅拷贝(WithContext等)共享同一份
+	keys *platformKeys
+
+	// 通过WithPlatformPublicKey配置的公钥ID, 加密敏感信息时优先使用
+	publicKeyId string
+
+	// 请求失败后的重试策略, 为nil时不重试
+	retryPolicy *RetryPolicy
+
+	// 请求中间件
+	middlewares []Middleware
+
+	// 应答解析拦截器
+	parseInterceptors []ParseInterceptor
+
+	// 应答、通知的Wechatpay-Timestamp与本地时间允许的最大误差, 小于等于0时不校验
+	clockSkew time.Duration
+
+	// 已使用的Wechatpay-Nonce, 为nil时不校验重放
+	nonceStore NonceStore
+
+	// 处理中以及已处理的通知ID, 与nonceStore分开保存, 避免被大量应答的随机串淘汰, 为nil时不校验重复通知
+	notifyStore NonceStore
+
+	// 是否通过WithNotifyStore单独设置了notifyStore
+	customNotifyStore bool
+
+	// 解析应答时是否保留敏感字段的密文
+	keepCiphertext bool
+
+	// API调用的观察者, 用于统计指标以及链路追踪
+	observers []Observer
+
+	// 调试日志, 为nil时不记录
+	logger Logger
+
+	// 客户端限流器, 为nil时不限流
+	rateLimiter *RateLimiter
 }
 
+// NewConfig 创建Config, 配置项加载失败(如私钥文件不存在)时panic, 如果需要返回error, 请使用NewConfigE
 func NewConfig(opts ...Option) *Config {
+	c := newConfig(opts...)
+	if c.err != nil {
+		panic(c.err)
+	}
+	return c
+}
+
+func newConfig(opts ...Option) *Config {
 	var c = &Config{
 		domain:         "https://api.mch.weixin.qq.com",
 		httpClient:     http.DefaultClient,
 		merchantCipher: secret.NewCipher(),
 		wechatCipher:   secret.NewCipher(),
 		hasher:         secret.NewHasher(),
-		certificates:   pkg.NewParam(),
+		keys:           &platformKeys{certificates: pkg.NewParam(), publicKeys: make(map[string]*rsa.PublicKey)},
+		clockSkew:      DefaultClockSkew,
+		nonceStore:     NewMemoryNonceStore(defaultNonceCapacity),
+		notifyStore:    NewMemoryNonceStore(defaultNotifyCapacity),
 	}
 	for _, op := range opts {
 		op(c)
 	}
+	// 未设置证书序列号时使用商户API证书的序列号
+	if c.serialNo == "" && c.merchantCert != nil {
+		c.serialNo = strings.ToUpper(c.merchantCert.SerialNumber.Text(16))
+	}
 	return c
 }
 
+// WithContext 返回一个绑定了ctx的Config浅拷贝, 通过该拷贝发起的所有请求(包括多次请求的流程, 如下载账单、下载证书,
+// 以及遇到未知的Wechatpay-Serial时触发的证书刷新)都会使用ctx, 以便调用方取消请求或者设置超时时间, 原Config不受影响;
+// 拷贝与原Config共享平台证书以及公钥, 通过任意一方添加的证书、公钥对双方都生效; ctx为nil时直接返回c
+func (c *Config) WithContext(ctx context.Context) *Config {
+	if ctx == nil {
+		return c
+	}
+	c2 := new(Config)
+	*c2 = *c
+	c2.ctx = ctx
+	return c2
+}
+
+// Context 返回Config绑定的上下文, 未绑定时返回context.Background()
+func (c *Config) Context() context.Context {
+	if c.ctx != nil {
+		return c.ctx
+	}
+	return context.Background()
+}
+
 func (c *Config) GetDomain() string {
 	return c.domain
 }
@@ -179,7 +272,13 @@ func (c *Config) GetApiKey() string {
 	return c.apiKey
 }
 
+// GetSerialNo 获取商户API证书序列号, 设置了签名器时优先使用签名器的序列号
 func (c *Config) GetSerialNo() string {
+	if c.signer != nil {
+		if serialNo := c.signer.SerialNo(); serialNo != "" {
+			return serialNo
+		}
+	}
 	return c.serialNo
 }
 
@@ -195,38 +294,115 @@ func (c *Config) GetWechatCipher() secret.Cipher {
 	return c.wechatCipher
 }
 
+// platformKeys 微信平台证书以及公钥, 证书可能在后台被更新, 所有字段由mu保护
+type platformKeys struct {
+	mu sync.RWMutex
+
+	// 微信平台公钥证书, key为serialNo, value为*x509.Certificate
+	certificates pkg.Param
+
+	// 微信支付平台公钥(公钥模式), key为公钥ID, value为*rsa.PublicKey
+	publicKeys map[string]*rsa.PublicKey
+
+	// 最近一次通过AddPublicKey添加的公钥ID, 未配置publicKeyId时用于加密敏感信息
+	latestPublicKeyId string
+
+	// 遇到未知的Wechatpay-Serial时调用, 用于刷新微信平台证书
+	refresher func(ctx context.Context, serialNo string) error
+}
+
 func (c *Config) AddCertificate(serialNo string, cert *x509.Certificate) {
 	serialNo = strings.ToUpper(serialNo)
-	c.certificates.Add(serialNo, cert)
+	c.keys.mu.Lock()
+	c.keys.certificates.Add(serialNo, cert)
+	c.keys.mu.Unlock()
+}
+
+// RemoveCertificate 删除内存中序列号为serialNo的微信平台证书, 一般由证书管理器在证书过期后调用
+func (c *Config) RemoveCertificate(serialNo string) {
+	serialNo = strings.ToUpper(serialNo)
+	c.keys.mu.Lock()
+	c.keys.certificates.Delete(serialNo)
+	c.keys.mu.Unlock()
+}
+
+// AddPublicKey 添加微信支付平台公钥(公钥模式), id为微信支付公钥ID
+func (c *Config) AddPublicKey(id string, publicKey *rsa.PublicKey) {
+	id = strings.ToUpper(id)
+	c.keys.mu.Lock()
+	c.keys.publicKeys[id] = publicKey
+	c.keys.latestPublicKeyId = id
+	c.keys.mu.Unlock()
+}
+
+// GetCertificates 获取当前内存中所有的微信平台证书, key为证书序列号
+func (c *Config) GetCertificates() map[string]*x509.Certificate {
+	c.keys.mu.RLock()
+	defer c.keys.mu.RUnlock()
+	certs := make(map[string]*x509.Certificate, len(c.keys.certificates))
+	c.keys.certificates.Range(func(key string, value interface{}) (breakOut bool) {
+		if cert, ok := value.(*x509.Certificate); ok && cert != nil {
+			certs[key] = cert
+		}
+		return
+	})
+	return certs
 }
 
+// SetCertificateRefresher 设置微信平台证书的刷新函数, 当解析微信应答或者通知时遇到内存中不存在的Wechatpay-Serial,
+// 会使用当前Config绑定的上下文调用fn刷新证书后再次查找, 一般由证书管理器设置
+func (c *Config) SetCertificateRefresher(fn func(ctx context.Context, serialNo string) error) {
+	c.keys.mu.Lock()
+	c.keys.refresher = fn
+	c.keys.mu.Unlock()
+}
+
+// GetValidPublicKey 获取用于加密敏感信息的微信平台公钥及其序列号, 优先使用公钥模式的公钥, 此时serialNo为公钥ID
+// 公钥模式下优先使用WithPlatformPublicKey配置的公钥, 否则使用最近一次添加的公钥;
+// 证书模式下使用当前有效且过期时间最晚的证书, 过期的证书由证书管理器清理, 这里只跳过不删除
 func (c *Config) GetValidPublicKey() (serialNo string, publicKey *rsa.PublicKey) {
 	now := time.Now()
-	c.certificates.Range(func(key string, value interface{}) (breakOut bool) {
+	c.keys.mu.RLock()
+	defer c.keys.mu.RUnlock()
+	for _, id := range []string{c.publicKeyId, c.keys.latestPublicKeyId} {
+		if key, ok := c.keys.publicKeys[id]; ok && id != "" {
+			return id, key
+		}
+	}
+	var latest *x509.Certificate
+	c.keys.certificates.Range(func(key string, value interface{}) (breakOut bool) {
 		data, ok := value.(*x509.Certificate)
 		if !ok || data == nil {
 			return
 		}
-		// 如果证书还没开始生效，
-		if now.Before(data.NotBefore) {
+		// 跳过还没开始生效或者已经过期的证书
+		if now.Before(data.NotBefore) || now.After(data.NotAfter) {
 			return
 		}
-		// 如果已经过期
-		if now.After(data.NotAfter) {
-			c.certificates.Delete(key)
+		rsaKey, ok := data.PublicKey.(*rsa.PublicKey)
+		if !ok {
 			return
 		}
-		serialNo = key
-		publicKey = data.PublicKey.(*rsa.PublicKey)
-		breakOut = true
+		// NotAfter相同时按序列号排序, 保证结果稳定
+		if latest == nil || data.NotAfter.After(latest.NotAfter) || (data.NotAfter.Equal(latest.NotAfter) && key > serialNo) {
+			latest, serialNo, publicKey = data, key, rsaKey
+		}
 		return
 	})
 	return
 }
 
+// GetRSAPublicKey 根据Wechatpay-Serial获取微信平台公钥, serialNo可以是平台证书序列号, 也可以是公钥ID
 func (c *Config) GetRSAPublicKey(serialNo string) *rsa.PublicKey {
 	serialNo = strings.ToUpper(serialNo)
-	data, ok := c.certificates.Get(serialNo)
+	c.keys.mu.RLock()
+	publicKey, ok := c.keys.publicKeys[serialNo]
+	if ok {
+		c.keys.mu.RUnlock()
+		return publicKey
+	}
+	data, ok := c.keys.certificates.Get(serialNo)
+	c.keys.mu.RUnlock()
 	if !ok || data == nil {
 		return nil
 	}
@@ -235,13 +411,56 @@ func (c *Config) GetRSAPublicKey(serialNo string) *rsa.PublicKey {
 		return nil
 	}
 
-	publicKey, ok := certs.PublicKey.(*rsa.PublicKey)
+	publicKey, ok = certs.PublicKey.(*rsa.PublicKey)
 	if !ok || publicKey == nil {
 		return nil
 	}
 	return publicKey
 }
 
+// GetEncryptCipher 获取用于加密敏感信息的加密器, 以及需要放在请求头Wechatpay-Serial中的平台证书序列号或公钥ID,
+// 加密器使用的公钥与serialNo保证一致
+func (c *Config) GetEncryptCipher() (serialNo string, cipher secret.Cipher, err error) {
+	serialNo, publicKey := c.GetValidPublicKey()
+	if publicKey == nil {
+		err = errors.ErrNoCertificate
+		return
+	}
+	cipher = secret.NewCipher()
+	err = cipher.SetRSAPublicKey(publicKey, 0)
+	return
+}
+
+// lookupPublicKey 根据Wechatpay-Serial查找微信平台公钥, 不存在时尝试刷新证书后再次查找
+func (c *Config) lookupPublicKey(serialNo string) *rsa.PublicKey {
+	if publicKey := c.GetRSAPublicKey(serialNo); publicKey != nil {
+		return publicKey
+	}
+	c.keys.mu.RLock()
+	refresher := c.keys.refresher
+	c.keys.mu.RUnlock()
+	if refresher == nil {
+		return nil
+	}
+	if err := refresher(c.Context(), serialNo); err != nil {
+		return nil
+	}
+	return c.GetRSAPublicKey(serialNo)
+}
+
+// VerifyWechatSignature 使用指定的微信平台公钥验证微信应答或者通知的签名
+func (c *Config) VerifyWechatSignature(header http.Header, body []byte, publicKey *rsa.PublicKey) error {
+	// 1. 获取微信的签名结果
+	wechatSign := header.Get("Wechatpay-Signature") // 微信签名
+	// 2. 获取签名参数
+	timestamp := header.Get("Wechatpay-Timestamp") // 时间戳
+	nonceStr := header.Get("Wechatpay-Nonce")      // 随机字符串
+	// 3. 构造原始的签名数据
+	plainTxt := fmt.Sprintf("%v\n%v\n%v\n", timestamp, nonceStr, string(body))
+	// 4. 验证签名
+	return rsas.VerifySHA256WithRSAPublicKey(publicKey, wechatSign, plainTxt)
+}
+
 func (c *Config) agent() string {
 	return fmt.Sprintf("Pyihe-Wechat-SDK With GO(%s)/%s", runtime.Version(), runtime.GOOS)
 }
@@ -255,39 +474,84 @@ func (c *Config) agent() string {
 // signResult: 返回用于签名的各个参数，包括签名结果
 // 签名介绍详细介绍: https://pay.weixin.qq.com/wiki/doc/apiv3/wechatpay/wechatpay4_0.shtml
 func (c *Config) RequestWithSign(method, url string, body interface{}, headers ...string) (response *http.Response, err error) {
+	return c.requestWithSign(OperationRequest, method, url, body, headers...)
+}
+
+// requestWithSign 签名并发送请求, operation为通知观察者时的调用类型
+func (c *Config) requestWithSign(operation, method, url string, body interface{}, headers ...string) (response *http.Response, err error) {
 	if c.mchId == "" {
 		err = errors.ErrNoMchId
 		return
 	}
-	if c.serialNo == "" {
+	if c.GetSerialNo() == "" {
 		err = errors.ErrNoSerialNo
 		return
 	}
+	// 实现了validate.Validator的请求参数在签名前校验, map、json等原始参数不校验
+	if err = validate.Check(body); err != nil {
+		return
+	}
+	// 自动加密tag为wechatpay:"encrypt"的敏感字段, 调用方已经设置Wechatpay-Serial时说明已自行加密
+	if !hasHeader(headers, "Wechatpay-Serial") && NeedEncrypt(body) {
+		var serialNo string
+		if body, serialNo, err = c.EncryptRequest(body); err != nil {
+			return
+		}
+		headers = append(headers, "Wechatpay-Serial", serialNo)
+	}
+
 	// 构造签名主体
 	data, err := marshalJSON(body)
 	if err != nil {
-		fmt.Println(11)
 		return
 	}
 
 	method = strings.ToUpper(method) // 方法类型，转为大写
-	timestamp := time.Now().Unix()   // 时间戳
-	nonceStr := pkg.String(32)       // 随机字符串
+
+	// 每次尝试都需要使用新的随机串和时间戳重新签名
+	ctx := c.Context()
+	maxAttempts := c.retryPolicy.maxAttempts(method, url)
+	for attempt := 1; ; attempt++ {
+		if err = c.acquire(operation, method, url, attempt); err != nil {
+			return nil, err
+		}
+		var request *http.Request
+		request, err = c.newSignedRequest(method, url, data, headers...)
+		if err != nil {
+			return
+		}
+		start := time.Now()
+		response, err = c.do(request)
+		c.observeRequest(operation, request, attempt, start, response, err)
+		if attempt >= maxAttempts || !shouldRetry(ctx, response, err) {
+			return
+		}
+		wait := c.retryPolicy.backoff(attempt, response)
+		discardResponse(response)
+		if err = sleepContext(ctx, wait); err != nil {
+			return nil, err
+		}
+	}
+}
+
+// newSignedRequest 构造带签名的请求
+func (c *Config) newSignedRequest(method, url string, data []byte, headers ...string) (request *http.Request, err error) {
+	timestamp := time.Now().Unix() // 时间戳
+	nonceStr := pkg.String(32)     // 随机字符串
 
 	source := fmt.Sprintf("%s\n%s\n%d\n%s\n%s\n", method, url, timestamp, nonceStr, string(data))
-	signature, err := rsas.SignSHA256WithRSA(c.merchantCipher, source)
+	signature, err := c.Sign(source)
 	if err != nil {
-		fmt.Println(22)
 		return
 	}
 	// 签名头
-	signatureHead := fmt.Sprintf("mchid=\"%s\",nonce_str=\"%s\",signature=\"%s\",timestamp=\"%d\",serial_no=\"%s\"", c.mchId, nonceStr, signature, timestamp, c.serialNo)
-	request, err := http.NewRequest(method, c.domain+url, ioutil.NopCloser(bytes.NewReader(data)))
+	signatureHead := fmt.Sprintf("mchid=\"%s\",nonce_str=\"%s\",signature=\"%s\",timestamp=\"%d\",serial_no=\"%s\"", c.mchId, nonceStr, signature, timestamp, c.GetSerialNo())
+	request, err = http.NewRequestWithContext(c.Context(), method, c.domain+url, ioutil.NopCloser(bytes.NewReader(data)))
 	if err != nil {
 		return
 	}
 	if headerLen := len(headers); headerLen > 0 && headerLen%2 == 0 {
-		for i := 0; i < headerLen-1; i++ {
+		for i := 0; i < headerLen-1; i += 2 {
 			hk := headers[i]
 			hv := headers[i+1]
 			request.Header.Set(hk, hv)
@@ -298,7 +562,11 @@ func (c *Config) RequestWithSign(method, url string, body interface{}, headers .
 	request.Header.Set("Accept", ContentTypeJSON)
 	request.Header.Set("User-Agent", c.agent())
 	request.Header.Set("Accept-Language", "zh-CN")
-	return c.httpClient.Do(request)
+	if c.logger != nil {
+		c.debug("微信支付请求", "method", method, "url", c.redactText(url), "headers", c.redactHeader(request.Header),
+			"signing_string", c.redactSigningString(method, url, strconv.FormatInt(timestamp, 10), nonceStr, data))
+	}
+	return
 }
 
 // Request 发起普通的HTTP请求
@@ -307,7 +575,7 @@ func (c *Config) Request(method, url, contentType string, data interface{}) (res
 	if err != nil {
 		return
 	}
-	request, err := http.NewRequest(method, url, bytes.NewReader(body))
+	request, err := http.NewRequestWithContext(c.Context(), method, url, bytes.NewReader(body))
 	if err != nil {
 		return
 	}
@@ -315,7 +583,7 @@ func (c *Config) Request(method, url, contentType string, data interface{}) (res
 	request.Header.Set("Accept", contentType)
 	request.Header.Set("User-Agent", c.agent())
 	request.Header.Set("Accept-Language", "zh-CN")
-	return c.httpClient.Do(request)
+	return c.do(request)
 }
 
 // ParseWechatResponse 验证向微信服务器发送请求后从微信得到的应答签名
@@ -326,11 +594,9 @@ func (c *Config) ParseWechatResponse(response *http.Response, dst interface{}) (
 		return
 	}
 	var body []byte
-	var header = response.Header
-	var code = response.StatusCode // 根据http code 判断请求是否成功
 
 	// 获取唯一请求ID
-	requestId = header.Get("Request-ID")
+	requestId = response.Header.Get("Request-ID")
 	// 读取response body
 	body, err = ioutil.ReadAll(response.Body)
 	if err != nil {
@@ -338,97 +604,155 @@ func (c *Config) ParseWechatResponse(response *http.Response, dst interface{}) (
 	}
 	_ = response.Body.Close()
 
-	// 请求失败，根据http.StatusCode返回对应的error
-	// 同时将读取出来的body(如果有的话)反序列化到对应的结果中
+	err = c.parseResponseBody(response, body, dst)
+	err = c.intercept(response, body, dst, err)
+	if c.logger != nil {
+		c.debug("微信支付应答", "status", response.StatusCode, "request_id", requestId, "headers", c.redactHeader(response.Header),
+			"body", c.redactBody(body), "error", c.redactError(err))
+	}
+	return
+}
+
+// parseResponseBody 验证应答签名并将body反序列化到dst中
+func (c *Config) parseResponseBody(response *http.Response, body []byte, dst interface{}) (err error) {
+	var header = response.Header
+	var code = response.StatusCode // 根据http code 判断请求是否成功
+
+	// 请求失败，返回包含http状态码、错误码、Request-ID等信息的*errors.APIError
+	// 同时将读取出来的body(如果有的话)反序列化到对应的结果中, body不是json(如网关返回的html)时忽略
 	if code != http.StatusOK && code != http.StatusNoContent {
-		if err = unmarshalJSON(body, dst); err != nil {
-			return
-		}
-		if data, ok := dst.(model.WError); ok && data != nil {
-			if err = data.Error(); err != nil {
-				return
-			}
-		}
-		err = errors.New(code)
+		_ = unmarshalJSON(body, dst)
+		err = errors.NewAPIError(code, header.Get("Request-ID"), body)
 		return
 	}
 
 	// API调用成功后的处理流程
 	// 1. 验证证书序列号是否正确
 	serialNo := header.Get("Wechatpay-Serial")
-	publicKey := c.GetRSAPublicKey(serialNo)
+	publicKey := c.lookupPublicKey(serialNo)
 	if publicKey == nil {
-		err = fmt.Errorf("解析微信应答失败: Wechatpay-Serial[%s]不存在", serialNo)
+		err = fmt.Errorf("解析微信应答失败: Wechatpay-Serial[%s]不存在: %w", serialNo, errors.ErrVerifySignFail)
 		return
 	}
-	// 2. 获取微信的签名结果
-	wechatSign := header.Get("Wechatpay-Signature") // 微信签名
-	// 3. 获取签名参数
-	timestamp := header.Get("Wechatpay-Timestamp") // 时间戳
-	nonceStr := header.Get("Wechatpay-Nonce")      // 随机字符串
-	// 4. 构造原始的签名数据
-	plainTxt := fmt.Sprintf("%v\n%v\n%v\n", timestamp, nonceStr, string(body))
-	// 验证签名
-	_ = c.merchantCipher.SetRSAPublicKey(publicKey, secret.PKCSLevel8)
-	err = rsas.VerifySHA256WithRSA(c.merchantCipher, wechatSign, plainTxt)
-	if err != nil {
+	// 2. 验证签名
+	if err = c.VerifyWechatSignature(header, body, publicKey); err != nil {
+		return
+	}
+	// 3. 验证时间戳以及随机串, 防止重放
+	if err = c.checkTimestamp(header); err != nil {
 		return
 	}
-	err = unmarshalJSON(body, dst)
+	if err = c.checkNonce(header); err != nil {
+		return
+	}
+	if err = unmarshalJSON(body, dst); err != nil {
+		return
+	}
+	// 4. 解密敏感字段
+	if !c.keepCiphertext && NeedEncrypt(dst) {
+		err = c.DecryptResponse(dst)
+	}
 	return
 }
 
 // ParseWechatNotify 验证微信服务器的通知，预支付、退款等请求后，微信回调的Request同样需要签名验证
 // 微信（验证）签名方法详细介绍: https://pay.weixin.qq.com/wiki/doc/apiv3/wechatpay/wechatpay4_1.shtml
+// 只校验时间戳并记录随机串, 不记录通知ID, 如果需要过滤已处理的重复通知, 请使用notify.Handler或者DecodeWechatNotify
 func (c *Config) ParseWechatNotify(request *http.Request, dst interface{}) (notifyId string, err error) {
 	if request == nil {
 		err = errors.ErrNoHttpRequest
 		return
 	}
-	if c.apiKey == "" {
-		err = errors.ErrNoApiV3Key
+
+	body, err := ioutil.ReadAll(request.Body)
+	if err != nil {
 		return
 	}
+	_ = request.Body.Close()
 
-	var body []byte
-	var header = request.Header
-
-	body, err = ioutil.ReadAll(request.Body)
+	notifyResponse, plainData, err := c.decodeWechatNotify(request.Header, body, false)
+	if notifyResponse != nil {
+		notifyId = notifyResponse.Id
+	}
 	if err != nil {
 		return
 	}
-	_ = request.Body.Close()
+	// 解析失败时删除随机串记录, 以便同一通知可以再次被解析
+	if err = unmarshalJSON(plainData, dst); err != nil {
+		c.ForgetNotify(request.Header, notifyId)
+	}
+	return
+}
+
+// DecodeWechatNotify 验证微信通知的签名并解密通知资源数据
+// header、body分别为微信通知的请求头和请求body, 返回通知的公共信息以及解密后的资源数据明文
+// 与ParseWechatNotify不同, 成功后通知ID被标记为处理中, 处理成功后必须调用AckNotify, 处理失败时必须调用ForgetNotify,
+// 否则微信重发的通知在1分钟内会返回ErrNotifyProcessing; 已处理过的通知返回ErrDuplicateNotify, notify.Handler会自动完成
+func (c *Config) DecodeWechatNotify(header http.Header, body []byte) (notifyResponse *model.WechatNotifyResponse, plainData []byte, err error) {
+	return c.decodeWechatNotify(header, body, true)
+}
+
+// decodeWechatNotify 验证并解密通知, trackId为true时同时将通知ID标记为处理中
+func (c *Config) decodeWechatNotify(header http.Header, body []byte, trackId bool) (notifyResponse *model.WechatNotifyResponse, plainData []byte, err error) {
+	if len(c.observers) > 0 {
+		start := time.Now()
+		defer func() {
+			event := &Event{Operation: OperationNotify, MchId: c.mchId, Attempt: 1, Start: start, Duration: time.Since(start), Err: err}
+			if notifyResponse != nil {
+				event.Endpoint = notifyResponse.EventType
+				event.RequestId = notifyResponse.Id
+			}
+			c.notifyObservers(event)
+		}()
+	}
+	if c.logger != nil {
+		defer func() {
+			c.debug("微信支付通知", "headers", c.redactHeader(header), "body", c.redactBody(body),
+				"plain_data", c.redactBody(plainData), "error", c.redactError(err))
+		}()
+	}
+	if notifyResponse, plainData, err = c.VerifyWechatNotify(header, body); err != nil {
+		return
+	}
+	// 解密成功后再记录随机串(以及通知ID), 防止重放
+	if trackId {
+		err = c.CheckNotifyReplay(header, notifyResponse.Id)
+	} else {
+		err = c.CheckNotifyNonce(header)
+	}
+	return
+}
+
+// VerifyWechatNotify 验证微信通知的签名、时间戳并解密通知资源数据, 但是不记录随机串以及通知ID,
+// 用于需要在解密后进一步确认通知归属(如多商户)的场景, 确认后需要调用CheckNotifyNonce或者CheckNotifyReplay
+func (c *Config) VerifyWechatNotify(header http.Header, body []byte) (notifyResponse *model.WechatNotifyResponse, plainData []byte, err error) {
+	if c.apiKey == "" {
+		err = errors.ErrNoApiV3Key
+		return
+	}
 
 	// 1. 验证证书序列号是否正确
 	serialNo := header.Get("Wechatpay-Serial")
-	publicKey := c.GetRSAPublicKey(serialNo)
+	publicKey := c.lookupPublicKey(serialNo)
 	if publicKey == nil {
 		err = fmt.Errorf("解析微信通知失败: Wechatpay-Serial[%s]不存在", serialNo)
 		return
 	}
 
-	// 2. 获取微信的签名结果
-	wechatSign := header.Get("Wechatpay-Signature") // 微信签名
-	// 3. 获取签名参数
-	timestamp := header.Get("Wechatpay-Timestamp") // 时间戳
-	nonceStr := header.Get("Wechatpay-Nonce")      // 随机字符串
-	// 4. 构造原始的签名数据
-	plainTxt := fmt.Sprintf("%v\n%v\n%v\n", timestamp, nonceStr, string(body))
-
-	// 验证签名
-	_ = c.merchantCipher.SetRSAPublicKey(publicKey, secret.PKCSLevel8)
-	err = rsas.VerifySHA256WithRSA(c.merchantCipher, wechatSign, plainTxt)
-	if err != nil {
+	// 2. 验证签名以及时间戳
+	if err = c.VerifyWechatSignature(header, body, publicKey); err != nil {
+		return
+	}
+	if err = c.checkTimestamp(header); err != nil {
 		return
 	}
 
 	// 签名通过的话反序列化body到结构体中
-	notifyResponse := new(model.WechatNotifyResponse)
+	notifyResponse = new(model.WechatNotifyResponse)
 	if err = unmarshalJSON(body, &notifyResponse); err != nil {
 		return
 	}
 
-	notifyId = notifyResponse.Id
 	// 判断资源类型
 	if notifyResponse.ResourceType != "encrypt-resource" {
 		err = fmt.Errorf("解析微信通知失败, 错误的资源类型: %s", notifyResponse.ResourceType)
@@ -443,25 +767,7 @@ func (c *Config) ParseWechatNotify(request *http.Request, dst interface{}) (noti
 	cipherText := notifyResponse.Resource.CipherText
 	associateData := notifyResponse.Resource.AssociatedData
 	nonce := notifyResponse.Resource.Nonce
-	plainData, err := aess.DecryptAEADAES256GCM(c.merchantCipher, c.apiKey, cipherText, associateData, nonce)
-	if err != nil {
-		return
-	}
-	err = unmarshalJSON(plainData, dst)
-	return
-}
-
-// Download 下载URL对应的数据流
-func (c *Config) Download(url string) (data []byte, err error) {
-	if strs := strings.Split(url, c.domain); len(strs) > 1 {
-		url = strs[1]
-	}
-	response, err := c.RequestWithSign(http.MethodGet, url, nil)
-	if err != nil {
-		return
-	}
-	defer response.Body.Close()
-	data, err = ioutil.ReadAll(response.Body)
+	plainData, err = aess.DecryptAEADAES256GCM(c.merchantCipher, c.apiKey, cipherText, associateData, nonce)
 	return
 }
 
@@ -471,7 +777,7 @@ func (c *Config) UploadMedia(url string, contentType string, fileName string, fi
 		err = errors.ErrNoMchId
 		return
 	}
-	if c.serialNo == "" {
+	if c.GetSerialNo() == "" {
 		err = errors.ErrNoSerialNo
 		return
 	}
@@ -497,19 +803,24 @@ func (c *Config) UploadMedia(url string, contentType string, fileName string, fi
 		return
 	}
 
+	// 限流等待结束后再签名, 避免等待过久导致时间戳过期
+	method := http.MethodPost // 方法类型
+	if err = c.acquire(OperationUpload, method, url, 1); err != nil {
+		return
+	}
+
 	// 构造签名
-	method := http.MethodPost      // 方法类型
 	timestamp := time.Now().Unix() // 时间戳
 	nonceStr := pkg.String(32)     // 随机字符串
 	source := fmt.Sprintf("%s\n%s\n%d\n%s\n%s\n", method, url, timestamp, nonceStr, string(metaData))
-	signature, err := rsas.SignSHA256WithRSA(c.merchantCipher, source)
+	signature, err := c.Sign(source)
 	if err != nil {
 		return
 	}
 	// 签名头
-	signatureHead := fmt.Sprintf("mchid=\"%s\",nonce_str=\"%s\",signature=\"%s\",timestamp=\"%d\",serial_no=\"%s\"", c.mchId, nonceStr, signature, timestamp, c.serialNo)
+	signatureHead := fmt.Sprintf("mchid=\"%s\",nonce_str=\"%s\",signature=\"%s\",timestamp=\"%d\",serial_no=\"%s\"", c.mchId, nonceStr, signature, timestamp, c.GetSerialNo())
 	// 构造请求头，这里的body为文件二进制数据
-	request, err := http.NewRequest(method, c.domain+url, body)
+	request, err := http.NewRequestWithContext(c.Context(), method, c.domain+url, body)
 	if err != nil {
 		return
 	}
@@ -519,7 +830,14 @@ func (c *Config) UploadMedia(url string, contentType string, fileName string, fi
 	request.Header.Set("Accept", "*/*")
 	request.Header.Set("User-Agent", c.agent())
 	request.Header.Set("Accept-Language", "zh-CN")
-	return c.httpClient.Do(request)
+	if c.logger != nil {
+		c.debug("微信支付上传", "method", method, "url", c.redactText(url), "headers", c.redactHeader(request.Header),
+			"signing_string", c.redactSigningString(method, url, strconv.FormatInt(timestamp, 10), nonceStr, metaData), "file_size", len(fileData))
+	}
+	start := time.Now()
+	response, err = c.do(request)
+	c.observeRequest(OperationUpload, request, 1, start, response, err)
+	return
 }
 
 // VerifyHashValue 校验hash值
@@ -613,6 +931,16 @@ func unmarshalJSON(data []byte, dst interface{}) (err error) {
 	return
 }
 
+// hasHeader 判断以key、value形式传入的headers中是否包含key
+func hasHeader(headers []string, key string) bool {
+	for i := 0; i < len(headers)-1; i += 2 {
+		if http.CanonicalHeaderKey(headers[i]) == http.CanonicalHeaderKey(key) {
+			return true
+		}
+	}
+	return false
+}
+
 func marshalJSON(data interface{}) (bytes []byte, err error) {
 	if data == nil {
 		return
@@ -627,7 +955,7 @@ func marshalJSON(data interface{}) (bytes []byte, err error) {
 	case reflect.String:
 		bytes = []byte(dataValue.String())
 	case reflect.Slice:
-		if dataValue.Elem().Kind() != reflect.Uint8 {
+		if dataValue.Type().Elem().Kind() != reflect.Uint8 {
 			err = errors.ErrMarshalFailInvalidDataType
 			break
 		}
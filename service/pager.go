@@ -0,0 +1,130 @@
+package service
+
+import (
+	"fmt"
+	"reflect"
+
+	"github.com/pyihe/wechat-sdk/v3/pkg/errors"
+)
+
+// Pager 列表接口的分页参数, 不同接口的offset含义与分页大小上限不同, 由各接口的遍历函数设置
+type Pager struct {
+	Offset      uint32 // 起始offset
+	PageSize    uint32 // 分页大小, 为0或者超过MaxPageSize时使用MaxPageSize
+	MaxPageSize uint32 // 接口允许的最大分页大小
+	ByPage      bool   // 接口的offset为页码(从0开始), 否则为记录的起始位置
+}
+
+// PageResult 一页的查询结果
+type PageResult struct {
+	RequestId  string // 唯一请求ID
+	TotalCount int64  // 接口返回的总条数, 接口未返回时为0
+	Count      int    // 本页的记录条数
+}
+
+// PageFunc 查询offset、limit对应的一页, 并将本页的记录交给调用方处理, 调用方要求停止遍历时more返回false
+type PageFunc func(offset, limit uint32) (page *PageResult, more bool, err error)
+
+// Paginate 从pager.Offset开始逐页调用fetch, 直到读取的条数达到接口返回的总条数(未返回总条数时为某一页不满)、
+// 某一页为空或者fetch要求停止, 每页查询前都会检查Config绑定的上下文是否已经取消, requestId为最后一次查询的唯一请求ID
+func (c *Config) Paginate(pager Pager, fetch PageFunc) (requestId string, err error) {
+	limit := pager.PageSize
+	if limit == 0 || (pager.MaxPageSize > 0 && limit > pager.MaxPageSize) {
+		limit = pager.MaxPageSize
+	}
+	offset := pager.Offset
+	// 已经读取(包括起始offset之前)的记录条数, 用于和总条数比较
+	position := int64(offset)
+	if pager.ByPage {
+		position = int64(offset) * int64(limit)
+	}
+	for {
+		if err = c.Context().Err(); err != nil {
+			return
+		}
+		var page *PageResult
+		var more bool
+		if page, more, err = fetch(offset, limit); page != nil {
+			requestId = page.RequestId
+		}
+		if err != nil || !more || page.Count == 0 {
+			return
+		}
+		position += int64(page.Count)
+		switch {
+		case page.TotalCount > 0 && position >= page.TotalCount:
+			return
+		case page.TotalCount <= 0 && uint32(page.Count) < limit:
+			return
+		}
+		if pager.ByPage {
+			offset++
+		} else {
+			offset += uint32(page.Count)
+		}
+	}
+}
+
+// QueryFunc 查询offset、limit对应的一页, 返回接口的应答(如*complaints.QueryComplaintListResponse)
+type QueryFunc func(offset, limit uint32) (response interface{}, err error)
+
+// RangePages 使用Paginate逐页调用query, 并将应答列表中的每一条记录交给fn, fn返回false时停止遍历,
+// fn必须为func(T) bool, 应答中元素类型为T的切片字段(如Data)即为列表, 总条数与唯一请求ID分别取自应答的TotalCount、RequestId字段
+func RangePages(config *Config, pager Pager, query QueryFunc, fn interface{}) (requestId string, err error) {
+	if config == nil {
+		err = errors.ErrNoConfig
+		return
+	}
+	fnValue := reflect.ValueOf(fn)
+	if !fnValue.IsValid() || fnValue.Kind() != reflect.Func || fnValue.IsNil() {
+		err = errors.ErrParam
+		return
+	}
+	fnType := fnValue.Type()
+	if fnType.NumIn() != 1 || fnType.NumOut() != 1 || fnType.Out(0).Kind() != reflect.Bool {
+		err = errors.ErrParam
+		return
+	}
+	return config.Paginate(pager, func(offset, limit uint32) (page *PageResult, more bool, err error) {
+		response, err := query(offset, limit)
+		value := reflect.Indirect(reflect.ValueOf(response))
+		if !value.IsValid() || value.Kind() != reflect.Struct {
+			return
+		}
+		list, e := pageList(value, fnType.In(0))
+		if e != nil {
+			return nil, false, e
+		}
+		page = &PageResult{Count: list.Len()}
+		if field := value.FieldByName("RequestId"); field.Kind() == reflect.String {
+			page.RequestId = field.String()
+		}
+		switch field := value.FieldByName("TotalCount"); field.Kind() {
+		case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+			page.TotalCount = field.Int()
+		case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+			page.TotalCount = int64(field.Uint())
+		}
+		if err != nil {
+			return
+		}
+		for i := 0; i < list.Len(); i++ {
+			if !fnValue.Call([]reflect.Value{list.Index(i)})[0].Bool() {
+				return page, false, nil
+			}
+		}
+		return page, true, nil
+	})
+}
+
+// pageList 返回应答中元素类型为elemType的切片字段
+func pageList(value reflect.Value, elemType reflect.Type) (list reflect.Value, err error) {
+	for i := 0; i < value.NumField(); i++ {
+		field := value.Field(i)
+		if field.Kind() == reflect.Slice && field.Type().Elem() == elemType {
+			return field, nil
+		}
+	}
+	err = fmt.Errorf("遍历分页失败: %s中没有元素类型为%s的列表", value.Type(), elemType)
+	return
+}
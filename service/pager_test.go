@@ -0,0 +1,138 @@
+package service
+
+import (
+	"context"
+	"fmt"
+	"testing"
+)
+
+// fakeList 模拟一个共有total条记录的列表接口, 记录i的值为i
+func fakeList(total int, byPage bool, calls *[]string) PageFunc {
+	return func(offset, limit uint32) (*PageResult, bool, error) {
+		*calls = append(*calls, fmt.Sprintf("%d/%d", offset, limit))
+		start := int(offset)
+		if byPage {
+			start = int(offset) * int(limit)
+		}
+		count := total - start
+		if count > int(limit) {
+			count = int(limit)
+		}
+		if count < 0 {
+			count = 0
+		}
+		return &PageResult{RequestId: fmt.Sprintf("req-%d", len(*calls)), TotalCount: int64(total), Count: count}, true, nil
+	}
+}
+
+func TestPaginate(t *testing.T) {
+	config := NewConfig()
+	cases := []struct {
+		name   string
+		pager  Pager
+		total  int
+		expect []string
+	}{
+		{name: "page", pager: Pager{MaxPageSize: 10, ByPage: true}, total: 25, expect: []string{"0/10", "1/10", "2/10"}},
+		{name: "offset", pager: Pager{PageSize: 100, MaxPageSize: 10}, total: 20, expect: []string{"0/10", "10/10"}},
+		{name: "start offset", pager: Pager{Offset: 5, PageSize: 5, MaxPageSize: 10}, total: 12, expect: []string{"5/5", "10/5"}},
+		{name: "empty", pager: Pager{MaxPageSize: 10}, total: 0, expect: []string{"0/10"}},
+	}
+	for _, c := range cases {
+		var calls []string
+		requestId, err := config.Paginate(c.pager, fakeList(c.total, c.pager.ByPage, &calls))
+		if err != nil {
+			t.Fatalf("%s: %v", c.name, err)
+		}
+		if fmt.Sprint(calls) != fmt.Sprint(c.expect) || requestId != fmt.Sprintf("req-%d", len(c.expect)) {
+			t.Fatalf("%s: unexpected calls: %v, request id: %s", c.name, calls, requestId)
+		}
+	}
+
+	// 未返回总条数时, 遇到不满的一页停止
+	var calls []string
+	list := fakeList(15, false, &calls)
+	_, err := config.Paginate(Pager{MaxPageSize: 10}, func(offset, limit uint32) (*PageResult, bool, error) {
+		page, more, err := list(offset, limit)
+		page.TotalCount = 0
+		return page, more, err
+	})
+	if err != nil || len(calls) != 2 {
+		t.Fatalf("unexpected calls: %v, %v", calls, err)
+	}
+
+	// 提前停止
+	calls = nil
+	list = fakeList(100, false, &calls)
+	requestId, err := config.Paginate(Pager{MaxPageSize: 10}, func(offset, limit uint32) (*PageResult, bool, error) {
+		page, _, err := list(offset, limit)
+		return page, offset < 20, err
+	})
+	if err != nil || len(calls) != 3 || requestId != "req-3" {
+		t.Fatalf("unexpected calls: %v, %s, %v", calls, requestId, err)
+	}
+
+	// 上下文取消后不再查询
+	ctx, cancel := context.WithCancel(context.Background())
+	calls = nil
+	list = fakeList(100, false, &calls)
+	_, err = config.WithContext(ctx).Paginate(Pager{MaxPageSize: 10}, func(offset, limit uint32) (*PageResult, bool, error) {
+		if offset == 20 {
+			cancel()
+		}
+		return list(offset, limit)
+	})
+	if err != context.Canceled || len(calls) != 3 {
+		t.Fatalf("expected context.Canceled after 3 calls, got: %v, %v", calls, err)
+	}
+}
+
+type fakeListResponse struct {
+	RequestId  string
+	TotalCount uint32
+	Names      []string
+	Data       []*PageResult
+}
+
+func TestRangePages(t *testing.T) {
+	config := NewConfig()
+	var calls []string
+	list := fakeList(25, true, &calls)
+	query := func(offset, limit uint32) (interface{}, error) {
+		page, _, err := list(offset, limit)
+		response := &fakeListResponse{RequestId: page.RequestId, TotalCount: uint32(page.TotalCount)}
+		for i := 0; i < page.Count; i++ {
+			response.Data = append(response.Data, &PageResult{Count: int(offset*limit) + i})
+		}
+		return response, err
+	}
+
+	// 按照元素类型找到列表字段, 并读取总条数以及唯一请求ID
+	var values []int
+	requestId, err := RangePages(config, Pager{MaxPageSize: 10, ByPage: true}, query, func(record *PageResult) bool {
+		values = append(values, record.Count)
+		return true
+	})
+	if err != nil || len(values) != 25 || values[24] != 24 || requestId != "req-3" {
+		t.Fatalf("unexpected values: %v, %s, %v", values, requestId, err)
+	}
+
+	// 提前停止
+	values, calls = nil, nil
+	if _, err = RangePages(config, Pager{MaxPageSize: 10, ByPage: true}, query, func(record *PageResult) bool {
+		values = append(values, record.Count)
+		return len(values) < 12
+	}); err != nil || len(values) != 12 || len(calls) != 2 {
+		t.Fatalf("unexpected values: %v, %v", values, err)
+	}
+
+	// fn的类型不正确或者应答中没有对应的列表
+	for _, fn := range []interface{}{nil, func(*PageResult) {}, (func(*PageResult) bool)(nil), func(int) bool { return true }} {
+		if _, err = RangePages(config, Pager{MaxPageSize: 10}, query, fn); err == nil {
+			t.Fatalf("%T: expected error", fn)
+		}
+	}
+	if _, err = RangePages(nil, Pager{MaxPageSize: 10}, query, func(string) bool { return true }); err == nil {
+		t.Fatal("expected ErrNoConfig")
+	}
+}
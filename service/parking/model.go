@@ -4,8 +4,40 @@ import (
 	"time"
 
 	"github.com/pyihe/wechat-sdk/v3/model"
+	"github.com/pyihe/wechat-sdk/v3/pkg/validate"
 )
 
+// CreateParkingRequest 创建停车入场请求参数
+type CreateParkingRequest struct {
+	SubMchId     string `json:"sub_mchid,omitempty"` // 子商户号, 服务商平台必填
+	OutParkingNo string `json:"out_parking_no"`      // 商户入场ID
+	PlateNumber  string `json:"plate_number"`        // 车牌号
+	PlateColor   string `json:"plate_color"`         // 车牌颜色: BLUE、GREEN、YELLOW、BLACK、WHITE、LIMEGREEN
+	NotifyUrl    string `json:"notify_url"`          // 回调通知url
+	StartTime    string `json:"start_time"`          // 入场时间, RFC3339格式
+	ParkingName  string `json:"parking_name"`        // 停车场名称
+	FreeDuration int32  `json:"free_duration"`       // 免费时长, 单位为秒
+}
+
+func (r *CreateParkingRequest) Validate() error {
+	return validate.First(
+		validate.MaxLength("sub_mchid", r.SubMchId, 32),
+		validate.Required("out_parking_no", r.OutParkingNo),
+		validate.MaxLength("out_parking_no", r.OutParkingNo, 32),
+		validate.Required("plate_number", r.PlateNumber),
+		validate.MaxLength("plate_number", r.PlateNumber, 32),
+		validate.Required("plate_color", r.PlateColor),
+		validate.OneOf("plate_color", r.PlateColor, "BLUE", "GREEN", "YELLOW", "BLACK", "WHITE", "LIMEGREEN"),
+		validate.Required("notify_url", r.NotifyUrl),
+		validate.NotifyUrl("notify_url", r.NotifyUrl),
+		validate.Required("start_time", r.StartTime),
+		validate.Time("start_time", r.StartTime),
+		validate.Required("parking_name", r.ParkingName),
+		validate.MaxChars("parking_name", r.ParkingName, 128),
+		validate.Min("free_duration", int64(r.FreeDuration), 0),
+	)
+}
+
 // FindRequest 查询车牌服务开通信息请求参数
 type FindRequest struct {
 	AppId       string `json:"appid,omitempty"`        // 应用ID
@@ -44,12 +44,13 @@ func FindParkingService(config *service.Config, request *FindRequest) (findRespo
 // CreateParking 创建停车入场
 // 商户平台文档: https://pay.weixin.qq.com/wiki/doc/apiv3/apis/chapter8_8_2.shtml
 // 服务商平台文档: https://pay.weixin.qq.com/wiki/doc/apiv3_partner/apis/chapter8_8_2.shtml
+// request: *CreateParkingRequest(签名前会校验参数)或者map等可序列化的参数
 func CreateParking(config *service.Config, request interface{}) (createResponse *CreateParkingResponse, err error) {
 	if config == nil {
 		err = errors.ErrNoConfig
 		return
 	}
-	if reflect.ValueOf(request).IsZero() {
+	if request == nil || reflect.ValueOf(request).IsZero() {
 		err = errors.ErrNoSDKRequest
 		return
 	}
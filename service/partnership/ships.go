@@ -65,3 +65,18 @@ func QueryPartnerShip(config *service.Config, request *QueryRequest) (queryRespo
 	queryResponse.RequestId, err = config.ParseWechatResponse(response, queryResponse)
 	return
 }
+
+// RangePartnerShip 遍历合作关系列表的所有分页, request.Offset为起始页码, request.Limit为分页大小(最大为50),
+// fn返回false时停止遍历, requestId为最后一次查询的唯一请求ID
+func RangePartnerShip(config *service.Config, request *QueryRequest, fn func(ship *Ship) bool) (requestId string, err error) {
+	if request == nil {
+		err = errors.ErrNoSDKRequest
+		return
+	}
+	pageRequest := *request
+	pager := service.Pager{Offset: uint32(request.Offset), PageSize: uint32(request.Limit), MaxPageSize: 50, ByPage: true}
+	return service.RangePages(config, pager, func(offset, limit uint32) (interface{}, error) {
+		pageRequest.Offset, pageRequest.Limit = uint64(offset), uint64(limit)
+		return QueryPartnerShip(config, &pageRequest)
+	}, fn)
+}
@@ -69,10 +69,22 @@ func QueryActivityMerchant(config *service.Config, activityId string, offset, li
 		return
 	}
 	queryResponse = new(QueryActivityMerchantResponse)
-	queryResponse.RequestId, err = config.ParseWechatResponse(response, response)
+	queryResponse.RequestId, err = config.ParseWechatResponse(response, queryResponse)
 	return
 }
 
+// RangeActivityMerchant 遍历活动发券商户号的所有分页(每页最多50条),
+// fn返回false时停止遍历, requestId为最后一次查询的唯一请求ID
+func RangeActivityMerchant(config *service.Config, activityId string, fn func(merchant *ActivityMerchant) bool) (requestId string, err error) {
+	if activityId == "" {
+		err = errors.ErrParam
+		return
+	}
+	return service.RangePages(config, service.Pager{MaxPageSize: 50, ByPage: true}, func(offset, limit uint32) (interface{}, error) {
+		return QueryActivityMerchant(config, activityId, offset, limit)
+	}, fn)
+}
+
 // QueryActivityGoods 查询活动指定商品列表
 // 商户平台文档: https://pay.weixin.qq.com/wiki/doc/apiv3/apis/chapter9_7_6.shtml
 // 服务商平台文档: https://pay.weixin.qq.com/wiki/doc/apiv3_partner/apis/chapter9_7_6.shtml
@@ -98,6 +110,18 @@ func QueryActivityGoods(config *service.Config, activityId string, offset, limit
 	return
 }
 
+// RangeActivityGoods 遍历活动指定商品列表的所有分页(每页最多50条),
+// fn返回false时停止遍历, requestId为最后一次查询的唯一请求ID
+func RangeActivityGoods(config *service.Config, activityId string, fn func(goods *ActivityGoods) bool) (requestId string, err error) {
+	if activityId == "" {
+		err = errors.ErrParam
+		return
+	}
+	return service.RangePages(config, service.Pager{MaxPageSize: 50, ByPage: true}, func(offset, limit uint32) (interface{}, error) {
+		return QueryActivityGoods(config, activityId, offset, limit)
+	}, fn)
+}
+
 // TerminateActivity 终止活动
 // 商户平台文档: https://pay.weixin.qq.com/wiki/doc/apiv3/apis/chapter9_7_7.shtml
 // 服务商平台文档: https://pay.weixin.qq.com/wiki/doc/apiv3_partner/apis/chapter9_7_7.shtml
@@ -12,6 +12,7 @@ import (
 // JSAPI JSAPI合单支付
 // 商户平台JSAPI合单支付API文档: https://pay.weixin.qq.com/wiki/doc/apiv3/apis/chapter5_1_3.shtml
 // 服务商平台JSAPI合单支付API文档: https://pay.weixin.qq.com/wiki/doc/apiv3_partner/apis/chapter5_1_3.shtml
+// request: *JSAPIRequest(签名前会校验参数)或者map等可序列化的参数
 func JSAPI(config *service.Config, request interface{}) (jsapiResponse *model.JSAPIResponse, err error) {
 	if config == nil {
 		err = errors.ErrNoConfig
@@ -35,6 +36,7 @@ func JSAPI(config *service.Config, request interface{}) (jsapiResponse *model.JS
 // H5 H5合单支付
 // 商户平台H5合单支付API文档: https://pay.weixin.qq.com/wiki/doc/apiv3/apis/chapter5_1_2.shtml
 // 服务商平台H5合单支付API文档: https://pay.weixin.qq.com/wiki/doc/apiv3_partner/apis/chapter5_1_2.shtml
+// request: *H5Request(签名前会校验参数)或者map等可序列化的参数
 func H5(config *service.Config, request interface{}) (h5Response *model.H5Response, err error) {
 	if config == nil {
 		err = errors.ErrNoConfig
@@ -57,6 +59,7 @@ func H5(config *service.Config, request interface{}) (h5Response *model.H5Respon
 // APP APP合单支付
 // 商户平台APP合单支付API文档: https://pay.weixin.qq.com/wiki/doc/apiv3/apis/chapter5_1_1.shtml
 // 服务商平台APP合单支付API文档: https://pay.weixin.qq.com/wiki/doc/apiv3_partner/apis/chapter5_1_1.shtml
+// request: *PrepayRequest(签名前会校验参数)或者map等可序列化的参数
 func APP(config *service.Config, request interface{}) (appResponse *model.AppResponse, err error) {
 	if config == nil {
 		err = errors.ErrNoConfig
@@ -79,6 +82,7 @@ func APP(config *service.Config, request interface{}) (appResponse *model.AppRes
 // Native native合单支付
 // 商户平台Native合单支付API文档: https://pay.weixin.qq.com/wiki/doc/apiv3/apis/chapter5_1_5.shtml
 // 服务商平台合单支付API文档: https://pay.weixin.qq.com/wiki/doc/apiv3_partner/apis/chapter5_1_5.shtml
+// request: *PrepayRequest(签名前会校验参数)或者map等可序列化的参数
 func Native(config *service.Config, request interface{}) (nativeResponse *model.NativeResponse, err error) {
 	if config == nil {
 		err = errors.ErrNoConfig
@@ -150,3 +154,33 @@ func ParsePrepayNotify(config *service.Config, request *http.Request) (order *Pr
 	order.Id, err = config.ParseWechatNotify(request, order)
 	return
 }
+
+// InvokeJSAPI 使用合单JSAPI、小程序下单返回的prepay_id生成前端调起支付的参数
+// combineAppId: 下单时的合单商户appid
+// API文档: https://pay.weixin.qq.com/wiki/doc/apiv3/apis/chapter5_1_8.shtml
+func InvokeJSAPI(config *service.Config, combineAppId string, jsapiResponse *model.JSAPIResponse) (params *model.JSAPIInvokeParams, err error) {
+	if config == nil {
+		err = errors.ErrNoConfig
+		return
+	}
+	if jsapiResponse == nil {
+		err = errors.ErrNoSDKRequest
+		return
+	}
+	return jsapiResponse.InvokeParams(config, combineAppId)
+}
+
+// InvokeAPP 使用合单APP下单返回的prepay_id生成APP调起支付的参数
+// combineAppId、combineMchId: 下单时的合单商户appid以及合单商户号
+// API文档: https://pay.weixin.qq.com/wiki/doc/apiv3/apis/chapter5_1_6.shtml
+func InvokeAPP(config *service.Config, combineAppId, combineMchId string, appResponse *model.AppResponse) (params *model.AppInvokeParams, err error) {
+	if config == nil {
+		err = errors.ErrNoConfig
+		return
+	}
+	if appResponse == nil {
+		err = errors.ErrNoSDKRequest
+		return
+	}
+	return appResponse.InvokeParams(config, combineAppId, combineMchId)
+}
@@ -1,11 +1,128 @@
 package combine
 
 import (
+	"fmt"
 	"time"
 
 	"github.com/pyihe/wechat-sdk/v3/model"
+	"github.com/pyihe/wechat-sdk/v3/pkg/validate"
 )
 
+// PrepayRequest 合单APP、Native预下单请求参数, JSAPI、H5预下单请分别使用JSAPIRequest、H5Request
+type PrepayRequest struct {
+	CombineAppId      string                 `json:"combine_appid"`         // 合单商户appid
+	CombineMchId      string                 `json:"combine_mchid"`         // 合单商户号
+	CombineOutTradeNo string                 `json:"combine_out_trade_no"`  // 合单商户订单号
+	SceneInfo         *model.PrepaySceneInfo `json:"scene_info,omitempty"`  // 场景信息
+	SubOrders         []*SubOrder            `json:"sub_orders"`            // 子单信息, 最多50单
+	TimeStart         string                 `json:"time_start,omitempty"`  // 交易起始时间, RFC3339格式
+	TimeExpire        string                 `json:"time_expire,omitempty"` // 交易结束时间, RFC3339格式
+	NotifyUrl         string                 `json:"notify_url"`            // 通知地址
+}
+
+func (r *PrepayRequest) Validate() (err error) {
+	err = validate.First(
+		validate.Required("combine_appid", r.CombineAppId),
+		validate.MaxLength("combine_appid", r.CombineAppId, 32),
+		validate.Required("combine_mchid", r.CombineMchId),
+		validate.MaxLength("combine_mchid", r.CombineMchId, 32),
+		validate.Required("combine_out_trade_no", r.CombineOutTradeNo),
+		validate.MaxLength("combine_out_trade_no", r.CombineOutTradeNo, 32),
+		validate.Nested("scene_info", r.SceneInfo),
+		validate.Size("sub_orders", len(r.SubOrders), 1, 50),
+		validate.Time("time_start", r.TimeStart),
+		validate.Time("time_expire", r.TimeExpire),
+		validate.Before("time_expire", r.TimeStart, r.TimeExpire),
+		validate.Required("notify_url", r.NotifyUrl),
+		validate.NotifyUrl("notify_url", r.NotifyUrl),
+	)
+	if err != nil {
+		return
+	}
+	for i, subOrder := range r.SubOrders {
+		field := fmt.Sprintf("sub_orders[%d]", i)
+		if err = validate.Required(field, subOrder); err != nil {
+			return
+		}
+		if err = validate.Nested(field, subOrder); err != nil {
+			return
+		}
+	}
+	return
+}
+
+// SubOrder 合单预下单子单信息
+type SubOrder struct {
+	MchId       string            `json:"mchid"`                 // 子单商户号
+	SubMchId    string            `json:"sub_mchid,omitempty"`   // 二级商户号, 服务商平台必填
+	Attach      string            `json:"attach"`                // 附加数据
+	Amount      *SubOrderAmount   `json:"amount"`                // 订单金额
+	OutTradeNo  string            `json:"out_trade_no"`          // 子单商户订单号
+	Description string            `json:"description"`           // 商品描述
+	SettleInfo  *model.SettleInfo `json:"settle_info,omitempty"` // 结算信息
+}
+
+func (o *SubOrder) Validate() error {
+	return validate.First(
+		validate.Required("mchid", o.MchId),
+		validate.MaxLength("mchid", o.MchId, 32),
+		validate.MaxLength("sub_mchid", o.SubMchId, 32),
+		validate.Required("attach", o.Attach),
+		validate.MaxLength("attach", o.Attach, 128),
+		validate.Required("amount", o.Amount),
+		validate.Nested("amount", o.Amount),
+		validate.Required("out_trade_no", o.OutTradeNo),
+		validate.Length("out_trade_no", o.OutTradeNo, 6, 32),
+		validate.Required("description", o.Description),
+		validate.MaxLength("description", o.Description, 127),
+	)
+}
+
+// SubOrderAmount 子单金额
+type SubOrderAmount struct {
+	TotalAmount int64  `json:"total_amount"` // 标价金额, 单位为分
+	Currency    string `json:"currency"`     // 标价币种, 境内商户号仅支持人民币: CNY
+}
+
+func (a *SubOrderAmount) Validate() error {
+	return validate.First(
+		validate.Min("total_amount", a.TotalAmount, 1),
+		validate.Required("currency", a.Currency),
+		validate.OneOf("currency", a.Currency, "CNY"),
+	)
+}
+
+// JSAPIRequest 合单JSAPI预下单请求参数
+type JSAPIRequest struct {
+	PrepayRequest
+	CombinePayerInfo *model.MerchantPayer `json:"combine_payer_info"` // 支付者信息
+}
+
+func (r *JSAPIRequest) Validate() (err error) {
+	if err = r.PrepayRequest.Validate(); err != nil {
+		return
+	}
+	if err = validate.Required("combine_payer_info", r.CombinePayerInfo); err != nil {
+		return
+	}
+	return validate.Required("combine_payer_info.openid", r.CombinePayerInfo.OpenId)
+}
+
+// H5Request 合单H5预下单请求参数, scene_info.h5_info必填
+type H5Request struct {
+	PrepayRequest
+}
+
+func (r *H5Request) Validate() (err error) {
+	if err = r.PrepayRequest.Validate(); err != nil {
+		return
+	}
+	if err = validate.Required("scene_info", r.SceneInfo); err != nil {
+		return
+	}
+	return validate.Required("scene_info.h5_info", r.SceneInfo.H5Info)
+}
+
 // PrepayOrder 合单支付订单
 type PrepayOrder struct {
 	model.WechatError
@@ -4,10 +4,81 @@ import (
 	"time"
 
 	"github.com/pyihe/wechat-sdk/v3/model"
+	"github.com/pyihe/wechat-sdk/v3/pkg/validate"
 )
 
 /****************************************************《基础支付》********************************************************/
 
+// PrepayRequest APP、Native预下单请求参数, JSAPI、H5预下单请分别使用JSAPIRequest、H5Request
+type PrepayRequest struct {
+	AppId         string                 `json:"appid"`                    // 应用ID
+	MchId         string                 `json:"mchid"`                    // 直连商户号
+	Description   string                 `json:"description"`              // 商品描述
+	OutTradeNo    string                 `json:"out_trade_no"`             // 商户订单号
+	TimeExpire    string                 `json:"time_expire,omitempty"`    // 交易结束时间, RFC3339格式, 如: 2018-06-08T10:34:56+08:00
+	Attach        string                 `json:"attach,omitempty"`         // 附加数据
+	NotifyUrl     string                 `json:"notify_url"`               // 通知地址
+	GoodsTag      string                 `json:"goods_tag,omitempty"`      // 订单优惠标记
+	SupportFapiao bool                   `json:"support_fapiao,omitempty"` // 电子发票入口开放标识
+	Amount        *model.PrepayAmount    `json:"amount"`                   // 订单金额
+	Detail        *model.PrepayDetail    `json:"detail,omitempty"`         // 优惠功能
+	SceneInfo     *model.PrepaySceneInfo `json:"scene_info,omitempty"`     // 场景信息
+	SettleInfo    *model.SettleInfo      `json:"settle_info,omitempty"`    // 结算信息
+}
+
+func (r *PrepayRequest) Validate() error {
+	return validate.First(
+		validate.Required("appid", r.AppId),
+		validate.MaxLength("appid", r.AppId, 32),
+		validate.Required("mchid", r.MchId),
+		validate.MaxLength("mchid", r.MchId, 32),
+		validate.Required("description", r.Description),
+		validate.MaxLength("description", r.Description, 127),
+		validate.Required("out_trade_no", r.OutTradeNo),
+		validate.Length("out_trade_no", r.OutTradeNo, 6, 32),
+		validate.Time("time_expire", r.TimeExpire),
+		validate.MaxLength("attach", r.Attach, 128),
+		validate.Required("notify_url", r.NotifyUrl),
+		validate.NotifyUrl("notify_url", r.NotifyUrl),
+		validate.MaxLength("goods_tag", r.GoodsTag, 32),
+		validate.Required("amount", r.Amount),
+		validate.Nested("amount", r.Amount),
+		validate.Nested("detail", r.Detail),
+		validate.Nested("scene_info", r.SceneInfo),
+	)
+}
+
+// JSAPIRequest JSAPI预下单请求参数
+type JSAPIRequest struct {
+	PrepayRequest
+	Payer *model.MerchantPayer `json:"payer"` // 支付者信息
+}
+
+func (r *JSAPIRequest) Validate() (err error) {
+	if err = r.PrepayRequest.Validate(); err != nil {
+		return
+	}
+	if err = validate.Required("payer", r.Payer); err != nil {
+		return
+	}
+	return validate.Required("payer.openid", r.Payer.OpenId)
+}
+
+// H5Request H5预下单请求参数, scene_info.h5_info必填
+type H5Request struct {
+	PrepayRequest
+}
+
+func (r *H5Request) Validate() (err error) {
+	if err = r.PrepayRequest.Validate(); err != nil {
+		return
+	}
+	if err = validate.Required("scene_info", r.SceneInfo); err != nil {
+		return
+	}
+	return validate.Required("scene_info.h5_info", r.SceneInfo.H5Info)
+}
+
 // QueryOrderRequest 订单查询request
 type QueryOrderRequest struct {
 	TransactionId string `json:"transaction_id,omitempty"` // 微信订单号
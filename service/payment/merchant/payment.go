@@ -12,6 +12,7 @@ import (
 
 // JSAPI JSAPI预下单
 // API文档: https://pay.weixin.qq.com/wiki/doc/apiv3/apis/chapter3_1_1.shtml
+// request: *JSAPIRequest(签名前会校验参数)或者map等可序列化的参数
 func JSAPI(config *service.Config, request interface{}) (jsapiResponse *model.JSAPIResponse, err error) {
 	if config == nil {
 		err = errors.ErrNoConfig
@@ -33,6 +34,7 @@ func JSAPI(config *service.Config, request interface{}) (jsapiResponse *model.JS
 
 // APP app预下单
 // API文档: https://pay.weixin.qq.com/wiki/doc/apiv3/apis/chapter3_2_1.shtml
+// request: *PrepayRequest(签名前会校验参数)或者map等可序列化的参数
 func APP(config *service.Config, request interface{}) (appResponse *model.AppResponse, err error) {
 	if config == nil {
 		err = errors.ErrNoConfig
@@ -54,6 +56,7 @@ func APP(config *service.Config, request interface{}) (appResponse *model.AppRes
 
 // Native native支付预下单
 // API文档: https://pay.weixin.qq.com/wiki/doc/apiv3/apis/chapter3_4_1.shtml
+// request: *PrepayRequest(签名前会校验参数)或者map等可序列化的参数
 func Native(config *service.Config, request interface{}) (nativeResponse *model.NativeResponse, err error) {
 	if config == nil {
 		err = errors.ErrNoConfig
@@ -74,6 +77,7 @@ func Native(config *service.Config, request interface{}) (nativeResponse *model.
 
 // H5 h5支付预下单
 // API文档: https://pay.weixin.qq.com/wiki/doc/apiv3/apis/chapter3_3_1.shtml
+// request: *H5Request(签名前会校验参数)或者map等可序列化的参数
 func H5(config *service.Config, request interface{}) (h5Response *model.H5Response, err error) {
 	if config == nil {
 		err = errors.ErrNoConfig
@@ -155,3 +159,31 @@ func ParsePrepayNotify(config *service.Config, request *http.Request) (orderResp
 	orderResponse.Id, err = config.ParseWechatNotify(request, orderResponse)
 	return
 }
+
+// InvokeJSAPI 使用JSAPI、小程序预下单返回的prepay_id生成前端调起支付的参数, appid为Config中的AppId
+// API文档: https://pay.weixin.qq.com/wiki/doc/apiv3/apis/chapter3_1_4.shtml
+func InvokeJSAPI(config *service.Config, jsapiResponse *model.JSAPIResponse) (params *model.JSAPIInvokeParams, err error) {
+	if config == nil {
+		err = errors.ErrNoConfig
+		return
+	}
+	if jsapiResponse == nil {
+		err = errors.ErrNoSDKRequest
+		return
+	}
+	return jsapiResponse.InvokeParams(config, config.GetAppId())
+}
+
+// InvokeAPP 使用APP预下单返回的prepay_id生成APP调起支付的参数, appid、partnerid为Config中的AppId、MchId
+// API文档: https://pay.weixin.qq.com/wiki/doc/apiv3/apis/chapter3_2_4.shtml
+func InvokeAPP(config *service.Config, appResponse *model.AppResponse) (params *model.AppInvokeParams, err error) {
+	if config == nil {
+		err = errors.ErrNoConfig
+		return
+	}
+	if appResponse == nil {
+		err = errors.ErrNoSDKRequest
+		return
+	}
+	return appResponse.InvokeParams(config, config.GetAppId(), config.GetMchId())
+}
@@ -0,0 +1,153 @@
+package merchant
+
+import (
+	"crypto"
+	"crypto/rsa"
+	"crypto/sha256"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"testing"
+
+	"github.com/pyihe/wechat-sdk/v3/model"
+	sdkerrors "github.com/pyihe/wechat-sdk/v3/pkg/errors"
+	"github.com/pyihe/wechat-sdk/v3/pkg/validate"
+	"github.com/pyihe/wechat-sdk/v3/service/tests"
+)
+
+func TestPaymentFlow(t *testing.T) {
+	outTradeNo := "1217752501201407033233368018"
+	request := map[string]interface{}{
+		"appid":        tests.Server.AppId,
+		"mchid":        tests.Server.MchId,
+		"description":  "Image形象店-深圳腾大-QQ公仔",
+		"out_trade_no": outTradeNo,
+		"notify_url":   "https://www.weixin.qq.com/wxpay/pay.php",
+		"amount":       &model.Amount{Total: 100, Currency: "CNY"},
+		"payer":        &model.MerchantPayer{OpenId: "oUpF8uMuAJO_M2pxb1Q9zNjWeS6o"},
+	}
+	jsapiResponse, err := JSAPI(tests.Config, request)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if jsapiResponse.PrepayId == "" {
+		t.Fatalf("unexpected response: %+v", jsapiResponse)
+	}
+
+	// 缺少payer.openid时返回参数错误
+	delete(request, "payer")
+	if _, err = JSAPI(tests.Config, request); err == nil {
+		t.Fatal("expected PARAM_ERROR")
+	}
+
+	order, err := QueryOrder(tests.Config, &QueryOrderRequest{OutTradeNo: outTradeNo})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if order.TradeState != "NOTPAY" || order.Amount.Total != 100 {
+		t.Fatalf("unexpected order: %+v", order)
+	}
+
+	notify, err := tests.Server.PayOrder(outTradeNo)
+	if err != nil {
+		t.Fatal(err)
+	}
+	paid, err := ParsePrepayNotify(tests.Config, notify)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if paid.TradeState != "SUCCESS" || paid.TransactionId != order.TransactionId || paid.SuccessTime.IsZero() {
+		t.Fatalf("unexpected notify: %+v", paid)
+	}
+
+	// 已支付的订单无法关闭
+	if _, err = CloseOrder(tests.Config, outTradeNo); !errors.Is(err, sdkerrors.ErrOrderPaid) {
+		t.Fatalf("expected ORDERPAID, got: %v", err)
+	}
+	if _, err = QueryOrder(tests.Config, &QueryOrderRequest{TransactionId: "4200000000000000000000000000"}); !errors.Is(err, sdkerrors.ErrOrderNotExist) {
+		t.Fatalf("expected ORDER_NOT_EXIST, got: %v", err)
+	}
+}
+
+func TestPrepayRequestValidate(t *testing.T) {
+	request := &JSAPIRequest{
+		PrepayRequest: PrepayRequest{
+			AppId:       tests.Server.AppId,
+			MchId:       tests.Server.MchId,
+			Description: "Image形象店-深圳腾大-QQ公仔",
+			OutTradeNo:  "1217752501201407033233368021",
+			TimeExpire:  "2018-06-08T10:34:56+08:00",
+			NotifyUrl:   "https://www.weixin.qq.com/wxpay/pay.php",
+			Amount:      &model.PrepayAmount{Total: 100, Currency: "CNY"},
+		},
+		Payer: &model.MerchantPayer{OpenId: "oUpF8uMuAJO_M2pxb1Q9zNjWeS6o"},
+	}
+	jsapiResponse, err := JSAPI(tests.Config, request)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if jsapiResponse.PrepayId == "" {
+		t.Fatalf("unexpected response: %+v", jsapiResponse)
+	}
+
+	cases := map[string]func(r *JSAPIRequest){
+		"payer":           func(r *JSAPIRequest) { r.Payer = nil },
+		"amount.total":    func(r *JSAPIRequest) { r.Amount = &model.PrepayAmount{Total: 0} },
+		"amount.currency": func(r *JSAPIRequest) { r.Amount = &model.PrepayAmount{Total: 1, Currency: "USD"} },
+		"out_trade_no":    func(r *JSAPIRequest) { r.OutTradeNo = "12345" },
+		"time_expire":     func(r *JSAPIRequest) { r.TimeExpire = "2018-06-08 10:34:56" },
+		"notify_url":      func(r *JSAPIRequest) { r.NotifyUrl = "http://www.weixin.qq.com/wxpay/pay.php" },
+		"scene_info.h5_info.type": func(r *JSAPIRequest) {
+			r.SceneInfo = &model.PrepaySceneInfo{PayerClientIp: "127.0.0.1", H5Info: &model.H5Info{Type: "PC"}}
+		},
+	}
+	for field, modify := range cases {
+		invalid := *request
+		modify(&invalid)
+		_, err = JSAPI(tests.Config, &invalid)
+		var validateErr *validate.Error
+		if !errors.As(err, &validateErr) || validateErr.Field != field || !errors.Is(err, sdkerrors.ErrInvalidRequest) {
+			t.Fatalf("%s: unexpected error: %v", field, err)
+		}
+	}
+
+	// H5预下单必须设置scene_info.h5_info
+	if _, err = H5(tests.Config, &H5Request{PrepayRequest: request.PrepayRequest}); !errors.Is(err, sdkerrors.ErrInvalidRequest) {
+		t.Fatalf("unexpected error: %v", err)
+	}
+}
+
+func TestInvokeParams(t *testing.T) {
+	verify := func(message, signature string) {
+		sign, err := base64.StdEncoding.DecodeString(signature)
+		if err != nil {
+			t.Fatal(err)
+		}
+		hashed := sha256.Sum256([]byte(message))
+		if err = rsa.VerifyPKCS1v15(&tests.Server.MerchantKey.PublicKey, crypto.SHA256, hashed[:], sign); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	jsapiParams, err := InvokeJSAPI(tests.Config, &model.JSAPIResponse{PrepayId: "wx201410272009395522657a690389285100"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if jsapiParams.AppId != tests.Server.AppId || jsapiParams.Package != "prepay_id=wx201410272009395522657a690389285100" || jsapiParams.SignType != "RSA" {
+		t.Fatalf("unexpected params: %+v", jsapiParams)
+	}
+	verify(fmt.Sprintf("%s\n%s\n%s\n%s\n", jsapiParams.AppId, jsapiParams.TimeStamp, jsapiParams.NonceStr, jsapiParams.Package), jsapiParams.PaySign)
+
+	appParams, err := InvokeAPP(tests.Config, &model.AppResponse{PrepayId: "WX1217752501201407033233368018"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if appParams.PartnerId != tests.Server.MchId || appParams.Package != "Sign=WXPay" {
+		t.Fatalf("unexpected params: %+v", appParams)
+	}
+	verify(fmt.Sprintf("%s\n%s\n%s\n%s\n", appParams.AppId, appParams.TimeStamp, appParams.NonceStr, appParams.PrepayId), appParams.Sign)
+
+	if _, err = InvokeJSAPI(tests.Config, &model.JSAPIResponse{}); err == nil {
+		t.Fatal("expected error for empty prepay_id")
+	}
+}
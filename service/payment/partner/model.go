@@ -4,8 +4,89 @@ import (
 	"time"
 
 	"github.com/pyihe/wechat-sdk/v3/model"
+	"github.com/pyihe/wechat-sdk/v3/pkg/validate"
 )
 
+// PrepayRequest APP、Native预下单请求参数, JSAPI、H5预下单请分别使用JSAPIRequest、H5Request
+type PrepayRequest struct {
+	SpAppId     string                 `json:"sp_appid"`              // 服务商应用ID
+	SpMchId     string                 `json:"sp_mchid"`              // 服务商户号
+	SubAppId    string                 `json:"sub_appid,omitempty"`   // 子商户应用ID
+	SubMchId    string                 `json:"sub_mchid"`             // 子商户号
+	Description string                 `json:"description"`           // 商品描述
+	OutTradeNo  string                 `json:"out_trade_no"`          // 商户订单号
+	TimeExpire  string                 `json:"time_expire,omitempty"` // 交易结束时间, RFC3339格式, 如: 2018-06-08T10:34:56+08:00
+	Attach      string                 `json:"attach,omitempty"`      // 附加数据
+	NotifyUrl   string                 `json:"notify_url"`            // 通知地址
+	GoodsTag    string                 `json:"goods_tag,omitempty"`   // 订单优惠标记
+	Amount      *model.PrepayAmount    `json:"amount"`                // 订单金额
+	Detail      *model.PrepayDetail    `json:"detail,omitempty"`      // 优惠功能
+	SceneInfo   *model.PrepaySceneInfo `json:"scene_info,omitempty"`  // 场景信息
+	SettleInfo  *model.SettleInfo      `json:"settle_info,omitempty"` // 结算信息
+}
+
+func (r *PrepayRequest) Validate() error {
+	return validate.First(
+		validate.Required("sp_appid", r.SpAppId),
+		validate.MaxLength("sp_appid", r.SpAppId, 32),
+		validate.Required("sp_mchid", r.SpMchId),
+		validate.MaxLength("sp_mchid", r.SpMchId, 32),
+		validate.MaxLength("sub_appid", r.SubAppId, 32),
+		validate.Required("sub_mchid", r.SubMchId),
+		validate.MaxLength("sub_mchid", r.SubMchId, 32),
+		validate.Required("description", r.Description),
+		validate.MaxLength("description", r.Description, 127),
+		validate.Required("out_trade_no", r.OutTradeNo),
+		validate.Length("out_trade_no", r.OutTradeNo, 6, 32),
+		validate.Time("time_expire", r.TimeExpire),
+		validate.MaxLength("attach", r.Attach, 128),
+		validate.Required("notify_url", r.NotifyUrl),
+		validate.NotifyUrl("notify_url", r.NotifyUrl),
+		validate.MaxLength("goods_tag", r.GoodsTag, 32),
+		validate.Required("amount", r.Amount),
+		validate.Nested("amount", r.Amount),
+		validate.Nested("detail", r.Detail),
+		validate.Nested("scene_info", r.SceneInfo),
+	)
+}
+
+// JSAPIRequest JSAPI预下单请求参数, payer.sp_openid与payer.sub_openid二选一, 使用sub_openid时sub_appid必填
+type JSAPIRequest struct {
+	PrepayRequest
+	Payer *model.PartnerPayer `json:"payer"` // 支付者信息
+}
+
+func (r *JSAPIRequest) Validate() (err error) {
+	if err = r.PrepayRequest.Validate(); err != nil {
+		return
+	}
+	if err = validate.Required("payer", r.Payer); err != nil {
+		return
+	}
+	if r.Payer.SpOpenId == "" && r.Payer.SubOpenId == "" {
+		return validate.Required("payer.sp_openid或payer.sub_openid", "")
+	}
+	if r.Payer.SubOpenId != "" {
+		err = validate.Required("sub_appid", r.SubAppId)
+	}
+	return
+}
+
+// H5Request H5预下单请求参数, scene_info.h5_info必填
+type H5Request struct {
+	PrepayRequest
+}
+
+func (r *H5Request) Validate() (err error) {
+	if err = r.PrepayRequest.Validate(); err != nil {
+		return
+	}
+	if err = validate.Required("scene_info", r.SceneInfo); err != nil {
+		return
+	}
+	return validate.Required("scene_info.h5_info", r.SceneInfo.H5Info)
+}
+
 // QueryOrderRequest 查询订单request
 type QueryOrderRequest struct {
 	SpMchId       string `json:"sp_mchid,omitempty"`       // 服务商户号
@@ -12,6 +12,7 @@ import (
 
 // JSAPI 服务商平台JSAPI支付
 // API文档: https://pay.weixin.qq.com/wiki/doc/apiv3_partner/apis/chapter4_1_1.shtml
+// request: *JSAPIRequest(签名前会校验参数)或者map等可序列化的参数
 func JSAPI(config *service.Config, request interface{}) (jsapiResponse *model.JSAPIResponse, err error) {
 	if config == nil {
 		err = errors.ErrNoConfig
@@ -33,6 +34,7 @@ func JSAPI(config *service.Config, request interface{}) (jsapiResponse *model.JS
 
 // APP 服务商平台JSAPI支付
 // API文档: https://pay.weixin.qq.com/wiki/doc/apiv3_partner/apis/chapter4_2_1.shtml
+// request: *PrepayRequest(签名前会校验参数)或者map等可序列化的参数
 func APP(config *service.Config, request interface{}) (appResponse *model.AppResponse, err error) {
 	if config == nil {
 		err = errors.ErrNoConfig
@@ -53,6 +55,7 @@ func APP(config *service.Config, request interface{}) (appResponse *model.AppRes
 
 // H5 服务商平台H5支付API
 // API文档: https://pay.weixin.qq.com/wiki/doc/apiv3_partner/apis/chapter4_3_1.shtml
+// request: *H5Request(签名前会校验参数)或者map等可序列化的参数
 func H5(config *service.Config, request interface{}) (h5Response *model.H5Response, err error) {
 	if config == nil {
 		err = errors.ErrNoConfig
@@ -74,6 +77,7 @@ func H5(config *service.Config, request interface{}) (h5Response *model.H5Respon
 
 // Native 服务商平台native支付
 // API文档: https://pay.weixin.qq.com/wiki/doc/apiv3_partner/apis/chapter4_4_1.shtml
+// request: *PrepayRequest(签名前会校验参数)或者map等可序列化的参数
 func Native(config *service.Config, request interface{}) (nativeResponse *model.NativeResponse, err error) {
 	if config == nil {
 		err = errors.ErrNoConfig
@@ -165,3 +169,33 @@ func ParsePrepayNotify(config *service.Config, request *http.Request) (order *Pr
 	order.Id, err = config.ParseWechatNotify(request, order)
 	return
 }
+
+// InvokeJSAPI 使用JSAPI、小程序预下单返回的prepay_id生成前端调起支付的参数, 使用服务商私钥签名
+// appId: 用户openid对应的appid, 下单时使用sp_openid则为sp_appid, 使用sub_openid则为sub_appid
+// API文档: https://pay.weixin.qq.com/wiki/doc/apiv3_partner/apis/chapter4_1_4.shtml
+func InvokeJSAPI(config *service.Config, appId string, jsapiResponse *model.JSAPIResponse) (params *model.JSAPIInvokeParams, err error) {
+	if config == nil {
+		err = errors.ErrNoConfig
+		return
+	}
+	if jsapiResponse == nil {
+		err = errors.ErrNoSDKRequest
+		return
+	}
+	return jsapiResponse.InvokeParams(config, appId)
+}
+
+// InvokeAPP 使用APP预下单返回的prepay_id生成APP调起支付的参数, 使用服务商私钥签名
+// subAppId、subMchId: 下单时的子商户应用ID以及子商户号
+// API文档: https://pay.weixin.qq.com/wiki/doc/apiv3_partner/apis/chapter4_2_4.shtml
+func InvokeAPP(config *service.Config, subAppId, subMchId string, appResponse *model.AppResponse) (params *model.AppInvokeParams, err error) {
+	if config == nil {
+		err = errors.ErrNoConfig
+		return
+	}
+	if appResponse == nil {
+		err = errors.ErrNoSDKRequest
+		return
+	}
+	return appResponse.InvokeParams(config, subAppId, subMchId)
+}
@@ -1,11 +1,136 @@
 package payscore
 
 import (
+	"fmt"
 	"time"
 
 	"github.com/pyihe/wechat-sdk/v3/model"
+	"github.com/pyihe/wechat-sdk/v3/pkg/validate"
 )
 
+// 支付分服务时间格式
+const (
+	timeLayout = "20060102150405" // 时间格式: yyyyMMddHHmmss
+	dateLayout = "20060102"       // 日期格式: yyyyMMdd
+	onAccept   = "OnAccept"       // 用户确认订单成功的时间为服务开始时间
+)
+
+// CreateServiceOrderRequest 创建支付分订单请求参数
+type CreateServiceOrderRequest struct {
+	OutOrderNo          string            `json:"out_order_no"`             // 商户服务订单号
+	AppId               string            `json:"appid"`                    // 应用ID
+	ServiceId           string            `json:"service_id"`               // 服务ID
+	ServiceIntroduction string            `json:"service_introduction"`     // 服务信息
+	PostPayments        []*PostPayment    `json:"post_payments,omitempty"`  // 后付费项目
+	PostDiscounts       []*PostDiscount   `json:"post_discounts,omitempty"` // 后付费商户优惠
+	TimeRange           *ServiceTimeRange `json:"time_range"`               // 服务时间段
+	Location            *Location         `json:"location,omitempty"`       // 服务位置
+	RiskFund            *RiskFund         `json:"risk_fund"`                // 订单风险金
+	Attach              string            `json:"attach,omitempty"`         // 商户数据包
+	NotifyUrl           string            `json:"notify_url"`               // 商户回调地址
+	OpenId              string            `json:"openid,omitempty"`         // 用户标识, need_user_confirm为false时必填
+	NeedUserConfirm     bool              `json:"need_user_confirm"`        // 是否需要用户确认
+}
+
+func (r *CreateServiceOrderRequest) Validate() (err error) {
+	err = validate.First(
+		validate.Required("out_order_no", r.OutOrderNo),
+		validate.MaxLength("out_order_no", r.OutOrderNo, 32),
+		validate.Required("appid", r.AppId),
+		validate.MaxLength("appid", r.AppId, 32),
+		validate.Required("service_id", r.ServiceId),
+		validate.MaxLength("service_id", r.ServiceId, 32),
+		validate.Required("service_introduction", r.ServiceIntroduction),
+		validate.MaxChars("service_introduction", r.ServiceIntroduction, 20),
+		validate.Size("post_payments", len(r.PostPayments), 0, 100),
+		validate.Size("post_discounts", len(r.PostDiscounts), 0, 30),
+		validate.Required("time_range", r.TimeRange),
+		validate.Nested("time_range", r.TimeRange),
+		validate.Required("risk_fund", r.RiskFund),
+		validate.MaxLength("attach", r.Attach, 256),
+		validate.Required("notify_url", r.NotifyUrl),
+		validate.NotifyUrl("notify_url", r.NotifyUrl),
+		validate.MaxLength("openid", r.OpenId, 128),
+	)
+	if err != nil {
+		return
+	}
+	if !r.NeedUserConfirm {
+		if err = validate.Required("openid", r.OpenId); err != nil {
+			return
+		}
+	}
+	err = validate.First(
+		validate.Required("risk_fund.name", r.RiskFund.Name),
+		validate.OneOf("risk_fund.name", r.RiskFund.Name, "DEPOSIT", "ADVANCE", "CASH_DEPOSIT", "ESTIMATE_ORDER_COST"),
+		validate.Min("risk_fund.amount", r.RiskFund.Amount, 1),
+		validate.MaxChars("risk_fund.description", r.RiskFund.Description, 30),
+	)
+	if err != nil {
+		return
+	}
+	for i, payment := range r.PostPayments {
+		field := fmt.Sprintf("post_payments[%d]", i)
+		if err = validate.Required(field, payment); err != nil {
+			return
+		}
+		err = validate.First(
+			validate.Required(field+".name", payment.Name),
+			validate.MaxChars(field+".name", payment.Name, 20),
+			validate.MaxChars(field+".description", payment.Description, 30),
+			validate.Min(field+".amount", payment.Amount, 0),
+		)
+		if err != nil {
+			return
+		}
+	}
+	for i, discount := range r.PostDiscounts {
+		field := fmt.Sprintf("post_discounts[%d]", i)
+		if err = validate.Required(field, discount); err != nil {
+			return
+		}
+		err = validate.First(
+			validate.Required(field+".name", discount.Name),
+			validate.MaxChars(field+".name", discount.Name, 20),
+			validate.MaxChars(field+".description", discount.Description, 30),
+			validate.Min(field+".amount", discount.Amount, 0),
+		)
+		if err != nil {
+			return
+		}
+	}
+	return
+}
+
+// ServiceTimeRange 创建订单时的服务时间段, 时间格式为yyyyMMddHHmmss或者yyyyMMdd, 开始时间可以为OnAccept
+type ServiceTimeRange struct {
+	StartTime       string `json:"start_time"`                  // 服务开始时间
+	StartTimeRemark string `json:"start_time_remark,omitempty"` // 服务开始时间备注
+	EndTime         string `json:"end_time,omitempty"`          // 预计服务结束时间
+	EndTimeRemark   string `json:"end_time_remark,omitempty"`   // 预计服务结束时间备注
+}
+
+func (t *ServiceTimeRange) Validate() error {
+	return validate.First(
+		validate.Required("start_time", t.StartTime),
+		checkServiceTime("start_time", t.StartTime, true),
+		validate.MaxChars("start_time_remark", t.StartTimeRemark, 20),
+		checkServiceTime("end_time", t.EndTime, false),
+		validate.MaxChars("end_time_remark", t.EndTimeRemark, 20),
+	)
+}
+
+// checkServiceTime 校验支付分服务时间格式
+func checkServiceTime(field, value string, allowOnAccept bool) error {
+	if value == "" || (allowOnAccept && value == onAccept) {
+		return nil
+	}
+	if len(value) == len(dateLayout) {
+		return validate.TimeLayout(field, value, dateLayout)
+	}
+	return validate.TimeLayout(field, value, timeLayout)
+}
+
 // PrePermitResponse 商户预授权应答
 type PrePermitResponse struct {
 	model.WechatError
@@ -131,12 +131,13 @@ func ParseConfirmOrderNotify(config *service.Config, request *http.Request) (con
 
 // CreateServiceOrder 创建支付订单
 // API文档: https://pay.weixin.qq.com/wiki/doc/apiv3/apis/chapter6_1_14.shtml
+// request: *CreateServiceOrderRequest(签名前会校验参数)或者map等可序列化的参数
 func CreateServiceOrder(config *service.Config, request interface{}) (serviceOrder *ServiceOrder, err error) {
 	if config == nil {
 		err = errors.ErrNoConfig
 		return
 	}
-	if reflect.ValueOf(request).IsZero() {
+	if request == nil || reflect.ValueOf(request).IsZero() {
 		err = errors.ErrNoSDKRequest
 		return
 	}
@@ -1,11 +1,73 @@
 package profitsharing
 
 import (
+	"fmt"
+	"io"
 	"time"
 
 	"github.com/pyihe/wechat-sdk/v3/model"
+	"github.com/pyihe/wechat-sdk/v3/pkg/validate"
 )
 
+// CreateSharingRequest 请求分账请求参数
+type CreateSharingRequest struct {
+	SubMchId        string             `json:"sub_mchid,omitempty"` // 子商户号, 服务商平台必填
+	AppId           string             `json:"appid"`               // 应用ID
+	SubAppId        string             `json:"sub_appid,omitempty"` // 子商户应用ID
+	TransactionId   string             `json:"transaction_id"`      // 微信订单号
+	OutOrderNo      string             `json:"out_order_no"`        // 商户分账单号
+	Receivers       []*SharingReceiver `json:"receivers"`           // 分账接收方列表, 最多50个
+	UnfreezeUnsplit bool               `json:"unfreeze_unsplit"`    // 是否解冻剩余未分资金
+}
+
+func (r *CreateSharingRequest) Validate() (err error) {
+	err = validate.First(
+		validate.MaxLength("sub_mchid", r.SubMchId, 32),
+		validate.Required("appid", r.AppId),
+		validate.MaxLength("appid", r.AppId, 32),
+		validate.MaxLength("sub_appid", r.SubAppId, 32),
+		validate.Required("transaction_id", r.TransactionId),
+		validate.MaxLength("transaction_id", r.TransactionId, 32),
+		validate.Required("out_order_no", r.OutOrderNo),
+		validate.MaxLength("out_order_no", r.OutOrderNo, 64),
+		validate.Size("receivers", len(r.Receivers), 1, 50),
+	)
+	if err != nil {
+		return
+	}
+	for i, receiver := range r.Receivers {
+		field := fmt.Sprintf("receivers[%d]", i)
+		if err = validate.Required(field, receiver); err != nil {
+			return
+		}
+		if err = validate.Nested(field, receiver); err != nil {
+			return
+		}
+	}
+	return
+}
+
+// SharingReceiver 请求分账的接收方, 姓名会使用微信支付平台公钥自动加密
+type SharingReceiver struct {
+	Type        string `json:"type"`                               // 接收方类型: MERCHANT_ID、PERSONAL_OPENID、PERSONAL_SUB_OPENID
+	Account     string `json:"account"`                            // 接收方账号
+	Name        string `json:"name,omitempty" wechatpay:"encrypt"` // 分账个人接收方姓名
+	Amount      int64  `json:"amount"`                             // 分账金额, 单位为分
+	Description string `json:"description"`                        // 分账描述
+}
+
+func (r *SharingReceiver) Validate() error {
+	return validate.First(
+		validate.Required("type", r.Type),
+		validate.OneOf("type", r.Type, "MERCHANT_ID", "PERSONAL_OPENID", "PERSONAL_SUB_OPENID"),
+		validate.Required("account", r.Account),
+		validate.MaxLength("account", r.Account, 64),
+		validate.Min("amount", r.Amount, 1),
+		validate.Required("description", r.Description),
+		validate.MaxChars("description", r.Description, 80),
+	)
+}
+
 // CreateSharingResponse 请求分账应答参数
 type CreateSharingResponse struct {
 	model.WechatError
@@ -61,12 +123,12 @@ type QueryMaxSharingRatioResponse struct {
 type AddReceiverResponse struct {
 	model.WechatError
 	RequestId      string // 唯一请求ID
-	SubMchId       string `json:"sub_mchid,omitempty"`       // 子商户号, 服务商平台返回
-	Type           string `json:"type,omitempty"`            // 分账接收方类型
-	Account        string `json:"account,omitempty"`         // 分账接收方账号
-	Name           string `json:"name,omitempty"`            // 分账接收方全称
-	RelationType   string `json:"relation_type,omitempty"`   // 与分账方的关系类型
-	CustomRelation string `json:"custom_relation,omitempty"` // 自定义的分账关系
+	SubMchId       string `json:"sub_mchid,omitempty"`                // 子商户号, 服务商平台返回
+	Type           string `json:"type,omitempty"`                     // 分账接收方类型
+	Account        string `json:"account,omitempty"`                  // 分账接收方账号
+	Name           string `json:"name,omitempty" wechatpay:"encrypt"` // 分账接收方全称, 微信返回密文, 解析时自动解密
+	RelationType   string `json:"relation_type,omitempty"`            // 与分账方的关系类型
+	CustomRelation string `json:"custom_relation,omitempty"`          // 自定义的分账关系
 }
 
 // DeleteReceiverResponse 删除分账接收方
@@ -92,10 +154,12 @@ type SharingNotifyResponse struct {
 
 // DownloadBillsRequest 申请分账账单请求参数
 type DownloadBillsRequest struct {
-	BillDate string // 账单日期
-	SubMchId string // 子商户号
-	FileName string // 账单文件的存储名
-	FilePath string // 账单文件的存储路径
+	BillDate string    // 账单日期
+	SubMchId string    // 子商户号
+	TarType  string    // 压缩类型, 为GZIP时下载后自动解压
+	FileName string    // 账单文件的存储名
+	FilePath string    // 账单文件的存储路径
+	Writer   io.Writer // 账单写入的目标, 不为nil时写入Writer而不是文件
 }
 
 // DownloadBillResponse 分账账单应答参数
@@ -1,19 +1,18 @@
 package profitsharing
 
 import (
-	"crypto"
 	"fmt"
 	"net/http"
 	"net/url"
 
 	"github.com/pyihe/wechat-sdk/v3/pkg/errors"
-	"github.com/pyihe/wechat-sdk/v3/pkg/files"
 	"github.com/pyihe/wechat-sdk/v3/service"
 )
 
 // CreateSharing 请求分账
 // 商户平台文档: https://pay.weixin.qq.com/wiki/doc/apiv3/apis/chapter8_1_1.shtml
 // 服务商平台文档: https://pay.weixin.qq.com/wiki/doc/apiv3_partner/apis/chapter8_1_1.shtml
+// request: *CreateSharingRequest(签名前会校验参数)或者map等可序列化的参数
 func CreateSharing(config *service.Config, request interface{}) (sharingResponse *CreateSharingResponse, err error) {
 	if config == nil {
 		err = errors.ErrNoConfig
@@ -228,6 +227,9 @@ func DownloadSharingBills(config *service.Config, request *DownloadBillsRequest)
 	if request.SubMchId != "" {
 		param.Add("sub_mchid", request.SubMchId)
 	}
+	if request.TarType != "" {
+		param.Add("tar_type", request.TarType)
+	}
 	response, err := config.RequestWithSign(http.MethodGet, fmt.Sprintf("/v3/profitsharing/bills?%s", param.Encode()), nil)
 	if err != nil {
 		return
@@ -238,21 +240,12 @@ func DownloadSharingBills(config *service.Config, request *DownloadBillsRequest)
 		return
 	}
 
-	content, err := config.Download(downloadResponse.DownloadUrl)
-	if err != nil {
-		return
-	}
-
-	switch downloadResponse.HashType {
-	case "SHA1":
-		if err = config.VerifyHashValue(crypto.SHA1, content, downloadResponse.HashValue); err != nil {
-			return
-		}
-	default:
-		err = errors.ErrInvalidHashType
+	// 流式下载并校验摘要值, gzip压缩的账单会自动解压
+	options := &service.DownloadOptions{HashType: downloadResponse.HashType, HashValue: downloadResponse.HashValue}
+	if request.Writer != nil {
+		_, err = config.DownloadTo(downloadResponse.DownloadUrl, request.Writer, options)
 		return
 	}
-
 	fileName := request.FileName
 	filePath := request.FilePath
 	if fileName == "" {
@@ -261,6 +254,6 @@ func DownloadSharingBills(config *service.Config, request *DownloadBillsRequest)
 	if filePath == "" {
 		filePath = "./sharing"
 	}
-	err = files.WritToFile(filePath, fileName, content)
+	_, err = config.DownloadToFile(downloadResponse.DownloadUrl, filePath, fileName, options)
 	return
 }
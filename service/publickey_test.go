@@ -0,0 +1,142 @@
+package service
+
+import (
+	"crypto"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/pem"
+	"fmt"
+	"math/big"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/pyihe/secret"
+)
+
+func signWechatResponse(t *testing.T, w http.ResponseWriter, privateKey *rsa.PrivateKey, serialNo string, body []byte) {
+	timestamp := fmt.Sprintf("%d", time.Now().Unix())
+	nonce := "5K8264ILTKCH16CQ2502SI8ZNMTM67VS"
+	hashed := sha256.Sum256([]byte(fmt.Sprintf("%s\n%s\n%s\n", timestamp, nonce, body)))
+	signature, err := rsa.SignPKCS1v15(rand.Reader, privateKey, crypto.SHA256, hashed[:])
+	if err != nil {
+		t.Fatal(err)
+	}
+	w.Header().Set("Wechatpay-Serial", serialNo)
+	w.Header().Set("Wechatpay-Timestamp", timestamp)
+	w.Header().Set("Wechatpay-Nonce", nonce)
+	w.Header().Set("Wechatpay-Signature", base64.StdEncoding.EncodeToString(signature))
+	w.Header().Set("Request-ID", "08F78BB5AF0610")
+	w.WriteHeader(http.StatusOK)
+	_, _ = w.Write(body)
+}
+
+func TestPlatformPublicKey(t *testing.T) {
+	const keyId = "PUB_KEY_ID_0114232134912410000000000000"
+	platformKey, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatal(err)
+	}
+	der, err := x509.MarshalPKIXPublicKey(&platformKey.PublicKey)
+	if err != nil {
+		t.Fatal(err)
+	}
+	publicKeyPEM := string(pem.EncodeToMemory(&pem.Block{Type: "PUBLIC KEY", Bytes: der}))
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		signWechatResponse(t, w, platformKey, keyId, []byte(`{"trade_state":"SUCCESS"}`))
+	}))
+	defer server.Close()
+
+	config := newTestConfig(t, server.URL, WithPlatformPublicKey(keyId, publicKeyPEM))
+	response, err := config.RequestWithSign(http.MethodGet, "/v3/pay/transactions/id/4200000985202103031441826014", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var order struct {
+		TradeState string `json:"trade_state"`
+	}
+	requestId, err := config.ParseWechatResponse(response, &order)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if requestId != "08F78BB5AF0610" || order.TradeState != "SUCCESS" {
+		t.Fatalf("unexpected response: %s %+v", requestId, order)
+	}
+
+	serialNo, cipher, err := config.GetEncryptCipher()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if serialNo != keyId {
+		t.Fatalf("unexpected Wechatpay-Serial: %s", serialNo)
+	}
+	if _, err = cipher.RSAEncryptToString("张三", crypto.SHA1, secret.RSAEncryptTypeOAEP, nil); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestGetValidPublicKey(t *testing.T) {
+	const keyId = "PUB_KEY_ID_0114232134912410000000000000"
+	keys := make([]*rsa.PrivateKey, 3)
+	for i := range keys {
+		key, err := rsa.GenerateKey(rand.Reader, 2048)
+		if err != nil {
+			t.Fatal(err)
+		}
+		keys[i] = key
+	}
+	der, err := x509.MarshalPKIXPublicKey(&keys[0].PublicKey)
+	if err != nil {
+		t.Fatal(err)
+	}
+	publicKeyPEM := string(pem.EncodeToMemory(&pem.Block{Type: "PUBLIC KEY", Bytes: der}))
+
+	// 未配置公钥ID时使用最近一次添加的公钥
+	config := newTestConfig(t, "")
+	for i := 0; i < 20; i++ {
+		config.AddPublicKey(fmt.Sprintf("PUB_KEY_ID_%d", i), &keys[i%3].PublicKey)
+	}
+	for i := 0; i < 10; i++ {
+		if serialNo, publicKey := config.GetValidPublicKey(); serialNo != "PUB_KEY_ID_19" || publicKey != &keys[1].PublicKey {
+			t.Fatalf("expected the latest public key, got: %s", serialNo)
+		}
+	}
+
+	// 配置了公钥ID时始终使用配置的公钥
+	config = newTestConfig(t, "", WithPlatformPublicKey(keyId, publicKeyPEM))
+	config.AddPublicKey("PUB_KEY_ID_NEW", &keys[2].PublicKey)
+	for i := 0; i < 10; i++ {
+		if serialNo, _ := config.GetValidPublicKey(); serialNo != keyId {
+			t.Fatalf("expected the configured public key, got: %s", serialNo)
+		}
+	}
+
+	// 证书模式下使用过期时间最晚的有效证书, 且不删除过期的证书
+	config = newTestConfig(t, "")
+	now := time.Now()
+	for i, notAfter := range []time.Time{now.Add(-time.Hour), now.Add(2 * 365 * 24 * time.Hour), now.Add(365 * 24 * time.Hour)} {
+		template := &x509.Certificate{SerialNumber: big.NewInt(int64(i + 1)), NotBefore: now.Add(-48 * time.Hour), NotAfter: notAfter}
+		der, err := x509.CreateCertificate(rand.Reader, template, template, &keys[i].PublicKey, keys[i])
+		if err != nil {
+			t.Fatal(err)
+		}
+		cert, err := x509.ParseCertificate(der)
+		if err != nil {
+			t.Fatal(err)
+		}
+		config.AddCertificate(fmt.Sprintf("SERIAL_%d", i), cert)
+	}
+	for i := 0; i < 10; i++ {
+		if serialNo, publicKey := config.GetValidPublicKey(); serialNo != "SERIAL_1" || publicKey.N.Cmp(keys[1].N) != 0 {
+			t.Fatalf("expected the certificate with the latest NotAfter, got: %s", serialNo)
+		}
+	}
+	if len(config.GetCertificates()) != 3 {
+		t.Fatal("GetValidPublicKey should not remove expired certificates")
+	}
+}
@@ -0,0 +1,239 @@
+package service
+
+import (
+	"context"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/pyihe/wechat-sdk/v3/pkg/errors"
+)
+
+// Limiter 令牌桶限流器, golang.org/x/time/rate.Limiter同样实现了该接口
+type Limiter interface {
+	// Wait 阻塞直到获取到令牌, ctx结束时返回ctx.Err()
+	Wait(ctx context.Context) error
+	// Allow 立即获取令牌, 没有可用的令牌时返回false
+	Allow() bool
+}
+
+// RateLimitRule 限流规则, 匹配的请求在发送前(包括每次重试)需要先获取令牌
+type RateLimitRule struct {
+	// 请求方法, 如: GET, 为空时匹配所有方法
+	Method string
+
+	// 除去域名的接口路径, 不包括query参数, {xxx}匹配任意一段路径, 以/*结尾时匹配所有子路径, 为空时匹配所有接口, 如:
+	// /v3/pay/transactions/out-trade-no/{out_trade_no}、/v3/marketing/favor/users/{openid}/coupons、/v3/bill/*
+	Pattern string
+
+	// 只对该商户号生效, 为空时对所有商户生效, 每个商户单独计算令牌
+	MchId string
+
+	// 每秒产生的令牌数
+	Rate float64
+
+	// 令牌桶的容量, 即允许的突发请求数, 小于1时为1
+	Burst int
+
+	// 为true时没有可用的令牌立即返回errors.ErrRateLimited, 否则阻塞等待, 直到Config绑定的ctx结束
+	FailFast bool
+
+	// 自定义限流器, 不为nil时忽略Rate、Burst, 所有商户共用该限流器
+	Limiter Limiter
+}
+
+// RateLimiter 按照接口以及商户号限流, 可以在多个Config之间共享(如多商户场景), 规则按照添加的顺序匹配, 只使用第一条匹配的规则
+type RateLimiter struct {
+	rules   []RateLimitRule
+	mu      sync.Mutex
+	buckets map[string]Limiter // key为规则序号+商户号
+}
+
+// NewRateLimiter 创建RateLimiter
+func NewRateLimiter(rules ...RateLimitRule) *RateLimiter {
+	return &RateLimiter{
+		rules:   rules,
+		buckets: make(map[string]Limiter),
+	}
+}
+
+// WithRateLimit 使用rules创建RateLimiter并设置到Config中, 对RequestWithSign、UploadMedia、Download发起的请求生效
+func WithRateLimit(rules ...RateLimitRule) Option {
+	return WithRateLimiter(NewRateLimiter(rules...))
+}
+
+// WithRateLimiter 设置RateLimiter, 多个Config共享同一个RateLimiter时, 相同商户号的请求共用令牌
+func WithRateLimiter(limiter *RateLimiter) Option {
+	return func(config *Config) {
+		config.rateLimiter = limiter
+	}
+}
+
+// acquire 为c发起的请求获取令牌, 被限流拒绝(或者等待时ctx结束)时同样通知观察者, 以便统计客户端的限流
+func (c *Config) acquire(operation, method, url string, attempt int) (err error) {
+	start := time.Now()
+	if err = c.rateLimiter.Acquire(c.Context(), c.mchId, method, url); err == nil || len(c.observers) == 0 {
+		return
+	}
+	path := url
+	if i := strings.IndexByte(path, '?'); i >= 0 {
+		path = path[:i]
+	}
+	c.notifyObservers(&Event{
+		Operation: operation,
+		Method:    method,
+		Endpoint:  EndpointTemplate(path),
+		Path:      path,
+		MchId:     c.mchId,
+		Attempt:   attempt,
+		Start:     start,
+		Duration:  time.Since(start),
+		Err:       err,
+	})
+	return
+}
+
+// Acquire 为商户mchId的请求获取令牌, 没有匹配的规则时直接返回
+func (r *RateLimiter) Acquire(ctx context.Context, mchId, method, path string) error {
+	if r == nil {
+		return nil
+	}
+	if i := strings.IndexByte(path, '?'); i >= 0 {
+		path = path[:i]
+	}
+	for i := range r.rules {
+		rule := &r.rules[i]
+		if !rule.match(mchId, method, path) {
+			continue
+		}
+		limiter := r.limiter(i, mchId)
+		if rule.FailFast {
+			if !limiter.Allow() {
+				return errors.ErrRateLimited
+			}
+			return nil
+		}
+		return limiter.Wait(ctx)
+	}
+	return nil
+}
+
+func (r *RateLimiter) limiter(index int, mchId string) Limiter {
+	rule := &r.rules[index]
+	if rule.Limiter != nil {
+		return rule.Limiter
+	}
+	key := strconv.Itoa(index) + ":" + mchId
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	limiter, ok := r.buckets[key]
+	if !ok {
+		limiter = NewTokenBucket(rule.Rate, rule.Burst)
+		r.buckets[key] = limiter
+	}
+	return limiter
+}
+
+func (rule *RateLimitRule) match(mchId, method, path string) bool {
+	if rule.MchId != "" && rule.MchId != mchId {
+		return false
+	}
+	if rule.Method != "" && !strings.EqualFold(rule.Method, method) {
+		return false
+	}
+	return rule.Pattern == "" || matchPattern(rule.Pattern, path)
+}
+
+// matchPattern 逐段匹配路径, {xxx}匹配任意一段, 结尾的*匹配剩余的所有路径
+func matchPattern(pattern, path string) bool {
+	patterns := strings.Split(strings.Trim(pattern, "/"), "/")
+	segments := strings.Split(strings.Trim(path, "/"), "/")
+	for i, p := range patterns {
+		if p == "*" && i == len(patterns)-1 {
+			return len(segments) > i
+		}
+		if i >= len(segments) {
+			return false
+		}
+		if strings.HasPrefix(p, "{") && strings.HasSuffix(p, "}") {
+			continue
+		}
+		if p != segments[i] {
+			return false
+		}
+	}
+	return len(patterns) == len(segments)
+}
+
+// TokenBucket 令牌桶, 以rate的速率产生令牌, 最多保存burst个令牌
+type TokenBucket struct {
+	mu     sync.Mutex
+	rate   float64
+	burst  float64
+	tokens float64
+	last   time.Time
+}
+
+// NewTokenBucket 创建令牌桶, 初始时桶是满的, rate小于等于0时不产生新的令牌
+func NewTokenBucket(rate float64, burst int) *TokenBucket {
+	if burst < 1 {
+		burst = 1
+	}
+	return &TokenBucket{
+		rate:   rate,
+		burst:  float64(burst),
+		tokens: float64(burst),
+		last:   time.Now(),
+	}
+}
+
+// Allow 实现Limiter
+func (b *TokenBucket) Allow() bool {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.refill(time.Now())
+	if b.tokens < 1 {
+		return false
+	}
+	b.tokens--
+	return true
+}
+
+// Wait 实现Limiter, 先预定令牌再等待, 保证等待的请求按照先后顺序获取令牌
+func (b *TokenBucket) Wait(ctx context.Context) error {
+	b.mu.Lock()
+	b.refill(time.Now())
+	b.tokens--
+	var wait time.Duration
+	if b.tokens < 0 {
+		if b.rate <= 0 {
+			b.tokens++
+			b.mu.Unlock()
+			return errors.ErrRateLimited
+		}
+		wait = time.Duration(-b.tokens / b.rate * float64(time.Second))
+	}
+	b.mu.Unlock()
+	if wait <= 0 {
+		return nil
+	}
+	if err := sleepContext(ctx, wait); err != nil {
+		// 归还预定的令牌
+		b.mu.Lock()
+		b.tokens++
+		b.mu.Unlock()
+		return err
+	}
+	return nil
+}
+
+func (b *TokenBucket) refill(now time.Time) {
+	if elapsed := now.Sub(b.last); elapsed > 0 {
+		b.tokens += elapsed.Seconds() * b.rate
+		if b.tokens > b.burst {
+			b.tokens = b.burst
+		}
+		b.last = now
+	}
+}
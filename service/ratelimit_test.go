@@ -0,0 +1,129 @@
+package service
+
+import (
+	"context"
+	stderrors "errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/pyihe/wechat-sdk/v3/pkg/errors"
+)
+
+func TestMatchPattern(t *testing.T) {
+	cases := []struct {
+		pattern, path string
+		match         bool
+	}{
+		{"/v3/pay/transactions/out-trade-no/{out_trade_no}", "/v3/pay/transactions/out-trade-no/1217752501201407033233368018", true},
+		{"/v3/pay/transactions/out-trade-no/{out_trade_no}", "/v3/pay/transactions/out-trade-no/1217752501201407033233368018/close", false},
+		{"/v3/marketing/favor/users/{openid}/coupons", "/v3/marketing/favor/users/oUpF8uMuAJO_M2pxb1Q9zNjWeS6o/coupons", true},
+		{"/v3/bill/*", "/v3/bill/tradebill", true},
+		{"/v3/bill/*", "/v3/bill", false},
+		{"/v3/bill/*", "/v3/billdownload/file", false},
+	}
+	for _, c := range cases {
+		if got := matchPattern(c.pattern, c.path); got != c.match {
+			t.Fatalf("matchPattern(%s, %s) = %v, want %v", c.pattern, c.path, got, c.match)
+		}
+	}
+}
+
+func TestRateLimit(t *testing.T) {
+	var hits int
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		hits++
+		w.WriteHeader(http.StatusNoContent)
+	}))
+	defer server.Close()
+
+	limiter := NewRateLimiter(
+		RateLimitRule{Method: http.MethodPost, Pattern: "/v3/marketing/favor/users/{openid}/coupons", Rate: 1, Burst: 1, FailFast: true},
+		RateLimitRule{Pattern: "/v3/pay/transactions/out-trade-no/{out_trade_no}", Rate: 20, Burst: 1},
+	)
+	var events []*Event
+	observer := ObserverFunc(func(ctx context.Context, event *Event) {
+		events = append(events, event)
+	})
+	config := newTestConfig(t, server.URL, WithRateLimiter(limiter), WithObserver(observer))
+	other := newTestConfig(t, server.URL, WithMchId("1900000002"), WithRateLimiter(limiter))
+
+	// 超出限制时立即失败, 请求不会发送到微信, 其他商户不受影响
+	send := "/v3/marketing/favor/users/oUpF8uMuAJO_M2pxb1Q9zNjWeS6o/coupons"
+	if _, err := config.RequestWithSign(http.MethodPost, send, nil); err != nil {
+		t.Fatal(err)
+	}
+	_, err := config.RequestWithSign(http.MethodPost, send, nil)
+	if !stderrors.Is(err, errors.ErrRateLimited) || !errors.IsRetryable(err) {
+		t.Fatalf("expected ErrRateLimited, got: %v", err)
+	}
+	if _, err = other.RequestWithSign(http.MethodPost, send, nil); err != nil {
+		t.Fatal(err)
+	}
+	if hits != 2 {
+		t.Fatalf("unexpected hits: %d", hits)
+	}
+	// 被限流拒绝的请求同样通知观察者
+	if len(events) != 2 || !stderrors.Is(events[1].Err, errors.ErrRateLimited) || events[1].StatusCode != 0 ||
+		events[1].Endpoint != "/v3/marketing/favor/users/{id}/coupons" {
+		t.Fatalf("unexpected events: %+v", events)
+	}
+
+	// 阻塞等待令牌
+	query := "/v3/pay/transactions/out-trade-no/1217752501201407033233368018?mchid=1900000001"
+	start := time.Now()
+	for i := 0; i < 3; i++ {
+		if _, err = config.RequestWithSign(http.MethodGet, query, nil); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if elapsed := time.Since(start); elapsed < 90*time.Millisecond {
+		t.Fatalf("requests should be throttled, elapsed: %v", elapsed)
+	}
+}
+
+// orderRecorder 记录限流与签名的先后顺序
+type orderRecorder struct {
+	steps []string
+}
+
+func (r *orderRecorder) Wait(ctx context.Context) error {
+	r.steps = append(r.steps, "acquire")
+	return nil
+}
+
+func (r *orderRecorder) Allow() bool {
+	r.steps = append(r.steps, "acquire")
+	return true
+}
+
+func (r *orderRecorder) Sign(ctx context.Context, message string) (string, error) {
+	r.steps = append(r.steps, "sign")
+	return "signature", nil
+}
+
+func (r *orderRecorder) SerialNo() string {
+	return "TESTSERIALNO"
+}
+
+func TestRateLimitBeforeSign(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusNoContent)
+	}))
+	defer server.Close()
+
+	recorder := new(orderRecorder)
+	config := newTestConfig(t, server.URL, WithSigner(recorder), WithRateLimit(RateLimitRule{Limiter: recorder}))
+	// 限流等待之后再签名, 避免签名中的时间戳过期
+	if _, err := config.RequestWithSign(http.MethodGet, "/v3/certificates", nil); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := config.UploadMedia("/v3/merchant/media/upload", "image/png", "a.png", []byte("png")); err != nil {
+		t.Fatal(err)
+	}
+	if fmt.Sprint(recorder.steps) != "[acquire sign acquire sign]" {
+		t.Fatalf("unexpected steps: %v", recorder.steps)
+	}
+}
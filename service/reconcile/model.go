@@ -0,0 +1,91 @@
+package reconcile
+
+import (
+	"time"
+
+	"github.com/pyihe/wechat-sdk/v3/service/bills"
+)
+
+// LocalOrder 本地支付订单
+type LocalOrder struct {
+	OutTradeNo    string // 商户订单号
+	TransactionId string // 微信订单号, 未记录时为空
+	Amount        int64  // 订单金额, 单位为分
+	State         string // 本地订单状态, 使用微信的交易状态表示, 如: SUCCESS、REFUND、NOTPAY、CLOSED
+}
+
+// LocalRefund 本地退款单
+type LocalRefund struct {
+	OutRefundNo string // 商户退款单号
+	RefundId    string // 微信退款单号, 未记录时为空
+	OutTradeNo  string // 商户订单号
+	Amount      int64  // 申请退款金额, 单位为分
+	Status      string // 本地退款状态, 使用微信的退款状态表示, 如: SUCCESS、PROCESSING、ABNORMAL、CLOSED
+}
+
+// OrderStore 本地订单存储, 一般基于业务数据库实现
+type OrderStore interface {
+	// FindOrder 根据商户订单号或者微信订单号查找本地订单, 不存在时返回nil, nil
+	FindOrder(outTradeNo, transactionId string) (*LocalOrder, error)
+	// FindRefund 根据商户退款单号或者微信退款单号查找本地退款单, 不存在时返回nil, nil
+	FindRefund(outRefundNo, refundId string) (*LocalRefund, error)
+	// ListOrders 返回支付成功时间在[start, end)内的本地订单, 用于查找微信账单中缺失的订单
+	ListOrders(start, end time.Time) ([]*LocalOrder, error)
+	// ListRefunds 返回退款成功时间在[start, end)内的本地退款单, 用于查找微信账单中缺失的退款单
+	ListRefunds(start, end time.Time) ([]*LocalRefund, error)
+}
+
+// Kind 差异类型
+type Kind string
+
+const (
+	MissingLocal   Kind = "MISSING_LOCAL"   // 微信账单中存在, 本地不存在
+	MissingRemote  Kind = "MISSING_REMOTE"  // 本地存在, 微信账单中不存在
+	AmountMismatch Kind = "AMOUNT_MISMATCH" // 金额不一致
+	StatusMismatch Kind = "STATUS_MISMATCH" // 状态不一致
+)
+
+// Discrepancy 对账差异
+type Discrepancy struct {
+	Kind          Kind                   // 差异类型
+	IsRefund      bool                   // 是否为退款单
+	OutTradeNo    string                 // 商户订单号
+	TransactionId string                 // 微信订单号
+	OutRefundNo   string                 // 商户退款单号, 仅退款单
+	RefundId      string                 // 微信退款单号, 仅退款单
+	LocalAmount   int64                  // 本地金额, 单位为分
+	RemoteAmount  int64                  // 账单金额, 单位为分
+	LocalStatus   string                 // 本地状态
+	RemoteStatus  string                 // 账单状态
+	Record        *bills.TradeBillRecord // 账单明细, MissingRemote时为nil
+	LocalOrder    *LocalOrder            // 本地订单, 退款单以及MissingLocal时为nil
+	LocalRefund   *LocalRefund           // 本地退款单, 支付订单以及MissingLocal时为nil
+
+	// 以下为开启自动确认后, 通过查询接口得到的结果
+	Confirmed     bool   // 是否已经查询确认, 查询到订单(退款单)不存在时同样为true
+	LiveStatus    string // 查询得到的订单(退款)状态, 订单(退款单)不存在时为空
+	LiveAmount    int64  // 查询得到的订单(退款)金额, 单位为分
+	LiveRequestId string // 查询接口返回的唯一请求ID
+	Resolved      bool   // 查询结果与本地一致, 差异可以忽略(如跨日支付的订单出现在次日账单中)
+	ConfirmErr    error  // 查询失败的原因
+}
+
+// Report 对账结果
+type Report struct {
+	BillDate      string                  // 账单日期
+	Orders        int                     // 账单中的支付笔数
+	Refunds       int                     // 账单中的退款笔数
+	Matched       int                     // 账单与本地一致的笔数
+	Discrepancies []*Discrepancy          // 差异明细
+	Summary       *bills.TradeBillSummary // 账单汇总
+}
+
+// Unresolved 返回未被自动确认消除的差异
+func (r *Report) Unresolved() (discrepancies []*Discrepancy) {
+	for _, d := range r.Discrepancies {
+		if !d.Resolved {
+			discrepancies = append(discrepancies, d)
+		}
+	}
+	return
+}
@@ -0,0 +1,308 @@
+package reconcile
+
+import (
+	stderrors "errors"
+	"fmt"
+	"io"
+	"time"
+
+	"github.com/pyihe/wechat-sdk/v3/pkg/errors"
+	"github.com/pyihe/wechat-sdk/v3/service"
+	"github.com/pyihe/wechat-sdk/v3/service/bills"
+	"github.com/pyihe/wechat-sdk/v3/service/payment/merchant"
+	"github.com/pyihe/wechat-sdk/v3/service/refunds"
+)
+
+// 账单日期按北京时间划分
+var cst = time.FixedZone("CST", 8*3600)
+
+// Reconciler 将微信交易账单与本地订单进行对账
+type Reconciler struct {
+	config      *service.Config
+	store       OrderStore
+	autoConfirm bool
+}
+
+// Option 对账选项
+type Option func(*Reconciler)
+
+// WithAutoConfirm 对无法仅凭账单判断的差异(本地存在但账单中不存在、状态不一致)通过查询订单、查询退款接口进行确认,
+// 查询使用Config的上下文与限流配置, 目前仅支持直连商户的订单
+func WithAutoConfirm() Option {
+	return func(r *Reconciler) {
+		r.autoConfirm = true
+	}
+}
+
+// New 创建对账器, config用于下载账单以及自动确认时查询订单
+func New(config *service.Config, store OrderStore, opts ...Option) *Reconciler {
+	r := &Reconciler{config: config, store: store}
+	for _, opt := range opts {
+		opt(r)
+	}
+	return r
+}
+
+// Reconcile 下载billDate(格式: 2006-01-02)的全部交易账单(ALL)并对账, 账单边下载边处理, 不会保存到本地文件
+func (r *Reconciler) Reconcile(billDate string) (report *Report, err error) {
+	if r.config == nil {
+		err = errors.ErrNoConfig
+		return
+	}
+	_, err = bills.DownloadTradeBill(r.config, &bills.TradeBillRequest{
+		BillDate: billDate,
+		BillType: "ALL",
+		TarType:  "GZIP",
+		Handler: func(bill io.Reader) (err error) {
+			report, err = r.ReconcileBill(billDate, bill)
+			return
+		},
+	})
+	if err == nil && report == nil {
+		err = fmt.Errorf("申请交易账单失败: 未返回账单[%s]的下载地址", billDate)
+	}
+	if err != nil {
+		report = nil
+	}
+	return
+}
+
+// ReconcileBill 使用已经下载(解压)的ALL类型交易账单对账, 其他类型的账单缺少支付或者退款记录, 会导致对应的本地记录被报告为账单缺失,
+// billDate用于从OrderStore中查询当日的本地订单, 以找出账单中缺失的记录
+func (r *Reconciler) ReconcileBill(billDate string, bill io.Reader) (report *Report, err error) {
+	if r.store == nil {
+		err = errors.ErrParam
+		return
+	}
+	start, err := time.ParseInLocation("2006-01-02", billDate, cst)
+	if err != nil {
+		return
+	}
+	end := start.AddDate(0, 0, 1)
+
+	reader, err := bills.NewTradeBillReader(bill)
+	if err != nil {
+		return
+	}
+	report = &Report{BillDate: billDate}
+	seen := make(map[string]bool)
+	for {
+		var record *bills.TradeBillRecord
+		if record, err = reader.Next(); err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, err
+		}
+		if isRefundRecord(record) {
+			report.Refunds++
+			seen["refund:"+record.OutRefundNo] = true
+			seen["refund_id:"+record.RefundId] = true
+			err = r.checkRefund(report, record)
+		} else {
+			report.Orders++
+			seen["order:"+record.OutTradeNo] = true
+			seen["transaction_id:"+record.TransactionId] = true
+			err = r.checkOrder(report, record)
+		}
+		if err != nil {
+			return nil, err
+		}
+	}
+	report.Summary, err = reader.Summary(), nil
+
+	if err = r.findMissingRemote(report, seen, start, end); err != nil {
+		return nil, err
+	}
+	if r.autoConfirm {
+		r.confirm(report)
+	}
+	return
+}
+
+// isRefundRecord 判断账单明细是否为退款, ALL类型账单中支付记录的退款列为0
+func isRefundRecord(record *bills.TradeBillRecord) bool {
+	return record.OutRefundNo != "" && record.OutRefundNo != "0"
+}
+
+// 本地订单状态为支付成功或者转入退款时, 账单中都应当存在支付记录
+func isPaid(state string) bool {
+	return state == "SUCCESS" || state == "REFUND"
+}
+
+func (r *Reconciler) checkOrder(report *Report, record *bills.TradeBillRecord) (err error) {
+	local, err := r.store.FindOrder(record.OutTradeNo, record.TransactionId)
+	if err != nil {
+		return
+	}
+	newDiscrepancy := func(kind Kind) *Discrepancy {
+		d := &Discrepancy{
+			Kind:          kind,
+			OutTradeNo:    record.OutTradeNo,
+			TransactionId: record.TransactionId,
+			RemoteAmount:  record.TotalFee,
+			RemoteStatus:  record.TradeState,
+			Record:        record,
+		}
+		if local != nil {
+			d.LocalOrder, d.LocalAmount, d.LocalStatus = local, local.Amount, local.State
+		}
+		return d
+	}
+	if local == nil {
+		report.Discrepancies = append(report.Discrepancies, newDiscrepancy(MissingLocal))
+		return
+	}
+	matched := true
+	if local.Amount != record.TotalFee {
+		matched = false
+		report.Discrepancies = append(report.Discrepancies, newDiscrepancy(AmountMismatch))
+	}
+	if isPaid(record.TradeState) != isPaid(local.State) {
+		matched = false
+		report.Discrepancies = append(report.Discrepancies, newDiscrepancy(StatusMismatch))
+	}
+	if matched {
+		report.Matched++
+	}
+	return
+}
+
+func (r *Reconciler) checkRefund(report *Report, record *bills.TradeBillRecord) (err error) {
+	local, err := r.store.FindRefund(record.OutRefundNo, record.RefundId)
+	if err != nil {
+		return
+	}
+	newDiscrepancy := func(kind Kind) *Discrepancy {
+		d := &Discrepancy{
+			Kind:          kind,
+			IsRefund:      true,
+			OutTradeNo:    record.OutTradeNo,
+			TransactionId: record.TransactionId,
+			OutRefundNo:   record.OutRefundNo,
+			RefundId:      record.RefundId,
+			RemoteAmount:  record.RefundFee,
+			RemoteStatus:  record.RefundStatus,
+			Record:        record,
+		}
+		if local != nil {
+			d.LocalRefund, d.LocalAmount, d.LocalStatus = local, local.Amount, local.Status
+		}
+		return d
+	}
+	if local == nil {
+		report.Discrepancies = append(report.Discrepancies, newDiscrepancy(MissingLocal))
+		return
+	}
+	matched := true
+	if local.Amount != record.RefundFee {
+		matched = false
+		report.Discrepancies = append(report.Discrepancies, newDiscrepancy(AmountMismatch))
+	}
+	if local.Status != record.RefundStatus {
+		matched = false
+		report.Discrepancies = append(report.Discrepancies, newDiscrepancy(StatusMismatch))
+	}
+	if matched {
+		report.Matched++
+	}
+	return
+}
+
+// findMissingRemote 找出当日本地存在但账单中不存在的订单、退款单
+func (r *Reconciler) findMissingRemote(report *Report, seen map[string]bool, start, end time.Time) (err error) {
+	orders, err := r.store.ListOrders(start, end)
+	if err != nil {
+		return
+	}
+	for _, o := range orders {
+		if seen["order:"+o.OutTradeNo] || (o.TransactionId != "" && seen["transaction_id:"+o.TransactionId]) {
+			continue
+		}
+		report.Discrepancies = append(report.Discrepancies, &Discrepancy{
+			Kind:          MissingRemote,
+			OutTradeNo:    o.OutTradeNo,
+			TransactionId: o.TransactionId,
+			LocalAmount:   o.Amount,
+			LocalStatus:   o.State,
+			LocalOrder:    o,
+		})
+	}
+
+	refundList, err := r.store.ListRefunds(start, end)
+	if err != nil {
+		return
+	}
+	for _, rf := range refundList {
+		if seen["refund:"+rf.OutRefundNo] || (rf.RefundId != "" && seen["refund_id:"+rf.RefundId]) {
+			continue
+		}
+		report.Discrepancies = append(report.Discrepancies, &Discrepancy{
+			Kind:        MissingRemote,
+			IsRefund:    true,
+			OutTradeNo:  rf.OutTradeNo,
+			OutRefundNo: rf.OutRefundNo,
+			RefundId:    rf.RefundId,
+			LocalAmount: rf.Amount,
+			LocalStatus: rf.Status,
+			LocalRefund: rf,
+		})
+	}
+	return
+}
+
+// confirm 查询实时的订单、退款状态确认差异, 账单中存在而本地不存在、金额不一致的差异无法通过查询消除, 因此不会查询
+func (r *Reconciler) confirm(report *Report) {
+	if r.config == nil {
+		return
+	}
+	for _, d := range report.Discrepancies {
+		if d.Kind != MissingRemote && d.Kind != StatusMismatch {
+			continue
+		}
+		if d.IsRefund {
+			r.confirmRefund(d)
+		} else {
+			r.confirmOrder(d)
+		}
+	}
+}
+
+func (r *Reconciler) confirmOrder(d *Discrepancy) {
+	order, err := merchant.QueryOrder(r.config, &merchant.QueryOrderRequest{OutTradeNo: d.OutTradeNo, TransactionId: d.TransactionId})
+	if !confirmed(d, err) {
+		return
+	}
+	d.LiveRequestId, d.LiveStatus = order.Id, order.TradeState
+	if order.Amount != nil {
+		d.LiveAmount = order.Amount.Total
+	}
+	d.Resolved = isPaid(d.LiveStatus) == isPaid(d.LocalStatus) && d.LiveAmount == d.LocalAmount
+}
+
+func (r *Reconciler) confirmRefund(d *Discrepancy) {
+	refund, err := refunds.QueryRefund(r.config, d.OutRefundNo)
+	if !confirmed(d, err) {
+		return
+	}
+	d.LiveRequestId, d.LiveStatus = refund.Id, refund.Status
+	if refund.Amount != nil {
+		d.LiveAmount = refund.Amount.Refund
+	}
+	d.Resolved = d.LiveStatus == d.LocalStatus && d.LiveAmount == d.LocalAmount
+}
+
+// confirmed 处理查询结果, 订单(退款单)不存在同样视为已确认, 返回是否需要继续比较查询结果
+func confirmed(d *Discrepancy, err error) bool {
+	if err == nil {
+		d.Confirmed = true
+		return true
+	}
+	var apiErr *errors.APIError
+	if stderrors.As(err, &apiErr) && (stderrors.Is(err, errors.ErrOrderNotExist) || stderrors.Is(err, errors.ErrResourceNotExists)) {
+		d.Confirmed, d.LiveRequestId = true, apiErr.RequestId
+		return false
+	}
+	d.ConfirmErr = err
+	return false
+}
@@ -0,0 +1,235 @@
+package reconcile
+
+import (
+	"net/http"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/pyihe/wechat-sdk/v3/model"
+	"github.com/pyihe/wechat-sdk/v3/service/payment/merchant"
+	"github.com/pyihe/wechat-sdk/v3/service/refunds"
+	"github.com/pyihe/wechat-sdk/v3/service/wechatpaytest"
+)
+
+const billHeader = "交易时间,公众账号ID,商户号,特约商户号,设备号,微信订单号,商户订单号,用户标识,交易类型,交易状态,付款银行,货币种类,应结订单金额,代金券金额,微信退款单号,商户退款单号,退款金额,充值券退款金额,退款类型,退款状态,商品名称,商户数据包,手续费,费率,订单金额,申请退款金额,费率备注\n"
+
+func paidRow(transactionId, outTradeNo, total string) string {
+	return "`2021-11-30 10:00:00,`wx8888888888888888,`1900000001,`0,`,`" + transactionId + ",`" + outTradeNo +
+		",`oUpF8uMuAJO_M2pxb1Q9zNjWeS6o,`NATIVE,`SUCCESS,`OTHERS,`CNY,`" + total + ",`0.00,`0,`0,`0.00,`0.00,`,`,`QQ公仔,`,`0.00,`0.60%,`" + total + ",`0.00,`\n"
+}
+
+func refundRow(transactionId, outTradeNo, refundId, outRefundNo, refund, status string) string {
+	return "`2021-11-30 12:00:00,`wx8888888888888888,`1900000001,`0,`,`" + transactionId + ",`" + outTradeNo +
+		",`oUpF8uMuAJO_M2pxb1Q9zNjWeS6o,`NATIVE,`REFUND,`OTHERS,`CNY,`0.00,`0.00,`" + refundId + ",`" + outRefundNo +
+		",`" + refund + ",`0.00,`ORIGINAL,`" + status + ",`QQ公仔,`,`0.00,`0.60%,`0.00,`" + refund + ",`\n"
+}
+
+// memoryStore 用于测试的本地订单存储, 所有订单都视为账单日当天的订单
+type memoryStore struct {
+	orders  []*LocalOrder
+	refunds []*LocalRefund
+}
+
+func (m *memoryStore) FindOrder(outTradeNo, transactionId string) (*LocalOrder, error) {
+	for _, o := range m.orders {
+		if o.OutTradeNo == outTradeNo || (transactionId != "" && o.TransactionId == transactionId) {
+			return o, nil
+		}
+	}
+	return nil, nil
+}
+
+func (m *memoryStore) FindRefund(outRefundNo, refundId string) (*LocalRefund, error) {
+	for _, r := range m.refunds {
+		if r.OutRefundNo == outRefundNo || (refundId != "" && r.RefundId == refundId) {
+			return r, nil
+		}
+	}
+	return nil, nil
+}
+
+func (m *memoryStore) ListOrders(start, end time.Time) ([]*LocalOrder, error) {
+	if !start.Equal(time.Date(2021, 11, 29, 16, 0, 0, 0, time.UTC)) || end.Sub(start) != 24*time.Hour {
+		return nil, nil
+	}
+	return m.orders, nil
+}
+
+func (m *memoryStore) ListRefunds(start, end time.Time) ([]*LocalRefund, error) {
+	return m.refunds, nil
+}
+
+func kinds(report *Report) map[string]Kind {
+	result := make(map[string]Kind)
+	for _, d := range report.Discrepancies {
+		key := d.OutTradeNo
+		if d.IsRefund {
+			key = d.OutRefundNo
+		}
+		result[key] = d.Kind
+	}
+	return result
+}
+
+func TestReconcileBill(t *testing.T) {
+	bill := billHeader +
+		paidRow("4200000001", "order-matched", "1.00") +
+		paidRow("4200000002", "order-amount", "2.00") +
+		paidRow("4200000003", "order-notpay", "3.00") +
+		paidRow("4200000004", "order-missing-local", "4.00") +
+		refundRow("4200000001", "order-matched", "5000000001", "refund-status", "0.50", "PROCESSING") +
+		"总交易单数,应结订单总金额,退款总金额,充值券退款总金额,手续费总金额,订单总金额,申请退款总金额\n" +
+		"`5,`10.00,`0.50,`0.00,`0.00,`10.00,`0.50\n"
+	store := &memoryStore{
+		orders: []*LocalOrder{
+			// 本地未记录商户订单号时通过微信订单号匹配
+			{TransactionId: "4200000001", Amount: 100, State: "REFUND"},
+			{OutTradeNo: "order-amount", Amount: 201, State: "SUCCESS"},
+			{OutTradeNo: "order-notpay", Amount: 300, State: "NOTPAY"},
+			{OutTradeNo: "order-missing-remote", Amount: 500, State: "SUCCESS"},
+		},
+		refunds: []*LocalRefund{
+			{OutRefundNo: "refund-status", OutTradeNo: "order-matched", Amount: 50, Status: "SUCCESS"},
+		},
+	}
+
+	report, err := New(nil, store).ReconcileBill("2021-11-30", strings.NewReader(bill))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if report.Orders != 4 || report.Refunds != 1 || report.Matched != 1 || report.Summary.TotalCount != 5 {
+		t.Fatalf("unexpected report: %+v", report)
+	}
+	expected := map[string]Kind{
+		"order-amount":         AmountMismatch,
+		"order-notpay":         StatusMismatch,
+		"order-missing-local":  MissingLocal,
+		"order-missing-remote": MissingRemote,
+		"refund-status":        StatusMismatch,
+	}
+	if got := kinds(report); len(got) != len(expected) || len(report.Discrepancies) != len(expected) {
+		t.Fatalf("unexpected discrepancies: %v", got)
+	} else {
+		for key, kind := range expected {
+			if got[key] != kind {
+				t.Fatalf("%s: expected %s, got %s", key, kind, got[key])
+			}
+		}
+	}
+	for _, d := range report.Discrepancies {
+		if d.Kind == AmountMismatch && (d.LocalAmount != 201 || d.RemoteAmount != 200 || d.Record == nil) {
+			t.Fatalf("unexpected amount mismatch: %+v", d)
+		}
+	}
+	if len(report.Unresolved()) != len(expected) {
+		t.Fatal("discrepancies should not be resolved without auto confirmation")
+	}
+
+	if _, err = New(nil, store).ReconcileBill("20211130", strings.NewReader(bill)); err == nil {
+		t.Fatal("expected invalid bill date error")
+	}
+}
+
+func TestReconcileAutoConfirm(t *testing.T) {
+	server := wechatpaytest.NewServer()
+	defer server.Close()
+	config := server.NewConfig()
+
+	// 跨日支付的订单与处理中的退款: 账单中缺失或者状态不一致, 查询后与本地一致
+	outTradeNo, outRefundNo := "order-cross-day", "refund-processing"
+	for _, no := range []string{outTradeNo, "order-paid"} {
+		_, err := merchant.Native(config, map[string]interface{}{
+			"appid":        server.AppId,
+			"mchid":        server.MchId,
+			"description":  "QQ公仔",
+			"out_trade_no": no,
+			"notify_url":   "https://www.weixin.qq.com/wxpay/pay.php",
+			"amount":       &model.Amount{Total: 100},
+		})
+		if err != nil {
+			t.Fatal(err)
+		}
+		if _, err = server.PayOrder(no); err != nil {
+			t.Fatal(err)
+		}
+	}
+	refundOrder, err := refunds.Refund(config, map[string]interface{}{
+		"out_trade_no":  "order-paid",
+		"out_refund_no": outRefundNo,
+		"amount":        &model.Amount{Refund: 30, Total: 100, Currency: "CNY"},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err = server.CompleteRefund(outRefundNo); err != nil {
+		t.Fatal(err)
+	}
+	paid := server.Order("order-paid")
+
+	bill := billHeader +
+		paidRow(paid["transaction_id"].(string), "order-paid", "1.00") +
+		refundRow(paid["transaction_id"].(string), "order-paid", refundOrder.RefundId, outRefundNo, "0.30", "PROCESSING") +
+		"总交易单数,应结订单总金额,退款总金额,充值券退款总金额,手续费总金额,订单总金额,申请退款总金额\n" +
+		"`2,`1.00,`0.30,`0.00,`0.00,`1.00,`0.30\n"
+	server.HandleFunc(http.MethodGet, "/v3/bill/tradebill", func(r *http.Request, body []byte) (int, interface{}) {
+		if r.URL.Query().Get("bill_type") != "ALL" {
+			return http.StatusBadRequest, wechatpaytest.Error("PARAM_ERROR", "bill_type错误")
+		}
+		if r.URL.Query().Get("bill_date") != "2021-11-30" {
+			return http.StatusBadRequest, wechatpaytest.Error("NO_STATEMENT_EXIST", "账单文件不存在")
+		}
+		return http.StatusOK, map[string]string{"download_url": server.URL + "/v3/billdownload/file?token=trade"}
+	})
+	server.HandleFunc(http.MethodGet, "/v3/billdownload/file", func(r *http.Request, body []byte) (int, interface{}) {
+		return http.StatusOK, []byte(bill)
+	})
+
+	store := &memoryStore{
+		orders: []*LocalOrder{
+			{OutTradeNo: "order-paid", Amount: 100, State: "REFUND"},
+			{OutTradeNo: outTradeNo, Amount: 100, State: "SUCCESS"},
+			// 本地已支付但微信不存在的订单
+			{OutTradeNo: "order-phantom", Amount: 100, State: "SUCCESS"},
+		},
+		refunds: []*LocalRefund{
+			{OutRefundNo: outRefundNo, OutTradeNo: "order-paid", Amount: 30, Status: "SUCCESS"},
+		},
+	}
+	report, err := New(config, store, WithAutoConfirm()).Reconcile("2021-11-30")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(report.Discrepancies) != 3 || report.Matched != 1 {
+		t.Fatalf("unexpected report: %+v", report)
+	}
+	for _, d := range report.Discrepancies {
+		if !d.Confirmed || d.ConfirmErr != nil || d.LiveRequestId == "" {
+			t.Fatalf("discrepancy should be confirmed: %+v", d)
+		}
+		switch {
+		case d.OutRefundNo == outRefundNo:
+			if d.Kind != StatusMismatch || !d.Resolved || d.LiveStatus != "SUCCESS" || d.LiveAmount != 30 {
+				t.Fatalf("unexpected refund discrepancy: %+v", d)
+			}
+		case d.OutTradeNo == outTradeNo:
+			if d.Kind != MissingRemote || !d.Resolved || d.LiveStatus != "SUCCESS" {
+				t.Fatalf("unexpected cross-day discrepancy: %+v", d)
+			}
+		case d.OutTradeNo == "order-phantom":
+			if d.Kind != MissingRemote || d.Resolved || d.LiveStatus != "" {
+				t.Fatalf("unexpected phantom discrepancy: %+v", d)
+			}
+		default:
+			t.Fatalf("unexpected discrepancy: %+v", d)
+		}
+	}
+	if unresolved := report.Unresolved(); len(unresolved) != 1 || unresolved[0].OutTradeNo != "order-phantom" {
+		t.Fatalf("unexpected unresolved discrepancies: %+v", unresolved)
+	}
+
+	// 申请账单失败时返回错误
+	if report, err = New(config, store).Reconcile("2021-12-01"); err == nil || report != nil {
+		t.Fatalf("expected NO_STATEMENT_EXIST, got: %v", err)
+	}
+}
@@ -1,11 +1,101 @@
 package refunds
 
 import (
+	"fmt"
 	"time"
 
 	"github.com/pyihe/wechat-sdk/v3/model"
+	"github.com/pyihe/wechat-sdk/v3/pkg/validate"
 )
 
+// RefundRequest 申请退款请求参数, transaction_id与out_trade_no二选一
+type RefundRequest struct {
+	SubMchId      string               `json:"sub_mchid,omitempty"`      // 子商户号, 服务商平台必填
+	TransactionId string               `json:"transaction_id,omitempty"` // 微信支付订单号
+	OutTradeNo    string               `json:"out_trade_no,omitempty"`   // 商户订单号
+	OutRefundNo   string               `json:"out_refund_no"`            // 商户退款单号
+	Reason        string               `json:"reason,omitempty"`         // 退款原因
+	NotifyUrl     string               `json:"notify_url,omitempty"`     // 退款结果回调地址
+	FundsAccount  string               `json:"funds_account,omitempty"`  // 退款资金来源, 仅支持: AVAILABLE
+	Amount        *RefundAmount        `json:"amount"`                   // 金额信息
+	GoodsDetail   []*model.GoodsDetail `json:"goods_detail,omitempty"`   // 退款商品
+}
+
+func (r *RefundRequest) Validate() (err error) {
+	if r.TransactionId == "" && r.OutTradeNo == "" {
+		return validate.Required("transaction_id或out_trade_no", "")
+	}
+	err = validate.First(
+		validate.MaxLength("sub_mchid", r.SubMchId, 32),
+		validate.MaxLength("transaction_id", r.TransactionId, 32),
+		validate.Length("out_trade_no", r.OutTradeNo, 6, 32),
+		validate.Required("out_refund_no", r.OutRefundNo),
+		validate.MaxLength("out_refund_no", r.OutRefundNo, 64),
+		validate.MaxLength("reason", r.Reason, 80),
+		validate.NotifyUrl("notify_url", r.NotifyUrl),
+		validate.OneOf("funds_account", r.FundsAccount, "AVAILABLE"),
+		validate.Required("amount", r.Amount),
+		validate.Nested("amount", r.Amount),
+	)
+	if err != nil {
+		return
+	}
+	for i, goods := range r.GoodsDetail {
+		field := fmt.Sprintf("goods_detail[%d]", i)
+		if err = validate.Required(field, goods); err != nil {
+			return
+		}
+		err = validate.First(
+			validate.Required(field+".merchant_goods_id", goods.MerchantGoodsId),
+			validate.MaxLength(field+".merchant_goods_id", goods.MerchantGoodsId, 32),
+			validate.MaxLength(field+".wechatpay_goods_id", goods.WechatpayGoodsId, 32),
+			validate.MaxLength(field+".goods_name", goods.GoodsName, 256),
+			validate.Min(field+".unit_price", goods.UnitPrice, 0),
+			validate.Min(field+".refund_amount", goods.RefundAmount, 0),
+			validate.Min(field+".refund_quantity", int64(goods.RefundQuantity), 1),
+		)
+		if err != nil {
+			return
+		}
+	}
+	return
+}
+
+// RefundAmount 退款金额信息
+type RefundAmount struct {
+	Refund   int64         `json:"refund"`         // 退款金额, 单位为分, 不能超过原订单支付金额
+	From     []*model.From `json:"from,omitempty"` // 退款出资账户及金额
+	Total    int64         `json:"total"`          // 原订单金额
+	Currency string        `json:"currency"`       // 退款币种, 目前只支持人民币: CNY
+}
+
+func (a *RefundAmount) Validate() (err error) {
+	err = validate.First(
+		validate.Min("total", a.Total, 1),
+		validate.Range("refund", a.Refund, 1, a.Total),
+		validate.Required("currency", a.Currency),
+		validate.OneOf("currency", a.Currency, "CNY"),
+	)
+	if err != nil {
+		return
+	}
+	for i, from := range a.From {
+		field := fmt.Sprintf("from[%d]", i)
+		if err = validate.Required(field, from); err != nil {
+			return
+		}
+		err = validate.First(
+			validate.Required(field+".account", from.Account),
+			validate.OneOf(field+".account", from.Account, "AVAILABLE", "UNAVAILABLE"),
+			validate.Min(field+".amount", from.Amount, 1),
+		)
+		if err != nil {
+			return
+		}
+	}
+	return
+}
+
 // RefundOrder 微信支付退款API应答
 type RefundOrder struct {
 	Id                  string                   `json:"-"`                               // id，请求或者通知的唯一ID
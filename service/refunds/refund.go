@@ -13,6 +13,7 @@ import (
 // 商户平台合单退款API文档: https://pay.weixin.qq.com/wiki/doc/apiv3/apis/chapter5_1_14.shtml
 // 服务商平台退款API文档: https://pay.weixin.qq.com/wiki/doc/apiv3_partner/apis/chapter4_1_9.shtml
 // 服务商平台合单退款API文档: https://pay.weixin.qq.com/wiki/doc/apiv3_partner/apis/chapter5_1_14.shtml
+// request: *RefundRequest(签名前会校验参数)或者map等可序列化的参数
 func Refund(config *service.Config, request interface{}) (refundOrder *RefundOrder, err error) {
 	if config == nil {
 		err = errors.ErrNoConfig
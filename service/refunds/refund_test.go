@@ -0,0 +1,68 @@
+package refunds
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/pyihe/wechat-sdk/v3/model"
+	sdkerrors "github.com/pyihe/wechat-sdk/v3/pkg/errors"
+	"github.com/pyihe/wechat-sdk/v3/service/payment/merchant"
+	"github.com/pyihe/wechat-sdk/v3/service/tests"
+)
+
+func TestRefundFlow(t *testing.T) {
+	outTradeNo, outRefundNo := "1217752501201407033233368019", "1217752501201407033233368020"
+	_, err := merchant.Native(tests.Config, map[string]interface{}{
+		"appid":        tests.Server.AppId,
+		"mchid":        tests.Server.MchId,
+		"description":  "Image形象店-深圳腾大-QQ公仔",
+		"out_trade_no": outTradeNo,
+		"notify_url":   "https://www.weixin.qq.com/wxpay/pay.php",
+		"amount":       &model.Amount{Total: 100},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err = tests.Server.PayOrder(outTradeNo); err != nil {
+		t.Fatal(err)
+	}
+
+	request := map[string]interface{}{
+		"out_trade_no":  outTradeNo,
+		"out_refund_no": outRefundNo,
+		"amount":        &model.Amount{Refund: 60, Total: 100, Currency: "CNY"},
+	}
+	refundOrder, err := Refund(tests.Config, request)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if refundOrder.Status != "PROCESSING" || refundOrder.Amount.Refund != 60 {
+		t.Fatalf("unexpected refund: %+v", refundOrder)
+	}
+
+	// 超出可退金额
+	request["out_refund_no"] = outRefundNo + "1"
+	if _, err = Refund(tests.Config, request); !errors.Is(err, sdkerrors.ErrNotEnough) {
+		t.Fatalf("expected NOT_ENOUGH, got: %v", err)
+	}
+
+	notify, err := tests.Server.CompleteRefund(outRefundNo)
+	if err != nil {
+		t.Fatal(err)
+	}
+	refunded, err := ParseRefundNotify(tests.Config, notify)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if refunded.RefundStatus != "SUCCESS" || refunded.RefundId != refundOrder.RefundId {
+		t.Fatalf("unexpected notify: %+v", refunded)
+	}
+
+	refundOrder, err = QueryRefund(tests.Config, outRefundNo)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if refundOrder.Status != "SUCCESS" {
+		t.Fatalf("unexpected refund: %+v", refundOrder)
+	}
+}
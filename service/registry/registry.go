@@ -0,0 +1,266 @@
+package registry
+
+import (
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"sort"
+	"sync"
+
+	"github.com/pyihe/wechat-sdk/v3/pkg/errors"
+	"github.com/pyihe/wechat-sdk/v3/service"
+	"github.com/pyihe/wechat-sdk/v3/service/certificate"
+)
+
+// Source 商户凭证来源, 用于按需加载商户的配置
+type Source interface {
+	// Load 加载商户号mchId对应的配置项, 如私钥、证书序列号、APIv3密钥等, 商户号本身无需返回
+	Load(mchId string) ([]service.Option, error)
+}
+
+// SourceFunc 函数形式的Source
+type SourceFunc func(mchId string) ([]service.Option, error)
+
+// Load 实现Source
+func (fn SourceFunc) Load(mchId string) ([]service.Option, error) {
+	return fn(mchId)
+}
+
+// Option Registry配置项
+type Option func(*Registry)
+
+// WithHttpClient 设置所有商户共用的http client
+func WithHttpClient(client *http.Client) Option {
+	return func(r *Registry) {
+		r.httpClient = client
+	}
+}
+
+// WithCertificateManager 设置所有商户共用的证书管理器, 商户配置加载后会添加到管理器中并立即下载平台证书
+func WithCertificateManager(manager *certificate.Manager) Option {
+	return func(r *Registry) {
+		r.manager = manager
+	}
+}
+
+// WithSharedOptions 设置所有商户共用的配置项, 如重试策略、中间件等, 商户自己的配置项会覆盖共用配置项
+func WithSharedOptions(opts ...service.Option) Option {
+	return func(r *Registry) {
+		r.sharedOpts = append(r.sharedOpts, opts...)
+	}
+}
+
+// Registry 多商户配置管理, 以商户号为key管理多个*service.Config
+type Registry struct {
+	source     Source
+	httpClient *http.Client
+	manager    *certificate.Manager
+	sharedOpts []service.Option
+
+	mu      sync.RWMutex
+	configs map[string]*service.Config
+	loading map[string]*loadCall
+}
+
+// loadCall 正在进行中的加载, 用于避免并发加载同一个商户
+type loadCall struct {
+	done   chan struct{}
+	config *service.Config
+	err    error
+}
+
+// New 创建Registry, source为nil时只能通过Add添加商户配置
+func New(source Source, opts ...Option) *Registry {
+	r := &Registry{
+		source:  source,
+		configs: make(map[string]*service.Config),
+		loading: make(map[string]*loadCall),
+	}
+	for _, op := range opts {
+		op(r)
+	}
+	return r
+}
+
+// Add 添加已经初始化好的商户配置, 如果设置了证书管理器, 会同时添加到证书管理器中, 并移除被替换的同一商户的旧配置
+func (r *Registry) Add(config *service.Config) (err error) {
+	if config == nil {
+		return errors.ErrNoConfig
+	}
+	if config.GetMchId() == "" {
+		return errors.ErrNoMchId
+	}
+	if r.manager != nil {
+		if err = r.manager.Add(config); err != nil {
+			return
+		}
+	}
+	r.mu.Lock()
+	old := r.configs[config.GetMchId()]
+	r.configs[config.GetMchId()] = config
+	r.mu.Unlock()
+	if old != nil && old != config && r.manager != nil {
+		r.manager.Remove(old)
+	}
+	return
+}
+
+// Remove 移除商户配置, 如果设置了证书管理器, 会同时从证书管理器中移除
+func (r *Registry) Remove(mchId string) {
+	r.mu.Lock()
+	config := r.configs[mchId]
+	delete(r.configs, mchId)
+	r.mu.Unlock()
+	if config != nil && r.manager != nil {
+		r.manager.Remove(config)
+	}
+}
+
+// MchIds 返回已经加载的商户号
+func (r *Registry) MchIds() []string {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	mchIds := make([]string, 0, len(r.configs))
+	for mchId := range r.configs {
+		mchIds = append(mchIds, mchId)
+	}
+	sort.Strings(mchIds)
+	return mchIds
+}
+
+// Get 获取商户号对应的配置, 尚未加载时通过Source加载
+func (r *Registry) Get(mchId string) (config *service.Config, err error) {
+	if mchId == "" {
+		return nil, errors.ErrNoMchId
+	}
+	r.mu.RLock()
+	config = r.configs[mchId]
+	r.mu.RUnlock()
+	if config != nil {
+		return
+	}
+
+	r.mu.Lock()
+	if config = r.configs[mchId]; config != nil {
+		r.mu.Unlock()
+		return
+	}
+	call, ok := r.loading[mchId]
+	if ok {
+		r.mu.Unlock()
+		<-call.done
+		return call.config, call.err
+	}
+	call = &loadCall{done: make(chan struct{})}
+	r.loading[mchId] = call
+	r.mu.Unlock()
+
+	call.config, call.err = r.load(mchId)
+
+	r.mu.Lock()
+	delete(r.loading, mchId)
+	if call.err == nil {
+		r.configs[mchId] = call.config
+	}
+	r.mu.Unlock()
+	close(call.done)
+	return call.config, call.err
+}
+
+// load 通过Source加载商户配置
+func (r *Registry) load(mchId string) (config *service.Config, err error) {
+	if r.source == nil {
+		return nil, fmt.Errorf("商户[%s]的配置不存在", mchId)
+	}
+	mchOpts, err := r.source.Load(mchId)
+	if err != nil {
+		return
+	}
+	opts := make([]service.Option, 0, len(r.sharedOpts)+len(mchOpts)+2)
+	opts = append(opts, service.WithMchId(mchId))
+	if r.httpClient != nil {
+		opts = append(opts, service.WithHttpClient(r.httpClient))
+	}
+	opts = append(opts, r.sharedOpts...)
+	opts = append(opts, mchOpts...)
+	if config, err = service.NewConfigE(opts...); err != nil {
+		return nil, err
+	}
+	if r.manager != nil {
+		if err = r.manager.Add(config); err != nil {
+			return nil, err
+		}
+	}
+	return
+}
+
+// ParseWechatNotify 验证并解密微信通知, 同时找到通知所属商户的配置
+// mchIds: 可能接收该通知的商户号, 如从回调URL中得到的商户号, 为空时依次尝试所有已经加载的商户配置
+// 只有签名验证、解密都成功, 并且解密后的mchid或sp_mchid(如果有的话)与商户号一致的配置才会被选中,
+// 防重放校验只在选中的配置上进行, 与service.Config.ParseWechatNotify相同, 只校验时间戳并记录随机串, 不记录通知ID
+func (r *Registry) ParseWechatNotify(request *http.Request, dst interface{}, mchIds ...string) (config *service.Config, notifyId string, err error) {
+	if request == nil {
+		err = errors.ErrNoHttpRequest
+		return
+	}
+	body, err := ioutil.ReadAll(request.Body)
+	if err != nil {
+		return
+	}
+	_ = request.Body.Close()
+
+	if len(mchIds) == 0 {
+		mchIds = r.MchIds()
+	}
+	if len(mchIds) == 0 {
+		err = errors.ErrNoConfig
+		return
+	}
+
+	var lastErr error
+	for _, mchId := range mchIds {
+		var candidate *service.Config
+		if candidate, lastErr = r.Get(mchId); lastErr != nil {
+			continue
+		}
+		// 先确认通知归属再记录随机串以及通知ID, 避免共用APIv3密钥以及NonceStore时被其他商户的配置消耗
+		notifyResponse, plainData, e := candidate.VerifyWechatNotify(request.Header, body)
+		if e != nil {
+			lastErr = e
+			continue
+		}
+		if !belongsTo(plainData, mchId) {
+			lastErr = fmt.Errorf("解析微信通知失败: 通知不属于商户[%s]", mchId)
+			continue
+		}
+		config, notifyId = candidate, notifyResponse.Id
+		if err = candidate.CheckNotifyNonce(request.Header); err != nil {
+			return
+		}
+		if dst == nil {
+			return
+		}
+		if err = json.Unmarshal(plainData, dst); err != nil {
+			candidate.ForgetNotify(request.Header, notifyId)
+		}
+		return
+	}
+	err = lastErr
+	return
+}
+
+// belongsTo 判断解密后的通知数据是否属于商户mchId
+func belongsTo(plainData []byte, mchId string) bool {
+	var owner struct {
+		MchId   string `json:"mchid"`
+		SpMchId string `json:"sp_mchid"`
+	}
+	if err := json.Unmarshal(plainData, &owner); err != nil {
+		return false
+	}
+	if owner.MchId == "" && owner.SpMchId == "" {
+		return true
+	}
+	return owner.MchId == mchId || owner.SpMchId == mchId
+}
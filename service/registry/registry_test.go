@@ -0,0 +1,202 @@
+package registry
+
+import (
+	"bytes"
+	"crypto"
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/pyihe/wechat-sdk/v3/service"
+	"github.com/pyihe/wechat-sdk/v3/service/certificate"
+	"github.com/pyihe/wechat-sdk/v3/service/wechatpaytest"
+)
+
+const platformKeyId = "PUB_KEY_ID_0114232134912410000000000000"
+
+var apiKeys = map[string]string{
+	"1900000001": "11111111111111111111111111111111",
+	"1900000002": "22222222222222222222222222222222",
+}
+
+func newNotifyRequest(t *testing.T, platformKey *rsa.PrivateKey, apiKey string, resource interface{}) *http.Request {
+	plainText, _ := json.Marshal(resource)
+	block, _ := aes.NewCipher([]byte(apiKey))
+	gcm, _ := cipher.NewGCM(block)
+	nonce := "fdasflkja484"
+	cipherText := gcm.Seal(nil, []byte(nonce), plainText, []byte("transaction"))
+	body, _ := json.Marshal(map[string]interface{}{
+		"id":            "EV-2018022511223320873",
+		"create_time":   "2015-05-20T13:29:35+08:00",
+		"resource_type": "encrypt-resource",
+		"event_type":    "TRANSACTION.SUCCESS",
+		"resource": map[string]string{
+			"algorithm":       "AEAD_AES_256_GCM",
+			"ciphertext":      base64.StdEncoding.EncodeToString(cipherText),
+			"associated_data": "transaction",
+			"nonce":           nonce,
+		},
+	})
+
+	timestamp := fmt.Sprintf("%d", time.Now().Unix())
+	hashed := sha256.Sum256([]byte(fmt.Sprintf("%s\n%s\n%s\n", timestamp, nonce, body)))
+	signature, err := rsa.SignPKCS1v15(rand.Reader, platformKey, crypto.SHA256, hashed[:])
+	if err != nil {
+		t.Fatal(err)
+	}
+	request := httptest.NewRequest(http.MethodPost, "/notify", bytes.NewReader(body))
+	request.Header.Set("Wechatpay-Serial", platformKeyId)
+	request.Header.Set("Wechatpay-Timestamp", timestamp)
+	request.Header.Set("Wechatpay-Nonce", nonce)
+	request.Header.Set("Wechatpay-Signature", base64.StdEncoding.EncodeToString(signature))
+	return request
+}
+
+func TestRegistry(t *testing.T) {
+	platformKey, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var mu sync.Mutex
+	var loads = make(map[string]int)
+	source := SourceFunc(func(mchId string) ([]service.Option, error) {
+		mu.Lock()
+		loads[mchId]++
+		mu.Unlock()
+		apiKey, ok := apiKeys[mchId]
+		if !ok {
+			return nil, fmt.Errorf("unknown mchid: %s", mchId)
+		}
+		return []service.Option{service.WithApiV3Key(apiKey)}, nil
+	})
+	client := &http.Client{Timeout: time.Second}
+	r := New(source, WithHttpClient(client))
+
+	var wg sync.WaitGroup
+	for i := 0; i < 10; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			if _, err := r.Get("1900000001"); err != nil {
+				t.Error(err)
+			}
+		}()
+	}
+	wg.Wait()
+	if loads["1900000001"] != 1 {
+		t.Fatalf("config should be loaded once, got %d", loads["1900000001"])
+	}
+	if _, err = r.Get("1900000009"); err == nil {
+		t.Fatalf("unknown merchant should fail to load")
+	}
+
+	for mchId := range apiKeys {
+		config, err := r.Get(mchId)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if config.GetHTTPClient() != client || config.GetMchId() != mchId {
+			t.Fatalf("unexpected config for %s", mchId)
+		}
+		config.AddPublicKey(platformKeyId, &platformKey.PublicKey)
+	}
+
+	var order struct {
+		MchId      string `json:"mchid"`
+		OutTradeNo string `json:"out_trade_no"`
+	}
+	resource := map[string]string{"mchid": "1900000002", "out_trade_no": "1217752501201407033233368018"}
+	request := newNotifyRequest(t, platformKey, apiKeys["1900000002"], resource)
+	config, notifyId, err := r.ParseWechatNotify(request, &order)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if config.GetMchId() != "1900000002" || notifyId != "EV-2018022511223320873" || order.OutTradeNo != resource["out_trade_no"] {
+		t.Fatalf("unexpected notify: %s %s %+v", config.GetMchId(), notifyId, order)
+	}
+
+	// 通知中的商户号与解密成功的商户配置不一致
+	resource["mchid"] = "1900000003"
+	request = newNotifyRequest(t, platformKey, apiKeys["1900000002"], resource)
+	if _, _, err = r.ParseWechatNotify(request, &order, "1900000002"); err == nil {
+		t.Fatalf("notify of another merchant should be rejected")
+	}
+}
+
+func TestRegistrySharedApiKey(t *testing.T) {
+	platformKey, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatal(err)
+	}
+	// 两个商户共用APIv3密钥以及NonceStore
+	const apiKey = "33333333333333333333333333333333"
+	source := SourceFunc(func(mchId string) ([]service.Option, error) {
+		return []service.Option{service.WithApiV3Key(apiKey)}, nil
+	})
+	r := New(source, WithSharedOptions(service.WithNonceStore(service.NewMemoryNonceStore(0))))
+	for _, mchId := range []string{"1900000001", "1900000002"} {
+		config, err := r.Get(mchId)
+		if err != nil {
+			t.Fatal(err)
+		}
+		config.AddPublicKey(platformKeyId, &platformKey.PublicKey)
+	}
+
+	// 先尝试的商户可以解密但通知不属于它, 不能消耗通知的随机串
+	request := newNotifyRequest(t, platformKey, apiKey, map[string]string{"mchid": "1900000002"})
+	config, notifyId, err := r.ParseWechatNotify(request, nil, "1900000001", "1900000002")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if config.GetMchId() != "1900000002" || notifyId == "" {
+		t.Fatalf("unexpected config: %s", config.GetMchId())
+	}
+}
+
+func TestRegistryLoadError(t *testing.T) {
+	source := SourceFunc(func(mchId string) ([]service.Option, error) {
+		return []service.Option{service.WithPrivateKeyFrom(service.FromString("invalid private key"))}, nil
+	})
+	r := New(source)
+	if _, err := r.Get("1900000001"); err == nil {
+		t.Fatal("invalid private key should fail to load")
+	}
+	if len(r.MchIds()) != 0 {
+		t.Fatalf("unexpected merchants: %v", r.MchIds())
+	}
+}
+
+func TestRegistryRemove(t *testing.T) {
+	server := wechatpaytest.NewServer()
+	defer server.Close()
+
+	manager := certificate.NewManager(nil, certificate.WithMinRefreshInterval(0))
+	r := New(SourceFunc(func(mchId string) ([]service.Option, error) {
+		return server.MerchantOptions(), nil
+	}), WithCertificateManager(manager))
+	config, err := r.Get(server.MchId)
+	if err != nil {
+		t.Fatal(err)
+	}
+	r.Remove(server.MchId)
+
+	// 移除后证书管理器不再为其下载轮换后的证书
+	server.RotatePlatformCertificate()
+	response, err := config.RequestWithSign(http.MethodGet, "/v3/certificates", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err = config.ParseWechatResponse(response, &struct{}{}); err == nil {
+		t.Fatal("removed config should not refresh certificates")
+	}
+}
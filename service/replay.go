@@ -0,0 +1,226 @@
+package service
+
+import (
+	"container/list"
+	"net/http"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/pyihe/wechat-sdk/v3/pkg/errors"
+)
+
+const (
+	// DefaultClockSkew 默认允许的Wechatpay-Timestamp与本地时间的最大误差
+	DefaultClockSkew = 5 * time.Minute
+
+	// 默认内存NonceStore的容量
+	defaultNonceCapacity = 10000
+
+	// 默认记录通知ID的内存NonceStore的容量
+	defaultNotifyCapacity = 100000
+
+	// 已处理通知ID的保存时间, 微信对同一通知的重试持续约24小时
+	notifyIdTTL = 25 * time.Hour
+
+	// 处理中通知ID的保存时间, 调用方既未AckNotify也未ForgetNotify(如进程崩溃)时, 超时后微信重发的通知可以再次被处理
+	notifyProcessingTTL = time.Minute
+)
+
+// NonceStore 保存已使用的Wechatpay-Nonce以及通知ID, 用于防止重放, 多实例部署时可以基于Redis等实现
+type NonceStore interface {
+	// Add 记录key, ttl后过期, key已存在且未过期时返回false
+	Add(key string, ttl time.Duration) (added bool, err error)
+	// Exists key存在且未过期时返回true
+	Exists(key string) (exists bool, err error)
+	// Remove 删除key
+	Remove(key string) error
+}
+
+// WithClockSkew 设置应答、通知的Wechatpay-Timestamp与本地时间允许的最大误差, 默认5分钟, 小于等于0时不校验
+func WithClockSkew(skew time.Duration) Option {
+	return func(config *Config) {
+		config.clockSkew = skew
+	}
+}
+
+// WithNonceStore 设置记录Wechatpay-Nonce的NonceStore, 默认为容量10000的内存LRU, 为nil时不校验重放
+// 未通过WithNotifyStore单独设置时, store同时用于记录通知ID
+func WithNonceStore(store NonceStore) Option {
+	return func(config *Config) {
+		config.nonceStore = store
+		if !config.customNotifyStore {
+			config.notifyStore = store
+		}
+	}
+}
+
+// WithNotifyStore 单独设置记录通知ID的NonceStore, 默认为容量100000的内存LRU, 为nil时不校验重复通知
+func WithNotifyStore(store NonceStore) Option {
+	return func(config *Config) {
+		config.notifyStore = store
+		config.customNotifyStore = true
+	}
+}
+
+// AckNotify 通知处理成功后调用, 将通知ID标记为已处理, 之后微信重发的同一通知会返回ErrDuplicateNotify
+func (c *Config) AckNotify(notifyId string) (err error) {
+	if c.notifyStore == nil || notifyId == "" {
+		return
+	}
+	// 先标记已处理再删除处理中标记, 保证并发的重发通知总能看到其中之一
+	if _, err = c.notifyStore.Add("notify:"+notifyId, notifyIdTTL); err != nil {
+		return
+	}
+	err = c.notifyStore.Remove("notify-processing:" + notifyId)
+	return
+}
+
+// ForgetNotify 通知处理失败时调用, 删除通知的随机串以及通知ID记录, 以便微信重新发送的通知可以再次被处理
+func (c *Config) ForgetNotify(header http.Header, notifyId string) {
+	if nonce := header.Get("Wechatpay-Nonce"); nonce != "" && c.nonceStore != nil {
+		_ = c.nonceStore.Remove("nonce:" + nonce)
+	}
+	if notifyId != "" && c.notifyStore != nil {
+		_ = c.notifyStore.Remove("notify-processing:" + notifyId)
+		_ = c.notifyStore.Remove("notify:" + notifyId)
+	}
+}
+
+// CheckNotifyNonce 记录通知的随机串, 重放的通知返回ErrReplayedNonce, 不记录通知ID
+func (c *Config) CheckNotifyNonce(header http.Header) error {
+	return c.checkNonce(header)
+}
+
+// CheckNotifyReplay 记录通知的随机串, 并将通知ID标记为处理中, 重放的通知返回ErrReplayedNonce,
+// 正在处理中的通知返回ErrNotifyProcessing, 已处理的通知返回ErrDuplicateNotify;
+// 调用后处理成功时必须调用AckNotify, 处理失败时必须调用ForgetNotify
+func (c *Config) CheckNotifyReplay(header http.Header, notifyId string) (err error) {
+	if err = c.checkNonce(header); err != nil {
+		return
+	}
+	err = c.checkNotifyId(notifyId)
+	return
+}
+
+// checkTimestamp 校验Wechatpay-Timestamp是否在允许的误差范围内
+func (c *Config) checkTimestamp(header http.Header) error {
+	if c.clockSkew <= 0 {
+		return nil
+	}
+	timestamp, err := strconv.ParseInt(header.Get("Wechatpay-Timestamp"), 10, 64)
+	if err != nil {
+		return errors.ErrTimestampExpired
+	}
+	if d := time.Since(time.Unix(timestamp, 0)); d > c.clockSkew || d < -c.clockSkew {
+		return errors.ErrTimestampExpired
+	}
+	return nil
+}
+
+// checkNonce 记录Wechatpay-Nonce, 已使用过时返回ErrReplayedNonce
+func (c *Config) checkNonce(header http.Header) error {
+	if c.nonceStore == nil {
+		return nil
+	}
+	// 超出时间误差的重放会被checkTimestamp拦截, 因此只需要保存2倍的误差时间
+	ttl := 2 * c.clockSkew
+	if ttl <= 0 {
+		ttl = 2 * DefaultClockSkew
+	}
+	added, err := c.nonceStore.Add("nonce:"+header.Get("Wechatpay-Nonce"), ttl)
+	if err != nil {
+		return err
+	}
+	if !added {
+		return errors.ErrReplayedNonce
+	}
+	return nil
+}
+
+// checkNotifyId 将通知ID标记为处理中, 已处理过时返回ErrDuplicateNotify, 正在处理中时返回ErrNotifyProcessing
+func (c *Config) checkNotifyId(notifyId string) error {
+	if c.notifyStore == nil || notifyId == "" {
+		return nil
+	}
+	added, err := c.notifyStore.Add("notify-processing:"+notifyId, notifyProcessingTTL)
+	if err != nil {
+		return err
+	}
+	if !added {
+		return errors.ErrNotifyProcessing
+	}
+	// 标记处理中之后再检查是否已处理, 与AckNotify的顺序相反, 避免并发时重复处理
+	done, err := c.notifyStore.Exists("notify:" + notifyId)
+	if err == nil && done {
+		err = errors.ErrDuplicateNotify
+	}
+	if err != nil {
+		_ = c.notifyStore.Remove("notify-processing:" + notifyId)
+	}
+	return err
+}
+
+// memoryNonceStore 基于LRU的内存NonceStore, 超出容量时淘汰最早的记录
+type memoryNonceStore struct {
+	mu       sync.Mutex
+	capacity int
+	items    map[string]*list.Element
+	order    *list.List
+}
+
+type nonceEntry struct {
+	key      string
+	expireAt time.Time
+}
+
+// NewMemoryNonceStore 创建容量为capacity的内存NonceStore
+func NewMemoryNonceStore(capacity int) NonceStore {
+	if capacity <= 0 {
+		capacity = defaultNonceCapacity
+	}
+	return &memoryNonceStore{
+		capacity: capacity,
+		items:    make(map[string]*list.Element),
+		order:    list.New(),
+	}
+}
+
+func (s *memoryNonceStore) Add(key string, ttl time.Duration) (bool, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	now := time.Now()
+	if element, ok := s.items[key]; ok {
+		entry := element.Value.(*nonceEntry)
+		if now.Before(entry.expireAt) {
+			return false, nil
+		}
+		s.order.Remove(element)
+		delete(s.items, key)
+	}
+	for s.order.Len() >= s.capacity {
+		oldest := s.order.Back()
+		s.order.Remove(oldest)
+		delete(s.items, oldest.Value.(*nonceEntry).key)
+	}
+	s.items[key] = s.order.PushFront(&nonceEntry{key: key, expireAt: now.Add(ttl)})
+	return true, nil
+}
+
+func (s *memoryNonceStore) Exists(key string) (bool, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	element, ok := s.items[key]
+	return ok && time.Now().Before(element.Value.(*nonceEntry).expireAt), nil
+}
+
+func (s *memoryNonceStore) Remove(key string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if element, ok := s.items[key]; ok {
+		s.order.Remove(element)
+		delete(s.items, key)
+	}
+	return nil
+}
@@ -0,0 +1,182 @@
+package service
+
+import (
+	"bytes"
+	"crypto"
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/pem"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/pyihe/wechat-sdk/v3/pkg/errors"
+)
+
+const testApiV3Key = "a7cde1ZJB1kG2e7VfTs3jQzaWizur8Gb"
+
+// newTestNotify 构造使用privateKey签名的通知
+func newTestNotify(t *testing.T, privateKey *rsa.PrivateKey, serialNo, notifyId, nonce string, timestamp time.Time) (http.Header, []byte) {
+	block, _ := aes.NewCipher([]byte(testApiV3Key))
+	gcm, _ := cipher.NewGCM(block)
+	cipherText := gcm.Seal(nil, []byte("fdasflkja484"), []byte(`{"out_trade_no":"1217752501201407033233368018"}`), []byte("transaction"))
+	body := []byte(fmt.Sprintf(`{"id":"%s","event_type":"TRANSACTION.SUCCESS","resource_type":"encrypt-resource","resource":{"algorithm":"AEAD_AES_256_GCM","ciphertext":"%s","associated_data":"transaction","nonce":"fdasflkja484"}}`,
+		notifyId, base64.StdEncoding.EncodeToString(cipherText)))
+
+	ts := fmt.Sprintf("%d", timestamp.Unix())
+	hashed := sha256.Sum256([]byte(fmt.Sprintf("%s\n%s\n%s\n", ts, nonce, body)))
+	signature, err := rsa.SignPKCS1v15(rand.Reader, privateKey, crypto.SHA256, hashed[:])
+	if err != nil {
+		t.Fatal(err)
+	}
+	header := make(http.Header)
+	header.Set("Wechatpay-Serial", serialNo)
+	header.Set("Wechatpay-Timestamp", ts)
+	header.Set("Wechatpay-Nonce", nonce)
+	header.Set("Wechatpay-Signature", base64.StdEncoding.EncodeToString(signature))
+	return header, body
+}
+
+func TestReplayProtection(t *testing.T) {
+	const keyId = "PUB_KEY_ID_0114232134912410000000000000"
+	platformKey, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatal(err)
+	}
+	der, err := x509.MarshalPKIXPublicKey(&platformKey.PublicKey)
+	if err != nil {
+		t.Fatal(err)
+	}
+	publicKeyPEM := string(pem.EncodeToMemory(&pem.Block{Type: "PUBLIC KEY", Bytes: der}))
+
+	// 应答: 相同的Wechatpay-Nonce只能使用一次
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		signWechatResponse(t, w, platformKey, keyId, []byte(`{"trade_state":"SUCCESS"}`))
+	}))
+	defer server.Close()
+	config := newTestConfig(t, server.URL, WithPlatformPublicKey(keyId, publicKeyPEM), WithApiV3Key(testApiV3Key))
+	for i, expected := range []error{nil, errors.ErrReplayedNonce} {
+		response, err := config.RequestWithSign(http.MethodGet, "/v3/pay/transactions/id/4200000985202103031441826014", nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if _, err = config.ParseWechatResponse(response, &struct{}{}); err != expected {
+			t.Fatalf("response %d: unexpected error: %v", i, err)
+		}
+	}
+
+	// 通知: 时间戳过期、随机串重复、通知ID处理中、通知ID已处理
+	now := time.Now()
+	const notifyId = "EV-2018022511223320873"
+	cases := []struct {
+		notifyId, nonce string
+		timestamp       time.Time
+		expected        error
+	}{
+		{notifyId, "nonce-1", now.Add(-10 * time.Minute), errors.ErrTimestampExpired},
+		{notifyId, "nonce-1", now, nil},
+		{"EV-2018022511223320874", "nonce-1", now, errors.ErrReplayedNonce},
+		{notifyId, "nonce-2", now, errors.ErrNotifyProcessing},
+	}
+	for i, c := range cases {
+		header, body := newTestNotify(t, platformKey, keyId, c.notifyId, c.nonce, c.timestamp)
+		if _, _, err = config.DecodeWechatNotify(header, body); err != c.expected {
+			t.Fatalf("notify %d: unexpected error: %v", i, err)
+		}
+	}
+
+	// 处理失败后删除记录, 微信重发的通知可以再次处理
+	header, body := newTestNotify(t, platformKey, keyId, notifyId, "nonce-3", now)
+	config.ForgetNotify(header, notifyId)
+	if _, _, err = config.DecodeWechatNotify(header, body); err != nil {
+		t.Fatal(err)
+	}
+
+	// 处理成功后重发的通知为重复通知
+	if err = config.AckNotify(notifyId); err != nil {
+		t.Fatal(err)
+	}
+	header, body = newTestNotify(t, platformKey, keyId, notifyId, "nonce-4", now)
+	if _, _, err = config.DecodeWechatNotify(header, body); err != errors.ErrDuplicateNotify {
+		t.Fatalf("expected ErrDuplicateNotify, got: %v", err)
+	}
+
+	// 大量应答的随机串不会淘汰已处理的通知ID
+	for i := 0; i < 2*defaultNonceCapacity; i++ {
+		header := make(http.Header)
+		header.Set("Wechatpay-Nonce", fmt.Sprintf("response-nonce-%d", i))
+		if err = config.checkNonce(header); err != nil {
+			t.Fatal(err)
+		}
+	}
+	header, body = newTestNotify(t, platformKey, keyId, notifyId, "nonce-5", now)
+	if _, _, err = config.DecodeWechatNotify(header, body); err != errors.ErrDuplicateNotify {
+		t.Fatalf("expected ErrDuplicateNotify after filling the nonce store, got: %v", err)
+	}
+
+	// ParseWechatNotify不记录通知ID, 调用方回复失败后微信重发的通知可以直接处理
+	const parseId = "EV-2018022511223320875"
+	for i, nonce := range []string{"parse-nonce-1", "parse-nonce-2"} {
+		header, body := newTestNotify(t, platformKey, keyId, parseId, nonce, now)
+		request := httptest.NewRequest(http.MethodPost, "/notify", bytes.NewReader(body))
+		request.Header = header
+		if _, err = config.ParseWechatNotify(request, &struct{}{}); err != nil {
+			t.Fatalf("parse %d: unexpected error: %v", i, err)
+		}
+	}
+	// 解析失败时删除随机串记录
+	header, body = newTestNotify(t, platformKey, keyId, parseId, "parse-nonce-3", now)
+	for i, dst := range []interface{}{&[]string{}, &struct{}{}} {
+		request := httptest.NewRequest(http.MethodPost, "/notify", bytes.NewReader(body))
+		request.Header = header
+		if _, err = config.ParseWechatNotify(request, dst); (err == nil) != (i == 1) {
+			t.Fatalf("parse %d: unexpected error: %v", i, err)
+		}
+	}
+
+	// 关闭校验
+	config = newTestConfig(t, server.URL, WithPlatformPublicKey(keyId, publicKeyPEM), WithApiV3Key(testApiV3Key), WithClockSkew(0), WithNonceStore(nil))
+	header, body = newTestNotify(t, platformKey, keyId, "EV-2018022511223320873", "nonce-1", now.Add(-time.Hour))
+	for i := 0; i < 2; i++ {
+		if _, _, err = config.DecodeWechatNotify(header, body); err != nil {
+			t.Fatal(err)
+		}
+	}
+}
+
+func TestMemoryNonceStore(t *testing.T) {
+	store := NewMemoryNonceStore(2)
+	for _, key := range []string{"a", "b", "c"} {
+		if added, _ := store.Add(key, time.Minute); !added {
+			t.Fatalf("%s should be added", key)
+		}
+	}
+	// a已被淘汰
+	if added, _ := store.Add("a", time.Minute); !added {
+		t.Fatal("a should be evicted")
+	}
+	if added, _ := store.Add("c", time.Minute); added {
+		t.Fatal("c should exist")
+	}
+	// 过期后可以再次添加
+	if added, _ := store.Add("d", -time.Second); !added {
+		t.Fatal("d should be added")
+	}
+	if added, _ := store.Add("d", time.Minute); !added {
+		t.Fatal("d should be expired")
+	}
+	if exists, _ := store.Exists("d"); !exists {
+		t.Fatal("d should exist")
+	}
+	_ = store.Remove("d")
+	if exists, _ := store.Exists("d"); exists {
+		t.Fatal("d should be removed")
+	}
+}
@@ -0,0 +1,149 @@
+package service
+
+import (
+	"context"
+	"io"
+	"io/ioutil"
+	"math/rand"
+	"net/http"
+	"strconv"
+	"time"
+)
+
+const (
+	defaultRetryBaseDelay = 200 * time.Millisecond
+	defaultRetryMaxDelay  = 5 * time.Second
+)
+
+// RetryPolicy 请求失败后的重试策略
+// 微信支付对于202、429、500、502、503的HTTP状态码建议稍后重试, 对于这些状态码以及网络错误,
+// RequestWithSign会按照指数退避(带随机抖动)的方式重新发起请求, 每次重试都会使用新的随机串和时间戳重新签名
+type RetryPolicy struct {
+	// 最大尝试次数(包括第一次请求), 小于等于1时不重试
+	MaxAttempts int
+
+	// 第一次重试前的等待时间, 之后每次重试等待时间翻倍, 为0时使用200ms
+	BaseDelay time.Duration
+
+	// 单次等待时间的上限(包括微信应答头Retry-After指定的时间), 为0时使用5s
+	MaxDelay time.Duration
+
+	// 返回true时表示对应的请求不进行重试, 用于非幂等的接口, 为nil时所有请求都可以重试
+	// method为大写的方法类型, url为除去域名的绝对URL
+	Skip func(method, url string) bool
+}
+
+// WithRetryPolicy 设置请求失败后的重试策略
+func WithRetryPolicy(policy RetryPolicy) Option {
+	return func(config *Config) {
+		config.retryPolicy = &policy
+	}
+}
+
+// WithoutRetry 返回一个不进行重试的Config浅拷贝, 用于单次调用非幂等的接口, 原Config不受影响
+func (c *Config) WithoutRetry() *Config {
+	c2 := new(Config)
+	*c2 = *c
+	c2.retryPolicy = nil
+	return c2
+}
+
+// maxAttempts 获取请求的最大尝试次数
+func (p *RetryPolicy) maxAttempts(method, url string) int {
+	if p == nil || p.MaxAttempts <= 1 {
+		return 1
+	}
+	if p.Skip != nil && p.Skip(method, url) {
+		return 1
+	}
+	return p.MaxAttempts
+}
+
+// backoff 获取第attempt次请求失败后需要等待的时间
+func (p *RetryPolicy) backoff(attempt int, response *http.Response) time.Duration {
+	base, max := p.BaseDelay, p.MaxDelay
+	if base <= 0 {
+		base = defaultRetryBaseDelay
+	}
+	if max <= 0 {
+		max = defaultRetryMaxDelay
+	}
+	// 优先使用微信指定的等待时间
+	if d, ok := retryAfter(response); ok {
+		if d > max {
+			d = max
+		}
+		return d
+	}
+	d := base
+	for i := 1; i < attempt && d < max; i++ {
+		d *= 2
+	}
+	if d > max {
+		d = max
+	}
+	// 在[d/2, d]之间随机, 避免大量请求同时重试
+	half := int64(d / 2)
+	return time.Duration(half + rand.Int63n(half+1))
+}
+
+// shouldRetry 判断请求结果是否可以重试
+func shouldRetry(ctx context.Context, response *http.Response, err error) bool {
+	if ctx.Err() != nil {
+		return false
+	}
+	if err != nil {
+		return true
+	}
+	switch response.StatusCode {
+	case http.StatusAccepted,
+		http.StatusTooManyRequests,
+		http.StatusInternalServerError,
+		http.StatusBadGateway,
+		http.StatusServiceUnavailable:
+		return true
+	}
+	return false
+}
+
+// retryAfter 解析应答头中的Retry-After, 支持秒数和HTTP时间两种格式
+func retryAfter(response *http.Response) (d time.Duration, ok bool) {
+	if response == nil {
+		return
+	}
+	value := response.Header.Get("Retry-After")
+	if value == "" {
+		return
+	}
+	if seconds, err := strconv.Atoi(value); err == nil && seconds >= 0 {
+		return time.Duration(seconds) * time.Second, true
+	}
+	if t, err := http.ParseTime(value); err == nil {
+		if d = time.Until(t); d < 0 {
+			d = 0
+		}
+		return d, true
+	}
+	return
+}
+
+// sleepContext 等待d时间, ctx结束时提前返回ctx.Err()
+func sleepContext(ctx context.Context, d time.Duration) error {
+	timer := time.NewTimer(d)
+	defer timer.Stop()
+	select {
+	case <-ctx.Done():
+		return ctx.Err()
+	case <-timer.C:
+		return nil
+	}
+}
+
+// discardResponse 丢弃并关闭应答body, 以便连接可以被复用
+func discardResponse(response *http.Response) {
+	if response == nil || response.Body == nil {
+		return
+	}
+	_, _ = io.Copy(ioutil.Discard, response.Body)
+	_ = response.Body.Close()
+}
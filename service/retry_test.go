@@ -0,0 +1,104 @@
+package service
+
+import (
+	"crypto/rand"
+	"crypto/rsa"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+func newTestConfig(t *testing.T, domain string, opts ...Option) *Config {
+	privateKey, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatal(err)
+	}
+	opts = append([]Option{WithMchId("1900000001"), WithSerialNo("TESTSERIALNO")}, opts...)
+	config := NewConfig(opts...)
+	config.domain = domain
+	if err = config.GetMerchantCipher().SetRSAPrivateKey(privateKey, 0); err != nil {
+		t.Fatal(err)
+	}
+	return config
+}
+
+func TestRequestWithSignRetry(t *testing.T) {
+	var attempts int
+	var signatures = make(map[string]bool)
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		attempts++
+		signatures[r.Header.Get("Authorization")] = true
+		if attempts < 3 {
+			w.Header().Set("Retry-After", "0")
+			w.WriteHeader(http.StatusServiceUnavailable)
+			return
+		}
+		w.WriteHeader(http.StatusOK)
+	}))
+	defer server.Close()
+
+	config := newTestConfig(t, server.URL, WithRetryPolicy(RetryPolicy{MaxAttempts: 3, BaseDelay: time.Millisecond}))
+	response, err := config.RequestWithSign(http.MethodGet, "/v3/certificates", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	discardResponse(response)
+	if response.StatusCode != http.StatusOK {
+		t.Fatalf("unexpected status: %d", response.StatusCode)
+	}
+	if attempts != 3 {
+		t.Fatalf("unexpected attempts: %d", attempts)
+	}
+	if len(signatures) != 3 {
+		t.Fatalf("every attempt should be signed again, got %d signatures", len(signatures))
+	}
+}
+
+func TestRequestWithSignRetrySkip(t *testing.T) {
+	var attempts int
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		attempts++
+		w.WriteHeader(http.StatusInternalServerError)
+	}))
+	defer server.Close()
+
+	policy := RetryPolicy{
+		MaxAttempts: 3,
+		BaseDelay:   time.Millisecond,
+		Skip: func(method, url string) bool {
+			return method == http.MethodPost
+		},
+	}
+	config := newTestConfig(t, server.URL, WithRetryPolicy(policy))
+	response, err := config.RequestWithSign(http.MethodPost, "/v3/refund/domestic/refunds", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	discardResponse(response)
+	if attempts != 1 {
+		t.Fatalf("skipped request should not be retried, got %d attempts", attempts)
+	}
+
+	attempts = 0
+	response, err = config.WithoutRetry().RequestWithSign(http.MethodGet, "/v3/certificates", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	discardResponse(response)
+	if attempts != 1 {
+		t.Fatalf("WithoutRetry should not retry, got %d attempts", attempts)
+	}
+}
+
+func TestRetryAfter(t *testing.T) {
+	response := &http.Response{Header: make(http.Header)}
+	response.Header.Set("Retry-After", "2")
+	if d, ok := retryAfter(response); !ok || d != 2*time.Second {
+		t.Fatalf("unexpected Retry-After: %v %v", d, ok)
+	}
+	policy := &RetryPolicy{MaxDelay: time.Second}
+	if d := policy.backoff(1, response); d != time.Second {
+		t.Fatalf("Retry-After should be capped by MaxDelay, got %v", d)
+	}
+}
@@ -0,0 +1,110 @@
+package service
+
+import (
+	"context"
+	"crypto"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha1"
+	"crypto/sha256"
+	"encoding/base64"
+	"strings"
+
+	"github.com/pyihe/wechat-sdk/v3/pkg/errors"
+	"github.com/pyihe/wechat-sdk/v3/pkg/rsas"
+)
+
+// Signer 商户签名器, 用于对请求进行SHA256 with RSA签名
+// 商户私钥保存在KMS/HSM等外部系统中时, 可以实现该接口通过远程调用完成签名
+type Signer interface {
+	// Sign 对message进行SHA256 with RSA签名, 返回base64编码后的签名结果
+	Sign(ctx context.Context, message string) (signature string, err error)
+
+	// SerialNo 返回签名私钥对应的商户API证书序列号
+	SerialNo() string
+}
+
+// Decrypter 商户解密器, 用于解密微信使用商户API证书公钥通过RSA-OAEP加密的数据
+type Decrypter interface {
+	// DecryptOAEP 解密base64编码的密文
+	DecryptOAEP(ctx context.Context, cipherText string) (plainText []byte, err error)
+}
+
+// RSASigner 默认的签名器及解密器, 私钥保存在内存中
+type RSASigner struct {
+	privateKey *rsa.PrivateKey
+	serialNo   string
+}
+
+// NewRSASigner 使用内存中的商户私钥创建签名器
+func NewRSASigner(privateKey *rsa.PrivateKey, serialNo string) *RSASigner {
+	return &RSASigner{
+		privateKey: privateKey,
+		serialNo:   strings.ToUpper(serialNo),
+	}
+}
+
+// Sign 实现Signer
+func (s *RSASigner) Sign(ctx context.Context, message string) (signature string, err error) {
+	if s.privateKey == nil {
+		err = errors.ErrNoCipher
+		return
+	}
+	hashed := sha256.Sum256([]byte(message))
+	data, err := rsa.SignPKCS1v15(rand.Reader, s.privateKey, crypto.SHA256, hashed[:])
+	if err != nil {
+		return
+	}
+	signature = base64.StdEncoding.EncodeToString(data)
+	return
+}
+
+// SerialNo 实现Signer
+func (s *RSASigner) SerialNo() string {
+	return s.serialNo
+}
+
+// DecryptOAEP 实现Decrypter
+func (s *RSASigner) DecryptOAEP(ctx context.Context, cipherText string) (plainText []byte, err error) {
+	if s.privateKey == nil {
+		err = errors.ErrNoCipher
+		return
+	}
+	data, err := base64.StdEncoding.DecodeString(cipherText)
+	if err != nil {
+		return
+	}
+	return rsa.DecryptOAEP(sha1.New(), rand.Reader, s.privateKey, data, nil)
+}
+
+// WithSigner 设置商户签名器, 设置后RequestWithSign、UploadMedia等都将使用该签名器签名, 签名器的序列号优先于WithSerialNo
+func WithSigner(signer Signer) Option {
+	return func(config *Config) {
+		config.signer = signer
+	}
+}
+
+// WithDecrypter 设置商户解密器, 设置后需要使用商户私钥解密的数据(如投诉单中的手机号、子商户资金账单的密钥)都将使用该解密器解密
+func WithDecrypter(decrypter Decrypter) Option {
+	return func(config *Config) {
+		config.decrypter = decrypter
+	}
+}
+
+// Sign 使用商户私钥对message进行SHA256 with RSA签名, 返回base64编码后的签名结果
+// 设置了签名器时使用签名器, 否则使用WithPrivateKey加载的私钥
+func (c *Config) Sign(message string) (signature string, err error) {
+	if c.signer != nil {
+		return c.signer.Sign(c.Context(), message)
+	}
+	return rsas.SignSHA256WithRSA(c.merchantCipher, message)
+}
+
+// DecryptOAEP 使用商户私钥解密微信通过RSA-OAEP加密的数据
+// 设置了解密器时使用解密器, 否则使用WithPrivateKey加载的私钥
+func (c *Config) DecryptOAEP(cipherText string) (plainText []byte, err error) {
+	if c.decrypter != nil {
+		return c.decrypter.DecryptOAEP(c.Context(), cipherText)
+	}
+	return rsas.DecryptOAEP(c.merchantCipher, cipherText)
+}
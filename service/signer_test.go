@@ -0,0 +1,82 @@
+package service
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha1"
+	"encoding/base64"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"regexp"
+	"testing"
+
+	"github.com/pyihe/wechat-sdk/v3/pkg/rsas"
+)
+
+// remoteSigner 模拟私钥保存在KMS中的签名器
+type remoteSigner struct {
+	*RSASigner
+	calls int
+}
+
+func (s *remoteSigner) Sign(ctx context.Context, message string) (string, error) {
+	s.calls++
+	return s.RSASigner.Sign(ctx, message)
+}
+
+var authorizationPattern = regexp.MustCompile(`mchid="(.*)",nonce_str="(.*)",signature="(.*)",timestamp="(.*)",serial_no="(.*)"`)
+
+func TestSigner(t *testing.T) {
+	privateKey, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatal(err)
+	}
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := ioutil.ReadAll(r.Body)
+		matches := authorizationPattern.FindStringSubmatch(r.Header.Get("Authorization"))
+		if len(matches) != 6 || matches[5] != "KMSSERIALNO" {
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+		message := fmt.Sprintf("%s\n%s\n%s\n%s\n%s\n", r.Method, r.URL.RequestURI(), matches[4], matches[2], body)
+		if err := rsas.VerifySHA256WithRSAPublicKey(&privateKey.PublicKey, matches[3], message); err != nil {
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+		w.WriteHeader(http.StatusNoContent)
+	}))
+	defer server.Close()
+
+	signer := &remoteSigner{RSASigner: NewRSASigner(privateKey, "kmsserialno")}
+	config := NewConfig(WithMchId("1900000001"), WithSigner(signer), WithDecrypter(signer))
+	config.domain = server.URL
+	if config.GetSerialNo() != "KMSSERIALNO" {
+		t.Fatalf("unexpected serial no: %s", config.GetSerialNo())
+	}
+	response, err := config.RequestWithSign(http.MethodPost, "/v3/pay/transactions/out-trade-no/1217752501201407033233368018/close", `{"mchid":"1900000001"}`)
+	if err != nil {
+		t.Fatal(err)
+	}
+	discardResponse(response)
+	if response.StatusCode != http.StatusNoContent {
+		t.Fatalf("signature verification failed: %d", response.StatusCode)
+	}
+	if signer.calls != 1 {
+		t.Fatalf("unexpected signer calls: %d", signer.calls)
+	}
+
+	cipherText, err := rsa.EncryptOAEP(sha1.New(), rand.Reader, &privateKey.PublicKey, []byte("13800138000"), nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	plainText, err := config.DecryptOAEP(base64.StdEncoding.EncodeToString(cipherText))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(plainText) != "13800138000" {
+		t.Fatalf("unexpected plain text: %s", plainText)
+	}
+}
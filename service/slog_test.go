@@ -0,0 +1,32 @@
+//go:build go1.21
+// +build go1.21
+
+package service
+
+import (
+	"bytes"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestSlogLogger(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusNoContent)
+	}))
+	defer server.Close()
+
+	var buf bytes.Buffer
+	logger := slog.New(slog.NewJSONHandler(&buf, &slog.HandlerOptions{Level: slog.LevelDebug}))
+	config := newTestConfig(t, server.URL, WithLogger(logger))
+	response, err := config.RequestWithSign(http.MethodPost, "/v3/pay/transactions/out-trade-no/1217752501201407033233368018/close", map[string]string{"mchid": "1900000001"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	_ = response.Body.Close()
+	if output := buf.String(); !strings.Contains(output, `"signing_string"`) || !strings.Contains(output, `signature=\"***\"`) {
+		t.Fatalf("unexpected slog output: %s", output)
+	}
+}
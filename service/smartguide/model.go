@@ -6,29 +6,15 @@ import (
 
 // RegisterRequest 服务人员注册请求
 type RegisterRequest struct {
-	SubMchId    string `json:"sub_mchid,omitempty"`    // 子商户ID
-	Corpid      string `json:"corpid"`                 // 企业ID
-	StoreId     int64  `json:"store_id"`               // 门店ID
-	UserId      string `json:"userid"`                 // 企业微信的员工ID
-	Name        string `json:"name"`                   // 企业微信的员工姓名
-	Mobile      string `json:"mobile"`                 // 手机号码
-	QrCode      string `json:"qr_code"`                // 员工个人二维码
-	Avatar      string `json:"avatar"`                 // 头像URL
-	GroupQrCode string `json:"group_qrcode,omitempty"` // 群二维码URL
-}
-
-func (r *RegisterRequest) clone() *RegisterRequest {
-	return &RegisterRequest{
-		SubMchId:    r.SubMchId,
-		Corpid:      r.Corpid,
-		StoreId:     r.StoreId,
-		UserId:      r.UserId,
-		Name:        r.Name,
-		Mobile:      r.Mobile,
-		QrCode:      r.QrCode,
-		Avatar:      r.Avatar,
-		GroupQrCode: r.GroupQrCode,
-	}
+	SubMchId    string `json:"sub_mchid,omitempty"`        // 子商户ID
+	Corpid      string `json:"corpid"`                     // 企业ID
+	StoreId     int64  `json:"store_id"`                   // 门店ID
+	UserId      string `json:"userid"`                     // 企业微信的员工ID
+	Name        string `json:"name" wechatpay:"encrypt"`   // 企业微信的员工姓名
+	Mobile      string `json:"mobile" wechatpay:"encrypt"` // 手机号码
+	QrCode      string `json:"qr_code"`                    // 员工个人二维码
+	Avatar      string `json:"avatar"`                     // 头像URL
+	GroupQrCode string `json:"group_qrcode,omitempty"`     // 群二维码URL
 }
 
 // RegisterResponse 服务人员注册应答
@@ -74,22 +60,22 @@ type QueryResponse struct {
 
 // Worker 服务人员信息
 type Worker struct {
-	GuideId string `json:"guide_id,omitempty"` // 服务人员ID
-	StoreId int64  `json:"store_id,omitempty"` // 门店ID
-	Name    string `json:"name,omitempty"`     // 服务人员姓名
-	Mobile  string `json:"mobile,omitempty"`   // 服务人员手机号
-	UserId  string `json:"userid,omitempty"`   // 企业温馨的员工ID
-	WorkId  string `json:"work_id,omitempty"`  // 工号
+	GuideId string `json:"guide_id,omitempty"`                   // 服务人员ID
+	StoreId int64  `json:"store_id,omitempty"`                   // 门店ID
+	Name    string `json:"name,omitempty" wechatpay:"encrypt"`   // 服务人员姓名, 已解密
+	Mobile  string `json:"mobile,omitempty" wechatpay:"encrypt"` // 服务人员手机号, 已解密
+	UserId  string `json:"userid,omitempty"`                     // 企业温馨的员工ID
+	WorkId  string `json:"work_id,omitempty"`                    // 工号
 }
 
 // UpdateRequest 服务人员信息更新request
 type UpdateRequest struct {
-	SubMchId    string `json:"sub_mchid,omitempty"`    // 子商户号
-	Name        string `json:"name,omitempty"`         // 服务人员姓名
-	Mobile      string `json:"mobile,omitempty"`       // 服务人员手机号码
-	QrCode      string `json:"qr_code,omitempty"`      // 服务人员二维码URL
-	Avatar      string `json:"avatar,omitempty"`       // 服务人员头像URL
-	GroupQrCode string `json:"group_qrcode,omitempty"` // 群二维码URL
+	SubMchId    string `json:"sub_mchid,omitempty"`                  // 子商户号
+	Name        string `json:"name,omitempty" wechatpay:"encrypt"`   // 服务人员姓名
+	Mobile      string `json:"mobile,omitempty" wechatpay:"encrypt"` // 服务人员手机号码
+	QrCode      string `json:"qr_code,omitempty"`                    // 服务人员二维码URL
+	Avatar      string `json:"avatar,omitempty"`                     // 服务人员头像URL
+	GroupQrCode string `json:"group_qrcode,omitempty"`               // 群二维码URL
 }
 
 func (u *UpdateRequest) isZero() bool {
@@ -114,17 +100,6 @@ func (u *UpdateRequest) isZero() bool {
 	return true
 }
 
-func (u *UpdateRequest) clone() *UpdateRequest {
-	return &UpdateRequest{
-		SubMchId:    u.SubMchId,
-		Name:        u.Name,
-		Mobile:      u.Mobile,
-		QrCode:      u.QrCode,
-		Avatar:      u.Avatar,
-		GroupQrCode: u.GroupQrCode,
-	}
-}
-
 // UpdateResponse 更新服务人员信息应答
 type UpdateResponse struct {
 	model.WechatError
@@ -6,7 +6,6 @@ import (
 	"net/url"
 
 	"github.com/pyihe/wechat-sdk/v3/pkg/errors"
-	"github.com/pyihe/wechat-sdk/v3/pkg/rsas"
 	"github.com/pyihe/wechat-sdk/v3/service"
 )
 
@@ -22,17 +21,7 @@ func Register(config *service.Config, request *RegisterRequest) (registerRespons
 		err = errors.ErrNoSDKRequest
 		return
 	}
-	cipher := config.GetMerchantCipher()
-	reqClone := request.clone()
-	reqClone.Name, err = rsas.EncryptOAEP(cipher, reqClone.Name)
-	if err != nil {
-		return
-	}
-	reqClone.Mobile, err = rsas.EncryptOAEP(cipher, reqClone.Mobile)
-	if err != nil {
-		return
-	}
-	response, err := config.RequestWithSign(http.MethodPost, "/v3/smartguide/guides", reqClone)
+	response, err := config.RequestWithSign(http.MethodPost, "/v3/smartguide/guides", request)
 	if err != nil {
 		return
 	}
@@ -81,13 +70,16 @@ func Query(config *service.Config, request *QueryRequest) (queryResponse *QueryR
 	if request.UserId != "" {
 		param.Add("userid", request.UserId)
 	}
+	// 手机号码需要使用微信支付平台公钥加密, 同时在请求头中携带对应的Wechatpay-Serial
+	var headers []string
 	if request.Mobile != "" {
-		mobile := ""
-		mobile, err = rsas.EncryptOAEP(config.GetMerchantCipher(), request.Mobile)
+		var mobile, serialNo string
+		mobile, serialNo, err = config.Encrypt(request.Mobile)
 		if err != nil {
 			return
 		}
 		param.Add("mobile", mobile)
+		headers = append(headers, "Wechatpay-Serial", serialNo)
 	}
 	if request.WorkId != "" {
 		param.Add("work_id", request.WorkId)
@@ -99,7 +91,7 @@ func Query(config *service.Config, request *QueryRequest) (queryResponse *QueryR
 		param.Add("offset", fmt.Sprintf("%d", request.Offset))
 	}
 
-	response, err := config.RequestWithSign(http.MethodGet, fmt.Sprintf("/v3/smartguide/guides?%s", param.Encode()), nil)
+	response, err := config.RequestWithSign(http.MethodGet, fmt.Sprintf("/v3/smartguide/guides?%s", param.Encode()), nil, headers...)
 	if err != nil {
 		return
 	}
@@ -108,6 +100,21 @@ func Query(config *service.Config, request *QueryRequest) (queryResponse *QueryR
 	return
 }
 
+// RangeQuery 遍历查询到的服务人员的所有分页, request.Offset为起始位置, request.Limit为分页大小(最大为10),
+// fn返回false时停止遍历, requestId为最后一次查询的唯一请求ID
+func RangeQuery(config *service.Config, request *QueryRequest, fn func(worker *Worker) bool) (requestId string, err error) {
+	if request == nil {
+		err = errors.ErrNoSDKRequest
+		return
+	}
+	pageRequest := *request
+	pager := service.Pager{Offset: uint32(request.Offset), PageSize: uint32(request.Limit), MaxPageSize: 10}
+	return service.RangePages(config, pager, func(offset, limit uint32) (interface{}, error) {
+		pageRequest.Offset, pageRequest.Limit = int(offset), int(limit)
+		return Query(config, &pageRequest)
+	}, fn)
+}
+
 // Update 更新服务人员的信息
 // 商户平台文档: https://pay.weixin.qq.com/wiki/doc/apiv3/apis/chapter8_4_4.shtml
 // 服务商平台文档: https://pay.weixin.qq.com/wiki/doc/apiv3_partner/apis/chapter8_4_4.shtml
@@ -117,24 +124,12 @@ func Update(config *service.Config, guideId string, request *UpdateRequest) (upd
 		return
 	}
 
-	var cipher = config.GetMerchantCipher()
-	var cloneReq *UpdateRequest
+	// 姓名、手机号码会在RequestWithSign中自动加密
+	var body *UpdateRequest
 	if request != nil && request.isZero() == false {
-		cloneReq = request.clone()
-		if cloneReq.Name != "" {
-			cloneReq.Name, err = rsas.EncryptOAEP(cipher, cloneReq.Name)
-			if err != nil {
-				return
-			}
-		}
-		if cloneReq.Mobile != "" {
-			cloneReq.Mobile, err = rsas.EncryptOAEP(cipher, cloneReq.Mobile)
-			if err != nil {
-				return
-			}
-		}
+		body = request
 	}
-	response, err := config.RequestWithSign(http.MethodPatch, fmt.Sprintf("/v3/smartguide/guides/%s", guideId), cloneReq)
+	response, err := config.RequestWithSign(http.MethodPatch, fmt.Sprintf("/v3/smartguide/guides/%s", guideId), body)
 	if err != nil {
 		return
 	}
@@ -0,0 +1,36 @@
+package smartguide
+
+import (
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha1"
+	"encoding/base64"
+	"net/http"
+	"testing"
+
+	"github.com/pyihe/wechat-sdk/v3/service/wechatpaytest"
+)
+
+func TestQueryDecrypt(t *testing.T) {
+	server := wechatpaytest.NewServer()
+	defer server.Close()
+	encrypt := func(plainText string) string {
+		data, err := rsa.EncryptOAEP(sha1.New(), rand.Reader, &server.MerchantKey.PublicKey, []byte(plainText), nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return base64.StdEncoding.EncodeToString(data)
+	}
+	server.HandleFunc(http.MethodGet, "/v3/smartguide/guides", func(r *http.Request, body []byte) (int, interface{}) {
+		worker := map[string]interface{}{"guide_id": "LLA3WJ6DSZUfiaZDS79FH5Wm5m4X69TBic", "store_id": 1234, "name": encrypt("张三"), "mobile": encrypt("13800138000")}
+		return http.StatusOK, map[string]interface{}{"data": []interface{}{worker}, "total_count": 1, "limit": 10}
+	})
+
+	queryResponse, err := Query(server.NewConfig(), &QueryRequest{StoreId: 1234})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(queryResponse.Data) != 1 || queryResponse.Data[0].Name != "张三" || queryResponse.Data[0].Mobile != "13800138000" {
+		t.Fatalf("unexpected workers: %+v", queryResponse.Data)
+	}
+}
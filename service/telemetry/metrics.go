@@ -0,0 +1,174 @@
+package telemetry
+
+import (
+	"context"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+
+	"github.com/pyihe/wechat-sdk/v3/service"
+)
+
+// 各项指标的标签名, 创建prometheus.CounterVec、HistogramVec时需要使用相同顺序的标签
+var (
+	RequestLabels   = []string{"operation", "method", "endpoint", "mchid", "status", "code"}
+	DurationLabels  = []string{"operation", "method", "endpoint", "mchid"}
+	RateLimitLabels = []string{"endpoint", "mchid"}
+)
+
+// CounterVec 带标签的计数器
+type CounterVec interface {
+	Add(value float64, labelValues ...string)
+}
+
+// HistogramVec 带标签的直方图
+type HistogramVec interface {
+	Observe(value float64, labelValues ...string)
+}
+
+// CounterFunc 函数形式的CounterVec, 可用于适配prometheus.CounterVec:
+// telemetry.CounterFunc(func(value float64, lvs ...string) { vec.WithLabelValues(lvs...).Add(value) })
+type CounterFunc func(value float64, labelValues ...string)
+
+func (f CounterFunc) Add(value float64, labelValues ...string) {
+	f(value, labelValues...)
+}
+
+// HistogramFunc 函数形式的HistogramVec, 可用于适配prometheus.HistogramVec:
+// telemetry.HistogramFunc(func(value float64, lvs ...string) { vec.WithLabelValues(lvs...).Observe(value) })
+type HistogramFunc func(value float64, labelValues ...string)
+
+func (f HistogramFunc) Observe(value float64, labelValues ...string) {
+	f(value, labelValues...)
+}
+
+// Metrics 将每次API调用记录为Prometheus风格的计数器和直方图, 未设置的指标不记录
+type Metrics struct {
+	Requests    CounterVec   // 调用次数, 标签为RequestLabels, 如: wechatpay_requests_total
+	Duration    HistogramVec // 调用耗时(秒), 标签为DurationLabels, 如: wechatpay_request_duration_seconds
+	RateLimited CounterVec   // 被限频的次数(http 429或者错误码FREQUENCY_LIMITED、RATELIMIT_EXCEED), 标签为RateLimitLabels
+}
+
+// Observe 实现service.Observer
+func (m *Metrics) Observe(_ context.Context, event *service.Event) {
+	if m.Requests != nil {
+		m.Requests.Add(1, event.Operation, event.Method, event.Endpoint, event.MchId, statusLabel(event), event.Code)
+	}
+	if m.Duration != nil {
+		m.Duration.Observe(event.Duration.Seconds(), event.Operation, event.Method, event.Endpoint, event.MchId)
+	}
+	if m.RateLimited != nil && isRateLimited(event) {
+		m.RateLimited.Add(1, event.Endpoint, event.MchId)
+	}
+}
+
+// statusLabel 有应答时为http状态码, 否则根据是否出错为error或者ok
+func statusLabel(event *service.Event) string {
+	switch {
+	case event.StatusCode > 0:
+		return strconv.Itoa(event.StatusCode)
+	case event.Err != nil:
+		return "error"
+	}
+	return "ok"
+}
+
+func isRateLimited(event *service.Event) bool {
+	return event.StatusCode == 429 || event.Code == "FREQUENCY_LIMITED" || event.Code == "RATELIMIT_EXCEED"
+}
+
+// MemoryCounter 内存计数器, 用于测试或者没有接入监控系统时查看指标
+type MemoryCounter struct {
+	mu     sync.Mutex
+	values map[string]float64
+}
+
+// NewMemoryCounter 创建内存计数器
+func NewMemoryCounter() *MemoryCounter {
+	return &MemoryCounter{values: make(map[string]float64)}
+}
+
+func (m *MemoryCounter) Add(value float64, labelValues ...string) {
+	key := labelKey(labelValues)
+	m.mu.Lock()
+	m.values[key] += value
+	m.mu.Unlock()
+}
+
+// Value 获取标签值对应的计数
+func (m *MemoryCounter) Value(labelValues ...string) float64 {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	return m.values[labelKey(labelValues)]
+}
+
+// Series 获取所有已记录的标签值组合
+func (m *MemoryCounter) Series() [][]string {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	return series(m.values)
+}
+
+// MemoryHistogram 内存直方图, 只记录观察次数与总和
+type MemoryHistogram struct {
+	mu     sync.Mutex
+	counts map[string]float64
+	sums   map[string]float64
+}
+
+// NewMemoryHistogram 创建内存直方图
+func NewMemoryHistogram() *MemoryHistogram {
+	return &MemoryHistogram{
+		counts: make(map[string]float64),
+		sums:   make(map[string]float64),
+	}
+}
+
+func (m *MemoryHistogram) Observe(value float64, labelValues ...string) {
+	key := labelKey(labelValues)
+	m.mu.Lock()
+	m.counts[key]++
+	m.sums[key] += value
+	m.mu.Unlock()
+}
+
+// Count 获取标签值对应的观察次数
+func (m *MemoryHistogram) Count(labelValues ...string) int {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	return int(m.counts[labelKey(labelValues)])
+}
+
+// Sum 获取标签值对应的观察值总和
+func (m *MemoryHistogram) Sum(labelValues ...string) float64 {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	return m.sums[labelKey(labelValues)]
+}
+
+// Series 获取所有已记录的标签值组合
+func (m *MemoryHistogram) Series() [][]string {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	return series(m.counts)
+}
+
+const labelSep = "\x00"
+
+func labelKey(labelValues []string) string {
+	return strings.Join(labelValues, labelSep)
+}
+
+func series(values map[string]float64) [][]string {
+	keys := make([]string, 0, len(values))
+	for key := range values {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	result := make([][]string, 0, len(keys))
+	for _, key := range keys {
+		result = append(result, strings.Split(key, labelSep))
+	}
+	return result
+}
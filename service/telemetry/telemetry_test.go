@@ -0,0 +1,63 @@
+package telemetry
+
+import (
+	"testing"
+
+	"github.com/pyihe/wechat-sdk/v3/service"
+	"github.com/pyihe/wechat-sdk/v3/service/payment/merchant"
+	"github.com/pyihe/wechat-sdk/v3/service/wechatpaytest"
+)
+
+func TestObservers(t *testing.T) {
+	server := wechatpaytest.NewServer()
+	defer server.Close()
+
+	metrics := &Metrics{
+		Requests:    NewMemoryCounter(),
+		Duration:    NewMemoryHistogram(),
+		RateLimited: NewMemoryCounter(),
+	}
+	tracer := NewMemoryTracer()
+	config := server.NewConfig(service.WithObserver(metrics, &Tracing{Tracer: tracer}))
+
+	if _, err := merchant.QueryOrder(config, &merchant.QueryOrderRequest{OutTradeNo: "1217752501201407033233368018"}); err == nil {
+		t.Fatal("expected ORDER_NOT_EXIST")
+	}
+
+	endpoint := "/v3/pay/transactions/out-trade-no/{id}"
+	requests := metrics.Requests.(*MemoryCounter)
+	if v := requests.Value("request", "GET", endpoint, server.MchId, "404", "ORDER_NOT_EXIST"); v != 1 {
+		t.Fatalf("unexpected request count: %v, series: %v", v, requests.Series())
+	}
+	if n := metrics.Duration.(*MemoryHistogram).Count("request", "GET", endpoint, server.MchId); n != 1 {
+		t.Fatalf("unexpected duration count: %d", n)
+	}
+	if series := metrics.RateLimited.(*MemoryCounter).Series(); len(series) != 0 {
+		t.Fatalf("unexpected rate limited series: %v", series)
+	}
+
+	spans := tracer.Spans()
+	if len(spans) != 1 {
+		t.Fatalf("expected 1 span, got %d", len(spans))
+	}
+	span := spans[0]
+	if span.Name != "GET "+endpoint || !span.Ended || span.EndTime.Before(span.StartTime) {
+		t.Fatalf("unexpected span: %+v", span)
+	}
+	if span.Attributes["http.status_code"] != 404 || span.Attributes["wechatpay.code"] != "ORDER_NOT_EXIST" ||
+		span.Attributes["wechatpay.mchid"] != server.MchId || len(span.Errors) != 1 {
+		t.Fatalf("unexpected span attributes: %+v, errors: %v", span.Attributes, span.Errors)
+	}
+
+	// 通知解析同样会被观察, span名称为通知类型
+	notify := server.NewNotifyRequest("TRANSACTION.SUCCESS", map[string]interface{}{"out_trade_no": "1217752501201407033233368018"})
+	if _, err := merchant.ParsePrepayNotify(config, notify); err != nil {
+		t.Fatal(err)
+	}
+	if v := requests.Value("notify", "", "TRANSACTION.SUCCESS", server.MchId, "ok", ""); v != 1 {
+		t.Fatalf("unexpected notify count: %v, series: %v", v, requests.Series())
+	}
+	if spans = tracer.Spans(); len(spans) != 2 || spans[1].Name != "notify TRANSACTION.SUCCESS" || len(spans[1].Errors) != 0 {
+		t.Fatalf("unexpected notify span: %+v", spans[len(spans)-1])
+	}
+}
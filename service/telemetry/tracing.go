@@ -0,0 +1,116 @@
+package telemetry
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"github.com/pyihe/wechat-sdk/v3/pkg/errors"
+	"github.com/pyihe/wechat-sdk/v3/service"
+)
+
+// Tracer 创建span, 观察者在调用结束后才会收到Event, 因此需要支持指定开始时间
+// 适配OpenTelemetry时可以使用: tracer.Start(ctx, name, trace.WithTimestamp(start), trace.WithSpanKind(trace.SpanKindClient))
+type Tracer interface {
+	Start(ctx context.Context, name string, start time.Time) Span
+}
+
+// Span 链路追踪中的一个span
+type Span interface {
+	SetAttribute(key string, value interface{})
+	RecordError(err error)
+	End(end time.Time)
+}
+
+// Tracing 将每次API调用记录为一个OpenTelemetry风格的span
+// span名称为"请求方法 接口模板", 如: POST /v3/pay/transactions/jsapi, 通知为: notify TRANSACTION.SUCCESS
+type Tracing struct {
+	Tracer Tracer
+}
+
+// Observe 实现service.Observer
+func (t *Tracing) Observe(ctx context.Context, event *service.Event) {
+	name := event.Method + " " + event.Endpoint
+	if event.Operation == service.OperationNotify {
+		name = service.OperationNotify + " " + event.Endpoint
+	}
+	span := t.Tracer.Start(ctx, name, event.Start)
+	span.SetAttribute("wechatpay.operation", event.Operation)
+	span.SetAttribute("wechatpay.mchid", event.MchId)
+	span.SetAttribute("wechatpay.attempt", event.Attempt)
+	if event.Method != "" {
+		span.SetAttribute("http.method", event.Method)
+		span.SetAttribute("http.route", event.Endpoint)
+	}
+	if event.StatusCode > 0 {
+		span.SetAttribute("http.status_code", event.StatusCode)
+	}
+	if event.Code != "" {
+		span.SetAttribute("wechatpay.code", event.Code)
+	}
+	if event.RequestId != "" {
+		span.SetAttribute("wechatpay.request_id", event.RequestId)
+	}
+	switch {
+	case event.Err != nil:
+		span.RecordError(event.Err)
+	case event.StatusCode >= 400:
+		span.RecordError(&errors.APIError{StatusCode: event.StatusCode, Code: event.Code, RequestId: event.RequestId})
+	}
+	span.End(event.Start.Add(event.Duration))
+}
+
+// MemoryTracer 将span保存在内存中, 用于测试
+type MemoryTracer struct {
+	mu    sync.Mutex
+	spans []*MemorySpan
+}
+
+// NewMemoryTracer 创建内存Tracer
+func NewMemoryTracer() *MemoryTracer {
+	return &MemoryTracer{}
+}
+
+func (m *MemoryTracer) Start(_ context.Context, name string, start time.Time) Span {
+	span := &MemorySpan{
+		Name:       name,
+		StartTime:  start,
+		Attributes: make(map[string]interface{}),
+	}
+	m.mu.Lock()
+	m.spans = append(m.spans, span)
+	m.mu.Unlock()
+	return span
+}
+
+// Spans 获取所有已开始的span
+func (m *MemoryTracer) Spans() []*MemorySpan {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	spans := make([]*MemorySpan, len(m.spans))
+	copy(spans, m.spans)
+	return spans
+}
+
+// MemorySpan 内存中的span
+type MemorySpan struct {
+	Name       string
+	StartTime  time.Time
+	EndTime    time.Time
+	Attributes map[string]interface{}
+	Errors     []error
+	Ended      bool
+}
+
+func (s *MemorySpan) SetAttribute(key string, value interface{}) {
+	s.Attributes[key] = value
+}
+
+func (s *MemorySpan) RecordError(err error) {
+	s.Errors = append(s.Errors, err)
+}
+
+func (s *MemorySpan) End(end time.Time) {
+	s.EndTime = end
+	s.Ended = true
+}
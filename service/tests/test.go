@@ -1,25 +1,17 @@
 package tests
 
-import "github.com/pyihe/wechat-sdk/v3/service"
+import (
+	"github.com/pyihe/wechat-sdk/v3/service"
+	"github.com/pyihe/wechat-sdk/v3/service/wechatpaytest"
+)
 
 var (
-	Config     *service.Config
-	mchId      = "**********"
-	appId      = "******************"
-	apiV3Key   = "********************************"
-	serialNo   = "****************************************"
-	publicKey  = "../test/pem/public_key.pem"
-	privateKey = "../test/pem/apiclient_key.pem"
+	// Server 离线的微信支付模拟服务器, 测试用例不再依赖真实的商户密钥和证书
+	Server *wechatpaytest.Server
+	Config *service.Config
 )
 
 func init() {
-	opts := []service.Option{
-		service.WithAppId(appId),
-		service.WithMchId(mchId),
-		service.WithApiV3Key(apiV3Key),
-		service.WithPrivateKey(privateKey),
-		service.WithPublicKey(publicKey),
-		service.WithSerialNo(serialNo),
-	}
-	Config = service.NewConfig(opts...)
+	Server = wechatpaytest.NewServer()
+	Config = service.NewConfig(Server.Options()...)
 }
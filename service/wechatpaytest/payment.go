@@ -0,0 +1,367 @@
+package wechatpaytest
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+)
+
+// 交易状态
+const (
+	TradeStateNotPay  = "NOTPAY"
+	TradeStateSuccess = "SUCCESS"
+	TradeStateRefund  = "REFUND"
+	TradeStateClosed  = "CLOSED"
+)
+
+// 退款状态
+const (
+	RefundStatusProcessing = "PROCESSING"
+	RefundStatusSuccess    = "SUCCESS"
+)
+
+// orderRequest 预下单请求中模拟服务器关心的字段
+type orderRequest struct {
+	AppId       string `json:"appid"`
+	MchId       string `json:"mchid"`
+	Description string `json:"description"`
+	OutTradeNo  string `json:"out_trade_no"`
+	NotifyUrl   string `json:"notify_url"`
+	Attach      string `json:"attach"`
+	Amount      *struct {
+		Total    int64  `json:"total"`
+		Currency string `json:"currency"`
+	} `json:"amount"`
+	Payer *struct {
+		OpenId string `json:"openid"`
+	} `json:"payer"`
+}
+
+// refundRequest 退款请求中模拟服务器关心的字段
+type refundRequest struct {
+	TransactionId string `json:"transaction_id"`
+	OutTradeNo    string `json:"out_trade_no"`
+	OutRefundNo   string `json:"out_refund_no"`
+	Reason        string `json:"reason"`
+	Amount        *struct {
+		Refund   int64  `json:"refund"`
+		Total    int64  `json:"total"`
+		Currency string `json:"currency"`
+	} `json:"amount"`
+}
+
+// order 模拟的订单
+type order struct {
+	appId         string
+	outTradeNo    string
+	transactionId string
+	tradeType     string
+	tradeState    string
+	attach        string
+	openId        string
+	total         int64
+	refunded      int64
+	successTime   time.Time
+}
+
+// refund 模拟的退款单
+type refund struct {
+	refundId    string
+	outRefundNo string
+	order       *order
+	refund      int64
+	status      string
+	createTime  time.Time
+	successTime time.Time
+}
+
+// Order 返回订单的JSON结构, 与查询订单接口返回的内容一致, 不存在时返回nil
+func (s *Server) Order(outTradeNo string) map[string]interface{} {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	o, ok := s.orders[outTradeNo]
+	if !ok {
+		return nil
+	}
+	return s.orderJSON(o)
+}
+
+// PayOrder 模拟用户支付成功, 返回可以直接交给回调处理的支付成功通知
+func (s *Server) PayOrder(outTradeNo string) (*http.Request, error) {
+	s.mu.Lock()
+	o, ok := s.orders[outTradeNo]
+	if !ok {
+		s.mu.Unlock()
+		return nil, fmt.Errorf("订单[%s]不存在", outTradeNo)
+	}
+	if o.tradeState != TradeStateNotPay {
+		s.mu.Unlock()
+		return nil, fmt.Errorf("订单[%s]状态为%s, 无法支付", outTradeNo, o.tradeState)
+	}
+	o.tradeState = TradeStateSuccess
+	o.successTime = time.Now()
+	resource := s.orderJSON(o)
+	s.mu.Unlock()
+	return s.NewNotifyRequest("TRANSACTION.SUCCESS", resource), nil
+}
+
+// CompleteRefund 模拟退款成功, 返回可以直接交给回调处理的退款成功通知
+func (s *Server) CompleteRefund(outRefundNo string) (*http.Request, error) {
+	s.mu.Lock()
+	r, ok := s.refunds[outRefundNo]
+	if !ok {
+		s.mu.Unlock()
+		return nil, fmt.Errorf("退款单[%s]不存在", outRefundNo)
+	}
+	if r.status != RefundStatusProcessing {
+		s.mu.Unlock()
+		return nil, fmt.Errorf("退款单[%s]状态为%s, 无法完成退款", outRefundNo, r.status)
+	}
+	r.status = RefundStatusSuccess
+	r.successTime = time.Now()
+	resource := s.refundJSON(r)
+	resource["refund_status"] = r.status
+	delete(resource, "status")
+	s.mu.Unlock()
+	return s.NewNotifyRequest("REFUND.SUCCESS", resource), nil
+}
+
+func (s *Server) registerPayment() {
+	for _, tradeType := range []string{"jsapi", "app", "native", "h5"} {
+		s.HandleFunc(http.MethodPost, "/v3/pay/transactions/"+tradeType, s.handlePrepay(tradeType))
+	}
+	s.HandleFunc(http.MethodGet, "/v3/pay/transactions/out-trade-no/{out_trade_no}", s.handleQueryOrder)
+	s.HandleFunc(http.MethodGet, "/v3/pay/transactions/id/{transaction_id}", s.handleQueryOrder)
+	s.HandleFunc(http.MethodPost, "/v3/pay/transactions/out-trade-no/{out_trade_no}/close", s.handleCloseOrder)
+	s.HandleFunc(http.MethodPost, "/v3/refund/domestic/refunds", s.handleRefund)
+	s.HandleFunc(http.MethodGet, "/v3/refund/domestic/refunds/{out_refund_no}", s.handleQueryRefund)
+}
+
+// handlePrepay 预下单
+func (s *Server) handlePrepay(tradeType string) HandlerFunc {
+	return func(r *http.Request, body []byte) (int, interface{}) {
+		var req orderRequest
+		if err := json.Unmarshal(body, &req); err != nil {
+			return http.StatusBadRequest, Error("PARAM_ERROR", "请求body格式错误")
+		}
+		switch {
+		case req.AppId == "", req.MchId == "", req.Description == "", req.OutTradeNo == "", req.NotifyUrl == "":
+			return http.StatusBadRequest, Error("PARAM_ERROR", "缺少必填参数")
+		case req.Amount == nil || req.Amount.Total <= 0:
+			return http.StatusBadRequest, Error("PARAM_ERROR", "订单金额错误")
+		case tradeType == "jsapi" && (req.Payer == nil || req.Payer.OpenId == ""):
+			return http.StatusBadRequest, Error("PARAM_ERROR", "缺少payer.openid")
+		case req.MchId != s.MchId:
+			return http.StatusBadRequest, Error("MCH_NOT_EXISTS", "商户号不存在")
+		}
+
+		s.mu.Lock()
+		defer s.mu.Unlock()
+		o, ok := s.orders[req.OutTradeNo]
+		if ok && o.tradeState != TradeStateNotPay {
+			return http.StatusForbidden, Error("ORDERPAID", "该订单已支付或已关闭")
+		}
+		if !ok {
+			s.sequence++
+			o = &order{
+				appId:         req.AppId,
+				outTradeNo:    req.OutTradeNo,
+				transactionId: fmt.Sprintf("4200%016d%08d", time.Now().Unix(), s.sequence),
+				tradeType:     strings.ToUpper(tradeType),
+				tradeState:    TradeStateNotPay,
+				attach:        req.Attach,
+				total:         req.Amount.Total,
+			}
+			if tradeType == "h5" {
+				o.tradeType = "MWEB"
+			}
+			if req.Payer != nil {
+				o.openId = req.Payer.OpenId
+			}
+			s.orders[req.OutTradeNo] = o
+		}
+
+		prepayId := "wx" + o.transactionId
+		switch tradeType {
+		case "native":
+			return http.StatusOK, map[string]string{"code_url": "weixin://wxpay/bizpayurl?pr=" + prepayId}
+		case "h5":
+			return http.StatusOK, map[string]string{"h5_url": "https://wx.tenpay.com/cgi-bin/mmpayweb-bin/checkmweb?prepay_id=" + prepayId}
+		default:
+			return http.StatusOK, map[string]string{"prepay_id": prepayId}
+		}
+	}
+}
+
+// handleQueryOrder 查询订单
+func (s *Server) handleQueryOrder(r *http.Request, body []byte) (int, interface{}) {
+	if r.URL.Query().Get("mchid") != s.MchId {
+		return http.StatusBadRequest, Error("PARAM_ERROR", "商户号错误")
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if o := s.findOrder(r.URL.Path); o != nil {
+		return http.StatusOK, s.orderJSON(o)
+	}
+	return http.StatusNotFound, Error("ORDER_NOT_EXIST", "订单不存在")
+}
+
+// handleCloseOrder 关闭订单
+func (s *Server) handleCloseOrder(r *http.Request, body []byte) (int, interface{}) {
+	var req struct {
+		MchId string `json:"mchid"`
+	}
+	if err := json.Unmarshal(body, &req); err != nil || req.MchId != s.MchId {
+		return http.StatusBadRequest, Error("PARAM_ERROR", "商户号错误")
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	o := s.findOrder(strings.TrimSuffix(r.URL.Path, "/close"))
+	if o == nil {
+		return http.StatusNotFound, Error("ORDER_NOT_EXIST", "订单不存在")
+	}
+	if o.tradeState != TradeStateNotPay && o.tradeState != TradeStateClosed {
+		return http.StatusBadRequest, Error("ORDERPAID", "订单已支付")
+	}
+	o.tradeState = TradeStateClosed
+	return http.StatusNoContent, nil
+}
+
+// handleRefund 申请退款
+func (s *Server) handleRefund(r *http.Request, body []byte) (int, interface{}) {
+	var req refundRequest
+	if err := json.Unmarshal(body, &req); err != nil {
+		return http.StatusBadRequest, Error("PARAM_ERROR", "请求body格式错误")
+	}
+	if req.OutRefundNo == "" || (req.OutTradeNo == "" && req.TransactionId == "") || req.Amount == nil || req.Amount.Refund <= 0 {
+		return http.StatusBadRequest, Error("PARAM_ERROR", "缺少必填参数")
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	// 相同的商户退款单号重复请求时返回同一笔退款
+	if existing, ok := s.refunds[req.OutRefundNo]; ok {
+		return http.StatusOK, s.refundJSON(existing)
+	}
+	var o *order
+	if req.OutTradeNo != "" {
+		o = s.orders[req.OutTradeNo]
+	} else {
+		o = s.findOrder("/id/" + req.TransactionId)
+	}
+	if o == nil {
+		return http.StatusNotFound, Error("RESOURCE_NOT_EXISTS", "订单不存在")
+	}
+	if o.tradeState != TradeStateSuccess && o.tradeState != TradeStateRefund {
+		return http.StatusForbidden, Error("TRADE_STATE_ERROR", "订单状态不允许退款")
+	}
+	if req.Amount.Total != 0 && req.Amount.Total != o.total {
+		return http.StatusBadRequest, Error("PARAM_ERROR", "订单金额与原订单不一致")
+	}
+	if o.refunded+req.Amount.Refund > o.total {
+		return http.StatusForbidden, Error("NOT_ENOUGH", "可退金额不足")
+	}
+
+	s.sequence++
+	o.refunded += req.Amount.Refund
+	o.tradeState = TradeStateRefund
+	rf := &refund{
+		refundId:    fmt.Sprintf("5030%016d%08d", time.Now().Unix(), s.sequence),
+		outRefundNo: req.OutRefundNo,
+		order:       o,
+		refund:      req.Amount.Refund,
+		status:      RefundStatusProcessing,
+		createTime:  time.Now(),
+	}
+	s.refunds[req.OutRefundNo] = rf
+	return http.StatusOK, s.refundJSON(rf)
+}
+
+// handleQueryRefund 查询单笔退款
+func (s *Server) handleQueryRefund(r *http.Request, body []byte) (int, interface{}) {
+	outRefundNo := r.URL.Path[strings.LastIndex(r.URL.Path, "/")+1:]
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if rf, ok := s.refunds[outRefundNo]; ok {
+		return http.StatusOK, s.refundJSON(rf)
+	}
+	return http.StatusNotFound, Error("RESOURCE_NOT_EXISTS", "退款单不存在")
+}
+
+// findOrder 根据路径.../out-trade-no/{out_trade_no}或者.../id/{transaction_id}查找订单, 调用方需持有锁
+func (s *Server) findOrder(path string) *order {
+	index := strings.LastIndex(path, "/")
+	key, value := path[:index], path[index+1:]
+	if strings.HasSuffix(key, "/out-trade-no") {
+		return s.orders[value]
+	}
+	for _, o := range s.orders {
+		if o.transactionId == value {
+			return o
+		}
+	}
+	return nil
+}
+
+func (s *Server) orderJSON(o *order) map[string]interface{} {
+	result := map[string]interface{}{
+		"appid":            o.appId,
+		"mchid":            s.MchId,
+		"out_trade_no":     o.outTradeNo,
+		"transaction_id":   o.transactionId,
+		"trade_type":       o.tradeType,
+		"trade_state":      o.tradeState,
+		"trade_state_desc": tradeStateDesc(o.tradeState),
+		"attach":           o.attach,
+		"amount":           map[string]interface{}{"total": o.total, "currency": "CNY", "payer_total": o.total, "payer_currency": "CNY"},
+	}
+	if o.openId != "" {
+		result["payer"] = map[string]string{"openid": o.openId}
+	}
+	if !o.successTime.IsZero() {
+		result["bank_type"] = "OTHERS"
+		result["success_time"] = o.successTime.Format(time.RFC3339)
+	}
+	return result
+}
+
+func (s *Server) refundJSON(r *refund) map[string]interface{} {
+	result := map[string]interface{}{
+		"mchid":                 s.MchId,
+		"refund_id":             r.refundId,
+		"out_refund_no":         r.outRefundNo,
+		"transaction_id":        r.order.transactionId,
+		"out_trade_no":          r.order.outTradeNo,
+		"channel":               "ORIGINAL",
+		"user_received_account": "支付用户零钱",
+		"create_time":           r.createTime.Format(time.RFC3339),
+		"status":                r.status,
+		"funds_account":         "AVAILABLE",
+		"amount": map[string]interface{}{
+			"total":        r.order.total,
+			"refund":       r.refund,
+			"payer_total":  r.order.total,
+			"payer_refund": r.refund,
+			"currency":     "CNY",
+		},
+	}
+	if !r.successTime.IsZero() {
+		result["success_time"] = r.successTime.Format(time.RFC3339)
+	}
+	return result
+}
+
+func tradeStateDesc(state string) string {
+	switch state {
+	case TradeStateSuccess:
+		return "支付成功"
+	case TradeStateRefund:
+		return "转入退款"
+	case TradeStateClosed:
+		return "已关闭"
+	default:
+		return "未支付"
+	}
+}
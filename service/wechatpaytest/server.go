@@ -0,0 +1,449 @@
+// Package wechatpaytest 提供离线的微信支付模拟服务器, 用于在不访问微信服务器的情况下测试支付、退款、证书下载等流程
+// 模拟服务器会验证请求签名, 并使用自动生成的测试平台证书对应答签名, 同时模拟订单和退款的状态
+package wechatpaytest
+
+import (
+	"bytes"
+	"crypto"
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/base64"
+	"encoding/json"
+	"encoding/pem"
+	"fmt"
+	"io/ioutil"
+	"math/big"
+	"mime"
+	"mime/multipart"
+	"net/http"
+	"net/http/httptest"
+	"regexp"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/pyihe/wechat-sdk/v3/pkg"
+	"github.com/pyihe/wechat-sdk/v3/service"
+)
+
+const (
+	// DefaultMchId 模拟服务器默认的商户号
+	DefaultMchId = "1900000001"
+	// DefaultAppId 模拟服务器默认的应用ID
+	DefaultAppId = "wxd678efh567hg6787"
+	// DefaultApiV3Key 模拟服务器默认的APIv3密钥
+	DefaultApiV3Key = "a7cde1ZJB1kG2e7VfTs3jQzaWizur8Gb"
+
+	// 请求签名中的时间戳与服务器时间允许的最大误差
+	maxClockSkew = 5 * time.Minute
+)
+
+var authorizationPattern = regexp.MustCompile(`^WECHATPAY2-SHA256-RSA2048 mchid="([^"]*)",nonce_str="([^"]*)",signature="([^"]*)",timestamp="([^"]*)",serial_no="([^"]*)"$`)
+
+// HandlerFunc 自定义接口的处理函数, body为请求body(签名已经验证通过), 返回的response会被序列化为JSON并签名
+// response为nil时应答body为空, 为[]byte时原样返回(如模拟账单文件下载)
+type HandlerFunc func(request *http.Request, body []byte) (status int, response interface{})
+
+// platformCertificate 模拟的微信支付平台证书
+type platformCertificate struct {
+	serialNo    string
+	privateKey  *rsa.PrivateKey
+	certificate *x509.Certificate
+	pem         []byte
+}
+
+// Server 微信支付模拟服务器
+type Server struct {
+	*httptest.Server
+
+	MchId            string          // 商户号
+	AppId            string          // 应用ID
+	ApiV3Key         string          // APIv3密钥
+	MerchantSerialNo string          // 商户API证书序列号
+	MerchantKey      *rsa.PrivateKey // 商户API私钥
+
+	mu           sync.Mutex
+	certificates []*platformCertificate // 平台证书, 最后一个为当前用于签名的证书
+	handlers     map[string]HandlerFunc
+	orders       map[string]*order  // key为out_trade_no
+	refunds      map[string]*refund // key为out_refund_no
+	sequence     int64
+}
+
+// NewServer 创建并启动模拟服务器, 使用完毕后请调用Close关闭
+func NewServer() *Server {
+	merchantKey, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		panic(err)
+	}
+	s := &Server{
+		MchId:            DefaultMchId,
+		AppId:            DefaultAppId,
+		ApiV3Key:         DefaultApiV3Key,
+		MerchantSerialNo: fmt.Sprintf("%X", merchantKey.PublicKey.N.Bytes()[:20]),
+		MerchantKey:      merchantKey,
+		handlers:         make(map[string]HandlerFunc),
+		orders:           make(map[string]*order),
+		refunds:          make(map[string]*refund),
+	}
+	s.RotatePlatformCertificate()
+	s.registerPayment()
+	s.HandleFunc(http.MethodGet, "/v3/certificates", s.handleCertificates)
+	s.Server = httptest.NewServer(http.HandlerFunc(s.serveHTTP))
+	return s
+}
+
+// Options 返回连接模拟服务器所需的全部配置项, 包括商户信息以及平台证书
+func (s *Server) Options() []service.Option {
+	return append(s.MerchantOptions(), service.WithPublicKeyFrom(service.FromBytes(s.PlatformCertificatePEM())))
+}
+
+// MerchantOptions 返回连接模拟服务器所需的商户配置项, 不包括平台证书, 用于测试证书下载
+func (s *Server) MerchantOptions() []service.Option {
+	return []service.Option{
+		service.WithDomain(s.URL),
+		service.WithMchId(s.MchId),
+		service.WithAppId(s.AppId),
+		service.WithApiV3Key(s.ApiV3Key),
+		service.WithSerialNo(s.MerchantSerialNo),
+		service.WithPrivateKeyFrom(service.FromBytes(s.MerchantPrivateKeyPEM())),
+	}
+}
+
+// NewConfig 使用Options以及额外的opts创建Config
+func (s *Server) NewConfig(opts ...service.Option) *service.Config {
+	return service.NewConfig(append(s.Options(), opts...)...)
+}
+
+// MerchantPrivateKeyPEM 返回PKCS#8格式的商户API私钥
+func (s *Server) MerchantPrivateKeyPEM() []byte {
+	der, err := x509.MarshalPKCS8PrivateKey(s.MerchantKey)
+	if err != nil {
+		panic(err)
+	}
+	return pem.EncodeToMemory(&pem.Block{Type: "PRIVATE KEY", Bytes: der})
+}
+
+// PlatformCertificatePEM 返回当前的平台证书
+func (s *Server) PlatformCertificatePEM() []byte {
+	return s.current().pem
+}
+
+// PlatformSerialNo 返回当前的平台证书序列号
+func (s *Server) PlatformSerialNo() string {
+	return s.current().serialNo
+}
+
+// RotatePlatformCertificate 生成新的平台证书, 之后的应答都使用新证书签名, 旧证书仍然可以通过/v3/certificates下载
+func (s *Server) RotatePlatformCertificate() (serialNo string) {
+	privateKey, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		panic(err)
+	}
+	serial, err := rand.Int(rand.Reader, new(big.Int).Lsh(big.NewInt(1), 159))
+	if err != nil {
+		panic(err)
+	}
+	template := &x509.Certificate{
+		SerialNumber: serial,
+		Subject:      pkix.Name{CommonName: "Tenpay.com Root CA", Organization: []string{"Tenpay.com"}},
+		NotBefore:    time.Now().Add(-time.Hour),
+		NotAfter:     time.Now().AddDate(5, 0, 0),
+	}
+	der, err := x509.CreateCertificate(rand.Reader, template, template, &privateKey.PublicKey, privateKey)
+	if err != nil {
+		panic(err)
+	}
+	certificate, err := x509.ParseCertificate(der)
+	if err != nil {
+		panic(err)
+	}
+	c := &platformCertificate{
+		serialNo:    strings.ToUpper(serial.Text(16)),
+		privateKey:  privateKey,
+		certificate: certificate,
+		pem:         pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der}),
+	}
+	s.mu.Lock()
+	s.certificates = append(s.certificates, c)
+	s.mu.Unlock()
+	return c.serialNo
+}
+
+// HandleFunc 注册自定义接口, 会覆盖同名的内置接口, path不包括查询参数
+func (s *Server) HandleFunc(method, path string, fn HandlerFunc) {
+	s.mu.Lock()
+	s.handlers[strings.ToUpper(method)+" "+path] = fn
+	s.mu.Unlock()
+}
+
+// NewNotifyRequest 构造一个经过平台证书签名、使用APIv3密钥加密的微信通知请求
+// eventType: 通知类型, 如TRANSACTION.SUCCESS; resource: 通知资源数据明文, 会被序列化为JSON后加密
+func (s *Server) NewNotifyRequest(eventType string, resource interface{}) *http.Request {
+	plainText, err := json.Marshal(resource)
+	if err != nil {
+		panic(err)
+	}
+	nonce := pkg.String(12)
+	associatedData := strings.ToLower(strings.SplitN(eventType, ".", 2)[0])
+	cipherText, err := encryptAES256GCM(s.ApiV3Key, nonce, associatedData, plainText)
+	if err != nil {
+		panic(err)
+	}
+	s.mu.Lock()
+	s.sequence++
+	id := fmt.Sprintf("EV-%d%010d", time.Now().Unix(), s.sequence)
+	s.mu.Unlock()
+	body, err := json.Marshal(map[string]interface{}{
+		"id":            id,
+		"create_time":   time.Now().Format(time.RFC3339),
+		"event_type":    eventType,
+		"resource_type": "encrypt-resource",
+		"summary":       "模拟通知",
+		"resource": map[string]string{
+			"algorithm":       "AEAD_AES_256_GCM",
+			"ciphertext":      cipherText,
+			"associated_data": associatedData,
+			"original_type":   associatedData,
+			"nonce":           nonce,
+		},
+	})
+	if err != nil {
+		panic(err)
+	}
+	request := httptest.NewRequest(http.MethodPost, "/notify", bytes.NewReader(body))
+	for k, v := range s.signHeader(body) {
+		request.Header[k] = v
+	}
+	request.Header.Set("Content-Type", service.ContentTypeJSON)
+	return request
+}
+
+// ResendNotifyRequest 模拟微信重发通知, 使用新的随机串和时间戳对request的body重新签名, 需要在request被处理之前调用
+func (s *Server) ResendNotifyRequest(request *http.Request) *http.Request {
+	body, err := ioutil.ReadAll(request.Body)
+	if err != nil {
+		panic(err)
+	}
+	request.Body = ioutil.NopCloser(bytes.NewReader(body))
+	resend := httptest.NewRequest(http.MethodPost, "/notify", bytes.NewReader(body))
+	for k, v := range s.signHeader(body) {
+		resend.Header[k] = v
+	}
+	resend.Header.Set("Content-Type", service.ContentTypeJSON)
+	return resend
+}
+
+func (s *Server) current() *platformCertificate {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.certificates[len(s.certificates)-1]
+}
+
+func (s *Server) serveHTTP(w http.ResponseWriter, r *http.Request) {
+	body, err := ioutil.ReadAll(r.Body)
+	if err != nil {
+		s.writeError(w, http.StatusBadRequest, "PARAM_ERROR", err.Error())
+		return
+	}
+	if err = s.verifyRequest(r, body); err != nil {
+		s.writeError(w, http.StatusUnauthorized, "SIGN_ERROR", err.Error())
+		return
+	}
+
+	s.mu.Lock()
+	handler, ok := s.handlers[r.Method+" "+r.URL.Path]
+	if !ok {
+		handler = s.matchPattern(r.Method, r.URL.Path)
+	}
+	s.mu.Unlock()
+	if handler == nil {
+		s.writeError(w, http.StatusNotFound, "RESOURCE_NOT_EXISTS", "请求的资源不存在")
+		return
+	}
+	status, response := handler(r, body)
+	s.writeJSON(w, status, response)
+}
+
+// matchPattern 匹配带路径参数的接口, 注册时路径参数用{}表示, 如/v3/refund/domestic/refunds/{out_refund_no}
+func (s *Server) matchPattern(method, path string) HandlerFunc {
+	segments := strings.Split(path, "/")
+	for key, handler := range s.handlers {
+		parts := strings.SplitN(key, " ", 2)
+		if parts[0] != method {
+			continue
+		}
+		patterns := strings.Split(parts[1], "/")
+		if len(patterns) != len(segments) {
+			continue
+		}
+		matched := true
+		for i, p := range patterns {
+			if strings.HasPrefix(p, "{") && strings.HasSuffix(p, "}") {
+				continue
+			}
+			if p != segments[i] {
+				matched = false
+				break
+			}
+		}
+		if matched {
+			return handler
+		}
+	}
+	return nil
+}
+
+// verifyRequest 验证请求签名
+func (s *Server) verifyRequest(r *http.Request, body []byte) error {
+	matches := authorizationPattern.FindStringSubmatch(r.Header.Get("Authorization"))
+	if len(matches) != 6 {
+		return fmt.Errorf("Authorization格式错误")
+	}
+	mchId, nonce, signature, timestamp, serialNo := matches[1], matches[2], matches[3], matches[4], matches[5]
+	if mchId != s.MchId {
+		return fmt.Errorf("商户号[%s]不存在", mchId)
+	}
+	if !strings.EqualFold(serialNo, s.MerchantSerialNo) {
+		return fmt.Errorf("商户证书序列号[%s]错误", serialNo)
+	}
+	ts, err := strconv.ParseInt(timestamp, 10, 64)
+	if err != nil {
+		return fmt.Errorf("时间戳[%s]格式错误", timestamp)
+	}
+	if d := time.Since(time.Unix(ts, 0)); d > maxClockSkew || d < -maxClockSkew {
+		return fmt.Errorf("时间戳[%s]已过期", timestamp)
+	}
+	// 上传媒体文件时签名主体为meta
+	if mediaType, params, err := mime.ParseMediaType(r.Header.Get("Content-Type")); err == nil && strings.HasPrefix(mediaType, "multipart/") {
+		if body, err = readMeta(body, params["boundary"]); err != nil {
+			return err
+		}
+	}
+	message := fmt.Sprintf("%s\n%s\n%s\n%s\n%s\n", r.Method, r.URL.RequestURI(), timestamp, nonce, body)
+	sign, err := base64.StdEncoding.DecodeString(signature)
+	if err != nil {
+		return err
+	}
+	hashed := sha256.Sum256([]byte(message))
+	if err = rsa.VerifyPKCS1v15(&s.MerchantKey.PublicKey, crypto.SHA256, hashed[:], sign); err != nil {
+		return fmt.Errorf("签名验证失败")
+	}
+	return nil
+}
+
+// readMeta 读取multipart请求中的meta部分
+func readMeta(body []byte, boundary string) ([]byte, error) {
+	reader := multipart.NewReader(bytes.NewReader(body), boundary)
+	for {
+		part, err := reader.NextPart()
+		if err != nil {
+			return nil, fmt.Errorf("缺少meta")
+		}
+		if part.FormName() == "meta" {
+			return ioutil.ReadAll(part)
+		}
+	}
+}
+
+// signHeader 使用当前平台证书生成应答签名头
+func (s *Server) signHeader(body []byte) http.Header {
+	c := s.current()
+	timestamp := strconv.FormatInt(time.Now().Unix(), 10)
+	nonce := pkg.String(32)
+	hashed := sha256.Sum256([]byte(fmt.Sprintf("%s\n%s\n%s\n", timestamp, nonce, body)))
+	signature, err := rsa.SignPKCS1v15(rand.Reader, c.privateKey, crypto.SHA256, hashed[:])
+	if err != nil {
+		panic(err)
+	}
+	header := make(http.Header)
+	header.Set("Wechatpay-Serial", c.serialNo)
+	header.Set("Wechatpay-Timestamp", timestamp)
+	header.Set("Wechatpay-Nonce", nonce)
+	header.Set("Wechatpay-Signature", base64.StdEncoding.EncodeToString(signature))
+	return header
+}
+
+func (s *Server) writeJSON(w http.ResponseWriter, status int, response interface{}) {
+	var body []byte
+	var contentType = service.ContentTypeJSON
+	switch v := response.(type) {
+	case nil:
+	case []byte:
+		body, contentType = v, "application/octet-stream"
+	default:
+		var err error
+		if body, err = json.Marshal(response); err != nil {
+			status, body = http.StatusInternalServerError, []byte(`{"code":"SYSTEM_ERROR","message":"系统错误"}`)
+		}
+	}
+	for k, v := range s.signHeader(body) {
+		w.Header()[k] = v
+	}
+	s.mu.Lock()
+	s.sequence++
+	w.Header().Set("Request-ID", fmt.Sprintf("08%X%08X", time.Now().Unix(), s.sequence))
+	s.mu.Unlock()
+	if len(body) > 0 {
+		w.Header().Set("Content-Type", contentType)
+	}
+	w.WriteHeader(status)
+	_, _ = w.Write(body)
+}
+
+func (s *Server) writeError(w http.ResponseWriter, status int, code, message string) {
+	s.writeJSON(w, status, Error(code, message))
+}
+
+// Error 构造微信支付格式的错误应答
+func Error(code, message string) map[string]interface{} {
+	return map[string]interface{}{"code": code, "message": message}
+}
+
+// handleCertificates 下载平台证书
+func (s *Server) handleCertificates(r *http.Request, body []byte) (int, interface{}) {
+	s.mu.Lock()
+	certificates := append([]*platformCertificate(nil), s.certificates...)
+	s.mu.Unlock()
+
+	var data []map[string]interface{}
+	for _, c := range certificates {
+		nonce := pkg.String(12)
+		cipherText, err := encryptAES256GCM(s.ApiV3Key, nonce, "certificate", c.pem)
+		if err != nil {
+			return http.StatusInternalServerError, Error("SYSTEM_ERROR", err.Error())
+		}
+		data = append(data, map[string]interface{}{
+			"serial_no":      c.serialNo,
+			"effective_time": c.certificate.NotBefore.Format(time.RFC3339),
+			"expire_time":    c.certificate.NotAfter.Format(time.RFC3339),
+			"encrypt_certificate": map[string]string{
+				"algorithm":       "AEAD_AES_256_GCM",
+				"nonce":           nonce,
+				"associated_data": "certificate",
+				"ciphertext":      cipherText,
+			},
+		})
+	}
+	return http.StatusOK, map[string]interface{}{"data": data}
+}
+
+// encryptAES256GCM 使用APIv3密钥加密, 返回base64编码的密文
+func encryptAES256GCM(key, nonce, associatedData string, plainText []byte) (string, error) {
+	block, err := aes.NewCipher([]byte(key))
+	if err != nil {
+		return "", err
+	}
+	gcm, err := cipher.NewGCM(block)
+	if err != nil {
+		return "", err
+	}
+	cipherText := gcm.Seal(nil, []byte(nonce), plainText, []byte(associatedData))
+	return base64.StdEncoding.EncodeToString(cipherText), nil
+}